@@ -0,0 +1,330 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/keybase/go-updater/util"
+)
+
+// DefaultCacheLimit is the default size limit (in bytes) for downloaded assets
+// in the cache
+const DefaultCacheLimit int64 = 500 * 1024 * 1024
+
+// cacheKeyRE matches a cache key, which is a (SHA256 hex) digest
+var cacheKeyRE = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// SetCacheDir sets the directory for downloaded assets. If not set, a
+// directory (for the current user) in the temp dir is used.
+func (u *Updater) SetCacheDir(dir string) {
+	u.cacheDir = dir
+}
+
+// SetCacheLimit sets the size limit (in bytes) for downloaded assets in the
+// cache. If 0, DefaultCacheLimit is used.
+func (u *Updater) SetCacheLimit(limit int64) {
+	u.cacheLimit = limit
+}
+
+func (u *Updater) cacheLimitOrDefault() int64 {
+	if u.cacheLimit <= 0 {
+		return DefaultCacheLimit
+	}
+	return u.cacheLimit
+}
+
+// openCache returns the cache dir, creating it if it doesn't exist. It errors
+// if the dir isn't owned by, and private to, the current user.
+func (u *Updater) openCache() (string, error) {
+	dir := u.cacheDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), fmt.Sprintf("KeybaseUpdaterCache.%d", os.Getuid()))
+	}
+	if err := util.MakeDirs(dir, 0700, u.log); err != nil {
+		return "", err
+	}
+	if err := util.CheckPermissions(dir, 0077); err != nil {
+		return "", fmt.Errorf("Unable to use cache: %s", err)
+	}
+	return dir, nil
+}
+
+// cachePath returns the path to an asset in the cache, which is keyed by the
+// asset digest.
+func (u *Updater) cachePath(asset Asset) (string, error) {
+	if !cacheKeyRE.MatchString(asset.Digest) {
+		return "", fmt.Errorf("Invalid asset digest: %q", asset.Digest)
+	}
+	name := filepath.Base(asset.Name)
+	if asset.Name == "" || name != asset.Name || name == "." || name == ".." {
+		return "", fmt.Errorf("Invalid asset name: %q", asset.Name)
+	}
+	dir, err := u.openCache()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, asset.Digest, name), nil
+}
+
+// cacheDownloadPath returns the path in the cache to download an asset to,
+// creating its dir if it doesn't exist
+func (u *Updater) cacheDownloadPath(asset Asset) (string, error) {
+	path, err := u.cachePath(asset)
+	if err != nil {
+		return "", err
+	}
+	if err := util.MakeDirs(filepath.Dir(path), 0700, u.log); err != nil {
+		return "", err
+	}
+	if err := util.CheckPermissions(filepath.Dir(path), 0077); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// FindDownloadedAsset returns the path to a previously downloaded asset in
+// the cache, or "" if it isn't there. The digest of the asset isn't checked
+// here, it should be checked before use.
+func (u *Updater) FindDownloadedAsset(asset Asset) (matchingAssetPath string, err error) {
+	path, err := u.cachePath(asset)
+	if err != nil {
+		return "", err
+	}
+	if exists, err := util.FileExists(path); err != nil || !exists {
+		return "", err
+	}
+	if err := util.CheckPermissions(filepath.Dir(path), 0077); err != nil {
+		return "", err
+	}
+	if err := util.CheckPermissions(path, 0022); err != nil {
+		return "", err
+	}
+	// Mark as recently used, for eviction
+	now := time.Now()
+	if err := os.Chtimes(filepath.Dir(path), now, now); err != nil {
+		u.log.Warningf("Error updating cache time: %s", err)
+	}
+	return path, nil
+}
+
+// findCachedUpdate sets the LocalPath of the update asset to a previous
+// download of it in the cache, and returns true, if there is one. A download
+// that doesn't match the digest is removed.
+func (u *Updater) findCachedUpdate(update *Update) bool {
+	path, err := u.FindDownloadedAsset(*update.Asset)
+	if err != nil {
+		u.log.Warningf("Error finding cached update: %s", err)
+		return false
+	}
+	if path == "" {
+		return false
+	}
+	if err := util.CheckDigest(update.Asset.Digest, path, u.log); err != nil {
+		u.log.Warningf("Removing invalid cached update: %s", err)
+		u.removeCachedAsset(update.Asset)
+		return false
+	}
+	u.log.Infof("Using cached update: %s", path)
+	update.Asset.LocalPath = path
+	return true
+}
+
+// cacheAsset moves a downloaded asset into the cache, and sets its LocalPath.
+// Older assets are evicted if the cache is over its size limit.
+func (u *Updater) cacheAsset(asset *Asset) error {
+	path, err := u.cacheDownloadPath(*asset)
+	if err != nil {
+		return err
+	}
+	if asset.LocalPath != path {
+		if err := util.MoveFile(asset.LocalPath, path, "", u.log); err != nil {
+			return err
+		}
+		asset.LocalPath = path
+	}
+	u.evictCache(asset.Digest)
+	return nil
+}
+
+// InstalledAssetDir returns the dir in the cache (see SetCacheDir) where the
+// asset that was last installed is kept, as the base for delta updates (see
+// DeltaBaser). There is at most one asset in it.
+func InstalledAssetDir(cacheDir string) string {
+	return filepath.Join(cacheDir, "installed")
+}
+
+// FindInstalledAsset returns the path to the asset that was last installed
+// from the cache, or "" if there isn't one. The digest of the asset isn't
+// checked here, it should be checked before use.
+func FindInstalledAsset(cacheDir string) (string, error) {
+	dir := InstalledAssetDir(cacheDir)
+	if exists, err := util.FileExists(dir); err != nil || !exists {
+		return "", err
+	}
+	if err := util.CheckPermissions(dir, 0077); err != nil {
+		return "", err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, fi := range files {
+		if fi.Type().IsRegular() {
+			return filepath.Join(dir, fi.Name()), nil
+		}
+	}
+	return "", nil
+}
+
+// keepInstalledAsset moves an asset that was installed into the installed dir
+// of the cache, replacing the one that was there
+func (u *Updater) keepInstalledAsset(asset *Asset) {
+	if asset == nil || asset.LocalPath == "" {
+		return
+	}
+	dir, err := u.openCache()
+	if err != nil {
+		u.log.Warningf("Error keeping installed asset: %s", err)
+		return
+	}
+	installedDir := InstalledAssetDir(dir)
+	name := filepath.Base(asset.LocalPath)
+	if err := os.RemoveAll(installedDir); err != nil {
+		u.log.Warningf("Error removing previous installed asset: %s", err)
+		return
+	}
+	if err := util.MakeDirs(installedDir, 0700, u.log); err != nil {
+		u.log.Warningf("Error keeping installed asset: %s", err)
+		return
+	}
+	if err := util.MoveFile(asset.LocalPath, filepath.Join(installedDir, name), "", u.log); err != nil {
+		u.log.Warningf("Error keeping installed asset: %s", err)
+	}
+}
+
+// removeCachedAsset removes an asset from the cache
+func (u *Updater) removeCachedAsset(asset *Asset) {
+	if asset == nil {
+		return
+	}
+	path, err := u.cachePath(*asset)
+	if err != nil {
+		return
+	}
+	u.log.Debugf("Removing cached asset: %s", path)
+	if err := os.RemoveAll(filepath.Dir(path)); err != nil {
+		u.log.Warningf("Error removing cached asset: %s", err)
+	}
+}
+
+type cacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// readCache returns the assets in the cache (least recently used first), and
+// their total size
+func (u *Updater) readCache() ([]cacheEntry, int64, error) {
+	dir, err := u.openCache()
+	if err != nil {
+		return nil, 0, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+	var entries []cacheEntry
+	var total int64
+	for _, fi := range files {
+		// Only consider entries we would have created
+		if !fi.IsDir() || !cacheKeyRE.MatchString(fi.Name()) {
+			continue
+		}
+		entry := cacheEntry{path: filepath.Join(dir, fi.Name())}
+		info, err := fi.Info()
+		if err != nil {
+			continue
+		}
+		entry.modTime = info.ModTime()
+		walkErr := filepath.Walk(entry.path, func(_ string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.Mode().IsRegular() {
+				entry.size += info.Size()
+			}
+			return nil
+		})
+		if walkErr != nil {
+			u.log.Warningf("Error reading cache entry: %s", walkErr)
+			continue
+		}
+		total += entry.size
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	return entries, total, nil
+}
+
+// evictCache removes the least recently used assets until the cache is under
+// its size limit. The asset with the keep digest isn't removed.
+func (u *Updater) evictCache(keep string) {
+	entries, total, err := u.readCache()
+	if err != nil {
+		u.log.Warningf("Error reading cache: %s", err)
+		return
+	}
+	limit := u.cacheLimitOrDefault()
+	for _, entry := range entries {
+		if total <= limit {
+			break
+		}
+		if filepath.Base(entry.path) == keep {
+			continue
+		}
+		u.log.Infof("Evicting cached asset: %s", entry.path)
+		if err := os.RemoveAll(entry.path); err != nil {
+			u.log.Warningf("Error evicting cached asset: %s", err)
+			continue
+		}
+		total -= entry.size
+	}
+}
+
+// pendingAsset returns the path to the most recently used asset in the cache,
+// or "" if the cache is empty
+func (u *Updater) pendingAsset() (string, error) {
+	entries, _, err := u.readCache()
+	if err != nil || len(entries) == 0 {
+		return "", err
+	}
+	dir := entries[len(entries)-1].path
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, fi := range files {
+		// Skip a partial download
+		if fi.Type().IsRegular() && !isPartialDownload(fi.Name()) {
+			return filepath.Join(dir, fi.Name()), nil
+		}
+	}
+	return "", nil
+}
+
+// isPartialDownload returns true if name is a partial download (or its
+// validator), see util.DownloadURL
+func isPartialDownload(name string) bool {
+	return strings.HasSuffix(name, ".download") || strings.HasSuffix(name, ".download.validator")
+}
@@ -5,6 +5,7 @@ package command
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -62,18 +63,25 @@ type execCmd func(name string, arg ...string) *exec.Cmd
 
 // Exec runs a command and returns the stdout/err output and error if any
 func Exec(name string, args []string, timeout time.Duration, log Log) (Result, error) {
-	return execWithFunc(name, args, nil, exec.Command, timeout, log)
+	return execWithFunc(context.Background(), name, args, nil, exec.Command, timeout, log)
+}
+
+// ExecContext runs a command and returns the stdout/err output and error if
+// any. If the context is done before the command finishes, the command is
+// terminated (like on timeout) and the context error is returned.
+func ExecContext(ctx context.Context, name string, args []string, timeout time.Duration, log Log) (Result, error) {
+	return execWithFunc(ctx, name, args, nil, exec.Command, timeout, log)
 }
 
 // ExecWithEnv runs a command with an environment and returns the stdout/err output and error if any
 func ExecWithEnv(name string, args []string, env []string, timeout time.Duration, log Log) (Result, error) {
-	return execWithFunc(name, args, env, exec.Command, timeout, log)
+	return execWithFunc(context.Background(), name, args, env, exec.Command, timeout, log)
 }
 
 // exec runs a command and returns a Result and error if any.
 // We will send TERM signal and wait 1 second or timeout, whichever is less,
 // before calling KILL.
-func execWithFunc(name string, args []string, env []string, execCmd execCmd, timeout time.Duration, log Log) (Result, error) {
+func execWithFunc(ctx context.Context, name string, args []string, env []string, execCmd execCmd, timeout time.Duration, log Log) (Result, error) {
 	var result Result
 	log.Debugf("Execute: %s %s", name, args)
 	if name == "" {
@@ -82,6 +90,9 @@ func execWithFunc(name string, args []string, env []string, execCmd execCmd, tim
 	if timeout < 0 {
 		return result, fmt.Errorf("Invalid timeout: %s", timeout)
 	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
 	cmd := execCmd(name, args...)
 	if cmd == nil {
 		return result, fmt.Errorf("No command")
@@ -101,7 +112,8 @@ func execWithFunc(name string, args []string, env []string, execCmd execCmd, tim
 		doneCh <- cmd.Wait()
 		close(doneCh)
 	}()
-	// Wait for the command to finish or time out
+	// Wait for the command to finish, time out or be canceled
+	stopErr := fmt.Errorf("Timed out")
 	select {
 	case cmdErr := <-doneCh:
 		log.Debugf("Executed %s %s", name, args)
@@ -109,6 +121,9 @@ func execWithFunc(name string, args []string, env []string, execCmd execCmd, tim
 	case <-time.After(timeout):
 		// Timed out
 		log.Warningf("Process timed out")
+	case <-ctx.Done():
+		log.Warningf("Process canceled")
+		stopErr = ctx.Err()
 	}
 	// If no process, nothing to kill
 	if cmd.Process == nil {
@@ -121,7 +136,7 @@ func execWithFunc(name string, args []string, env []string, execCmd execCmd, tim
 	if timeout < termWait {
 		termWait = timeout
 	}
-	log.Warningf("Command timed out, terminating (will wait %s before killing)", termWait)
+	log.Warningf("Command stopped (%s), terminating (will wait %s before killing)", stopErr, termWait)
 	err = cmd.Process.Signal(syscall.SIGTERM)
 	if err != nil {
 		log.Warningf("Error sending terminate: %s", err)
@@ -138,12 +153,19 @@ func execWithFunc(name string, args []string, env []string, execCmd execCmd, tim
 			log.Warningf("Killed process")
 		}
 	}
-	return result, fmt.Errorf("Timed out")
+	return result, stopErr
 }
 
 // ExecForJSON runs a command (with timeout) expecting JSON output with obj interface
 func ExecForJSON(command string, args []string, obj interface{}, timeout time.Duration, log Log) error {
-	result, err := execWithFunc(command, args, nil, exec.Command, timeout, log)
+	return ExecForJSONContext(context.Background(), command, args, obj, timeout, log)
+}
+
+// ExecForJSONContext runs a command (with timeout) expecting JSON output with
+// obj interface. If the context is done before the command finishes, the
+// command is terminated and the context error is returned.
+func ExecForJSONContext(ctx context.Context, command string, args []string, obj interface{}, timeout time.Duration, log Log) error {
+	result, err := execWithFunc(ctx, command, args, nil, exec.Command, timeout, log)
 	if err != nil {
 		return err
 	}
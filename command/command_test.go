@@ -4,6 +4,7 @@
 package command
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -48,7 +49,7 @@ func TestExecNil(t *testing.T) {
 	execCmd := func(name string, arg ...string) *exec.Cmd {
 		return nil
 	}
-	_, err := execWithFunc("echo", []string{"arg1", "arg2"}, nil, execCmd, time.Second, testLog)
+	_, err := execWithFunc(context.Background(), "echo", []string{"arg1", "arg2"}, nil, execCmd, time.Second, testLog)
 	require.Error(t, err)
 }
 
@@ -66,6 +67,33 @@ func TestExecTimeout(t *testing.T) {
 	require.EqualError(t, err, "Timed out")
 }
 
+func TestExecContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	start := time.Now()
+	_, err := ExecContext(ctx, "sleep", []string{"10"}, time.Minute, testLog)
+	require.Equal(t, context.Canceled, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+
+	// Already canceled
+	_, err = ExecContext(ctx, "sleep", []string{"10"}, time.Minute, testLog)
+	require.Equal(t, context.Canceled, err)
+}
+
+func TestExecForJSONContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	var obj testObj
+	err := ExecForJSONContext(ctx, "sleep", []string{"10"}, &obj, time.Minute, testLog)
+	require.Equal(t, context.Canceled, err)
+}
+
 func TestExecBadTimeout(t *testing.T) {
 	result, err := Exec("sleep", []string{"1"}, -time.Second, testLog)
 	assert.Equal(t, result.Stdout.String(), "")
@@ -151,7 +179,7 @@ func TestExecTimeoutProcessKilled(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("Unsupported on windows")
 	}
-	result, err := execWithFunc("sleep", []string{"10"}, nil, exec.Command, 10*time.Millisecond, testLog)
+	result, err := execWithFunc(context.Background(), "sleep", []string{"10"}, nil, exec.Command, 10*time.Millisecond, testLog)
 	assert.Equal(t, result.Stdout.String(), "")
 	assert.Equal(t, result.Stderr.String(), "")
 	assert.Error(t, err)
@@ -172,7 +200,7 @@ func TestExecNoExit(t *testing.T) {
 
 func TestExecOutput(t *testing.T) {
 	path := filepath.Join(os.Getenv("GOPATH"), "bin", "test")
-	result, err := execWithFunc(path, []string{"output"}, nil, exec.Command, time.Second, testLog)
+	result, err := execWithFunc(context.Background(), path, []string{"output"}, nil, exec.Command, time.Second, testLog)
 	assert.NoError(t, err)
 	assert.Equal(t, "stdout output\n", result.Stdout.String())
 	assert.Equal(t, "stderr output\n", result.Stderr.String())
@@ -0,0 +1,110 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package control
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/keybase/go-updater"
+)
+
+// Client is a client for the control API
+type Client struct {
+	client *http.Client
+}
+
+// NewClient constructs a client for the server on the socket at path
+func NewClient(path string) *Client {
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	return &Client{
+		client: &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", path)
+				},
+			},
+		},
+	}
+}
+
+// do sends a request (with body encoded as JSON, if not nil), and decodes the
+// response into result (if not nil)
+func (c *Client) do(method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	// The host is ignored, since we always connect to the socket
+	req, err := http.NewRequest(method, "http://updater"+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Unable to connect to updater: %s", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Error == "" {
+			return fmt.Errorf("Updater returned bad HTTP status %v", resp.Status)
+		}
+		return fmt.Errorf("%s", errResp.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("Invalid response: %s", err)
+	}
+	return nil
+}
+
+// Status returns the state of the updater
+func (c *Client) Status() (*updater.Status, error) {
+	var status updater.Status
+	if err := c.do(http.MethodGet, "/status", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Check triggers an update check
+func (c *Client) Check() error {
+	return c.do(http.MethodPost, "/check", nil, nil)
+}
+
+// Snooze snoozes an update for a duration (or the default if 0). If version is
+// empty, the available update is snoozed.
+func (c *Client) Snooze(version string, duration time.Duration) error {
+	return c.do(http.MethodPost, "/snooze", snoozeRequest{Version: version, Duration: int(duration / time.Second)}, nil)
+}
+
+// SetAuto sets whether to update automatically
+func (c *Client) SetAuto(auto bool) error {
+	return c.do(http.MethodPost, "/auto", autoRequest{Auto: auto}, nil)
+}
+
+// ApplyDownloaded applies a previously downloaded update
+func (c *Client) ApplyDownloaded() (bool, error) {
+	var resp applyResponse
+	if err := c.do(http.MethodPost, "/apply-downloaded", nil, &resp); err != nil {
+		return false, err
+	}
+	return resp.Applied, nil
+}
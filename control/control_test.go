@@ -0,0 +1,148 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package control
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/keybase/go-logging"
+	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLog = &logging.Logger{Module: "test"}
+
+type testHandler struct {
+	auto           bool
+	checks         int
+	snoozeVersion  string
+	snoozeDuration time.Duration
+	applied        bool
+	err            error
+}
+
+func (h *testHandler) Status() (*updater.Status, error) {
+	return &updater.Status{Version: updater.Version, Auto: h.auto, AutoSet: true}, h.err
+}
+
+func (h *testHandler) Check() error {
+	h.checks++
+	return h.err
+}
+
+func (h *testHandler) Snooze(version string, duration time.Duration) error {
+	h.snoozeVersion, h.snoozeDuration = version, duration
+	return h.err
+}
+
+func (h *testHandler) SetAuto(auto bool) error {
+	h.auto = auto
+	return h.err
+}
+
+func (h *testHandler) ApplyDownloaded(goCtx context.Context) (bool, error) {
+	h.applied = h.err == nil
+	return h.applied, h.err
+}
+
+func testServer(t *testing.T, handler Handler) (*Server, *Client) {
+	dir, err := util.MakeTempDir("TestControl.", 0700)
+	require.NoError(t, err)
+	t.Cleanup(func() { util.RemoveFileAtPath(dir) })
+	path := filepath.Join(dir, "updater.sock")
+	server := NewServer(path, handler, testLog)
+	err = server.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = server.Stop() })
+	return server, NewClient(path)
+}
+
+func TestControl(t *testing.T) {
+	handler := &testHandler{}
+	_, client := testServer(t, handler)
+
+	err := client.SetAuto(true)
+	require.NoError(t, err)
+	status, err := client.Status()
+	require.NoError(t, err)
+	assert.Equal(t, updater.Version, status.Version)
+	assert.True(t, status.Auto)
+	assert.True(t, status.AutoSet)
+
+	err = client.Check()
+	require.NoError(t, err)
+	assert.Equal(t, 1, handler.checks)
+
+	err = client.Snooze("1.2.3", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3", handler.snoozeVersion)
+	assert.Equal(t, time.Hour, handler.snoozeDuration)
+
+	applied, err := client.ApplyDownloaded()
+	require.NoError(t, err)
+	assert.True(t, applied)
+}
+
+func TestControlErrors(t *testing.T) {
+	handler := &testHandler{err: fmt.Errorf("Test error")}
+	server, client := testServer(t, handler)
+
+	err := client.Check()
+	assert.EqualError(t, err, "Test error")
+	_, err = client.Status()
+	assert.EqualError(t, err, "Test error")
+	_, err = client.ApplyDownloaded()
+	assert.EqualError(t, err, "Test error")
+
+	err = client.do(http.MethodPost, "/snooze", nil, nil)
+	assert.Error(t, err)
+	err = client.do(http.MethodGet, "/check", nil, nil)
+	assert.EqualError(t, err, "Method GET not allowed")
+	err = client.do(http.MethodGet, "/unknown", nil, nil)
+	assert.EqualError(t, err, "Updater returned bad HTTP status 404 Not Found")
+
+	// No server
+	err = server.Stop()
+	require.NoError(t, err)
+	err = client.Check()
+	assert.Error(t, err)
+}
+
+func TestControlInsecureDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Permissions aren't checked on Windows")
+	}
+	dir, err := util.MakeTempDir("TestControl.", 0700)
+	require.NoError(t, err)
+	t.Cleanup(func() { util.RemoveFileAtPath(dir) })
+	err = os.Chmod(dir, 0755)
+	require.NoError(t, err)
+
+	server := NewServer(filepath.Join(dir, "updater.sock"), &testHandler{}, testLog)
+	err = server.Start()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "insecure permissions")
+}
+
+func TestControlRestart(t *testing.T) {
+	handler := &testHandler{}
+	server, client := testServer(t, handler)
+
+	// A new server replaces the socket
+	restarted := NewServer(server.path, handler, testLog)
+	err := restarted.Start()
+	require.NoError(t, err)
+	defer func() { _ = restarted.Stop() }()
+	err = client.Check()
+	require.NoError(t, err)
+}
@@ -0,0 +1,222 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/util"
+)
+
+// Log is the logging interface for the control package
+type Log interface {
+	Debugf(s string, args ...interface{})
+	Infof(s string, args ...interface{})
+	Warningf(s string, args ...interface{})
+	Errorf(s string, args ...interface{})
+}
+
+// Handler does what the control API asks
+type Handler interface {
+	// Status returns the state of the updater
+	Status() (*updater.Status, error)
+	// Check triggers an update check
+	Check() error
+	// Snooze snoozes an update for a duration. If version is empty, the
+	// available update is snoozed.
+	Snooze(version string, duration time.Duration) error
+	// SetAuto sets whether to update automatically
+	SetAuto(auto bool) error
+	// ApplyDownloaded applies a previously downloaded update
+	ApplyDownloaded(goCtx context.Context) (bool, error)
+}
+
+// snoozeRequest is the body of a snooze request
+type snoozeRequest struct {
+	Version string `json:"version"`
+	// Duration is in seconds
+	Duration int `json:"duration"`
+}
+
+// autoRequest is the body of an auto request
+type autoRequest struct {
+	Auto bool `json:"auto"`
+}
+
+// applyResponse is the response to an apply-downloaded request
+type applyResponse struct {
+	Applied bool `json:"applied"`
+}
+
+// errorResponse is the response to a request that failed
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves the control API on a Unix domain socket
+type Server struct {
+	path     string
+	handler  Handler
+	log      Log
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer constructs a server for the socket at path
+func NewServer(path string, handler Handler, log Log) *Server {
+	return &Server{
+		path:    path,
+		handler: handler,
+		log:     log,
+	}
+}
+
+// Start listens on the socket, and serves requests until Stop. A socket left
+// over at the path (by a server that didn't stop) is replaced. The dir of the
+// socket must be private to the current user, since the socket has the
+// default (umask) permissions until we chmod it.
+func (s *Server) Start() error {
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	if err := util.CheckPermissions(dir, 0077); err != nil {
+		return fmt.Errorf("Unable to use socket dir: %s", err)
+	}
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Unable to remove existing socket: %s", err)
+	}
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return err
+	}
+	// Only the current user can connect
+	if err := os.Chmod(s.path, 0600); err != nil {
+		_ = listener.Close()
+		return err
+	}
+	s.listener = listener
+	s.server = &http.Server{Handler: s.mux()}
+	s.log.Infof("Control API listening on %s", s.path)
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.log.Errorf("Control API error: %s", err)
+		}
+	}()
+	return nil
+}
+
+// Stop stops serving, and removes the socket
+func (s *Server) Stop() error {
+	if s.server == nil {
+		return nil
+	}
+	err := s.server.Close()
+	s.server = nil
+	if rerr := os.Remove(s.path); rerr != nil && !os.IsNotExist(rerr) {
+		s.log.Warningf("Error removing socket: %s", rerr)
+	}
+	return err
+}
+
+func (s *Server) mux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.handle(http.MethodGet, s.status))
+	mux.HandleFunc("/check", s.handle(http.MethodPost, s.check))
+	mux.HandleFunc("/snooze", s.handle(http.MethodPost, s.snooze))
+	mux.HandleFunc("/auto", s.handle(http.MethodPost, s.auto))
+	mux.HandleFunc("/apply-downloaded", s.handle(http.MethodPost, s.applyDownloaded))
+	return mux
+}
+
+// requestError is an error in the request (rather than handling it)
+type requestError struct {
+	err error
+}
+
+func (e requestError) Error() string {
+	return e.err.Error()
+}
+
+// handle returns an http handler for a method, which writes the result (or
+// error) from f as JSON
+func (s *Server) handle(method string, f func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.log.Debugf("Control API request: %s %s", r.Method, r.URL.Path)
+		if r.Method != method {
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: fmt.Sprintf("Method %s not allowed", r.Method)})
+			return
+		}
+		result, err := f(r)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if _, ok := err.(requestError); ok {
+				status = http.StatusBadRequest
+			}
+			s.log.Warningf("Control API error for %s: %s", r.URL.Path, err)
+			writeJSON(w, status, errorResponse{Error: err.Error()})
+			return
+		}
+		if result == nil {
+			result = struct{}{}
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func decodeRequest(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return requestError{fmt.Errorf("Invalid request: %s", err)}
+	}
+	return nil
+}
+
+func (s *Server) status(r *http.Request) (interface{}, error) {
+	return s.handler.Status()
+}
+
+func (s *Server) check(r *http.Request) (interface{}, error) {
+	return nil, s.handler.Check()
+}
+
+func (s *Server) snooze(r *http.Request) (interface{}, error) {
+	var req snoozeRequest
+	if err := decodeRequest(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Duration < 0 {
+		return nil, requestError{fmt.Errorf("Invalid duration: %d", req.Duration)}
+	}
+	return nil, s.handler.Snooze(req.Version, time.Duration(req.Duration)*time.Second)
+}
+
+func (s *Server) auto(r *http.Request) (interface{}, error) {
+	var req autoRequest
+	if err := decodeRequest(r, &req); err != nil {
+		return nil, err
+	}
+	return nil, s.handler.SetAuto(req.Auto)
+}
+
+func (s *Server) applyDownloaded(r *http.Request) (interface{}, error) {
+	applied, err := s.handler.ApplyDownloaded(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	return applyResponse{Applied: applied}, nil
+}
@@ -0,0 +1,274 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package delta
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/keybase/go-updater/util"
+)
+
+// magic is the header for the patch format, which (after the header) is a gzip
+// stream of:
+//
+//	uvarint newSize
+//	ops...
+//
+// where each op is either a copy from the base:
+//
+//	byte opCopy, uvarint offset, uvarint length
+//
+// or data to add:
+//
+//	byte opAdd, uvarint length, bytes
+const magic = "KBDELTA1"
+
+const (
+	opCopy byte = 1
+	opAdd  byte = 2
+)
+
+// blockSize is the size of blocks we look for in the base
+const blockSize = 64
+
+// maxCandidates is the maximum number of offsets we keep for a block hash
+const maxCandidates = 8
+
+// hashBase is the base for the rolling block hash
+const hashBase uint32 = 257
+
+func hashBlock(b []byte) uint32 {
+	var h uint32
+	for _, c := range b {
+		h = h*hashBase + uint32(c)
+	}
+	return h
+}
+
+// hashPow is hashBase^(blockSize-1), for rolling the hash
+func hashPow() uint32 {
+	p := uint32(1)
+	for i := 0; i < blockSize-1; i++ {
+		p *= hashBase
+	}
+	return p
+}
+
+type encoder struct {
+	w   io.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (e *encoder) uvarint(v uint64) error {
+	n := binary.PutUvarint(e.buf[:], v)
+	_, err := e.w.Write(e.buf[:n])
+	return err
+}
+
+func (e *encoder) copy(offset int, length int) error {
+	if length == 0 {
+		return nil
+	}
+	if _, err := e.w.Write([]byte{opCopy}); err != nil {
+		return err
+	}
+	if err := e.uvarint(uint64(offset)); err != nil {
+		return err
+	}
+	return e.uvarint(uint64(length))
+}
+
+func (e *encoder) add(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if _, err := e.w.Write([]byte{opAdd}); err != nil {
+		return err
+	}
+	if err := e.uvarint(uint64(len(data))); err != nil {
+		return err
+	}
+	_, err := e.w.Write(data)
+	return err
+}
+
+// Diff writes a patch to w, which transforms base into target
+func Diff(base []byte, target []byte, w io.Writer) error {
+	if _, err := io.WriteString(w, magic); err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(w)
+	enc := &encoder{w: gz}
+	if err := enc.uvarint(uint64(len(target))); err != nil {
+		return err
+	}
+
+	index := map[uint32][]int{}
+	for i := 0; i+blockSize <= len(base); i += blockSize {
+		h := hashBlock(base[i : i+blockSize])
+		if len(index[h]) < maxCandidates {
+			index[h] = append(index[h], i)
+		}
+	}
+
+	pow := hashPow()
+	literalStart := 0
+	i := 0
+	var h uint32
+	hashValid := false
+	for i+blockSize <= len(target) {
+		if !hashValid {
+			h = hashBlock(target[i : i+blockSize])
+			hashValid = true
+		}
+		if offset, length := longestMatch(index[h], base, target[i:]); length > 0 {
+			// Extend the match backwards into data we haven't written yet
+			for offset > 0 && i > literalStart && base[offset-1] == target[i-1] {
+				offset--
+				i--
+				length++
+			}
+			if err := enc.add(target[literalStart:i]); err != nil {
+				return err
+			}
+			if err := enc.copy(offset, length); err != nil {
+				return err
+			}
+			i += length
+			literalStart = i
+			hashValid = false
+			continue
+		}
+		if i+blockSize < len(target) {
+			h = (h-uint32(target[i])*pow)*hashBase + uint32(target[i+blockSize])
+		}
+		i++
+	}
+	if err := enc.add(target[literalStart:]); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// longestMatch returns the offset and length of the longest match for target
+// in base from the candidate offsets, or a length of 0 if there is none.
+func longestMatch(candidates []int, base []byte, target []byte) (offset int, length int) {
+	for _, c := range candidates {
+		if !bytes.Equal(base[c:c+blockSize], target[:blockSize]) {
+			continue
+		}
+		n := blockSize
+		for c+n < len(base) && n < len(target) && base[c+n] == target[n] {
+			n++
+		}
+		if n > length {
+			offset, length = c, n
+		}
+	}
+	return offset, length
+}
+
+// Patch applies a patch (from Diff) to base, writing the result to w
+func Patch(base io.ReaderAt, patch io.Reader, w io.Writer) error {
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(patch, header); err != nil {
+		return fmt.Errorf("Error reading patch header: %s", err)
+	}
+	if string(header) != magic {
+		return fmt.Errorf("Invalid patch header")
+	}
+	gz, err := gzip.NewReader(patch)
+	if err != nil {
+		return err
+	}
+	defer util.Close(gz)
+	r := bufio.NewReader(gz)
+
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return fmt.Errorf("Error reading patch size: %s", err)
+	}
+	var written uint64
+	for {
+		op, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch op {
+		case opCopy:
+			offset, err := binary.ReadUvarint(r)
+			if err != nil {
+				return err
+			}
+			length, err := binary.ReadUvarint(r)
+			if err != nil {
+				return err
+			}
+			if length > size-written || length > math.MaxInt64 || offset > math.MaxInt64 {
+				return fmt.Errorf("Invalid copy in patch")
+			}
+			n, err := io.Copy(w, io.NewSectionReader(base, int64(offset), int64(length)))
+			if err != nil {
+				return err
+			}
+			if uint64(n) != length {
+				return fmt.Errorf("Patch doesn't match base (copy out of range)")
+			}
+			written += length
+		case opAdd:
+			length, err := binary.ReadUvarint(r)
+			if err != nil {
+				return err
+			}
+			if length > size-written || length > math.MaxInt64 {
+				return fmt.Errorf("Invalid add in patch")
+			}
+			if _, err := io.CopyN(w, r, int64(length)); err != nil {
+				return err
+			}
+			written += length
+		default:
+			return fmt.Errorf("Invalid patch op: %d", op)
+		}
+	}
+	if written != size {
+		return fmt.Errorf("Patch size mismatch: %d != %d", written, size)
+	}
+	return nil
+}
+
+// PatchFile applies a patch at patchPath to the file at basePath, and writes
+// the result to outPath.
+func PatchFile(basePath string, patchPath string, outPath string) error {
+	base, err := os.Open(basePath)
+	if err != nil {
+		return err
+	}
+	defer util.Close(base)
+	patch, err := os.Open(patchPath)
+	if err != nil {
+		return err
+	}
+	defer util.Close(patch)
+	out, err := os.OpenFile(outPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := Patch(base, bufio.NewReader(patch), out); err != nil {
+		_ = out.Close()
+		_ = os.Remove(outPath)
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,108 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package delta
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testData(size int, seed int64) []byte {
+	data := make([]byte, size)
+	_, _ = rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+func testDiffPatch(t *testing.T, base []byte, target []byte) int {
+	var patch bytes.Buffer
+	err := Diff(base, target, &patch)
+	require.NoError(t, err)
+	size := patch.Len()
+
+	var out bytes.Buffer
+	err = Patch(bytes.NewReader(base), &patch, &out)
+	require.NoError(t, err)
+	assert.Equal(t, target, out.Bytes())
+	return size
+}
+
+func TestDiffPatch(t *testing.T) {
+	base := testData(100000, 1)
+
+	// Small changes in the middle, an insert and a removal
+	target := append([]byte{}, base[:30000]...)
+	target = append(target, []byte("inserted")...)
+	target = append(target, base[30000:60000]...)
+	target = append(target, base[60100:]...)
+	target[50000] ^= 0xff
+	size := testDiffPatch(t, base, target)
+	assert.True(t, size < 1000, "patch too big: %d", size)
+
+	// Nothing in common
+	testDiffPatch(t, base, testData(5000, 2))
+	// Empty base or target
+	testDiffPatch(t, nil, target)
+	testDiffPatch(t, base, nil)
+	// Identical
+	testDiffPatch(t, base, base)
+}
+
+func TestPatchWrongBase(t *testing.T) {
+	base := testData(10000, 1)
+	target := append(append([]byte{}, base...), []byte("more")...)
+	var patch bytes.Buffer
+	err := Diff(base, target, &patch)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	err = Patch(bytes.NewReader(base[:5000]), &patch, &out)
+	assert.EqualError(t, err, "Patch doesn't match base (copy out of range)")
+}
+
+func TestPatchInvalid(t *testing.T) {
+	var out bytes.Buffer
+	err := Patch(bytes.NewReader(nil), bytes.NewReader([]byte("invalid patch")), &out)
+	assert.EqualError(t, err, "Invalid patch header")
+
+	err = Patch(bytes.NewReader(nil), bytes.NewReader([]byte("KBDE")), &out)
+	assert.Error(t, err)
+}
+
+func TestPatchFile(t *testing.T) {
+	base := testData(10000, 1)
+	target := append([]byte("prefix"), base...)
+	var patch bytes.Buffer
+	err := Diff(base, target, &patch)
+	require.NoError(t, err)
+
+	dir, err := util.MakeTempDir("TestPatchFile.", 0700)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(dir)
+	basePath := filepath.Join(dir, "base")
+	patchPath := filepath.Join(dir, "patch")
+	outPath := filepath.Join(dir, "out")
+	require.NoError(t, os.WriteFile(basePath, base, 0600))
+	require.NoError(t, os.WriteFile(patchPath, patch.Bytes(), 0600))
+
+	err = PatchFile(basePath, patchPath, outPath)
+	require.NoError(t, err)
+	out, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+	assert.Equal(t, target, out)
+
+	// A failed patch doesn't leave output behind
+	require.NoError(t, os.WriteFile(basePath, base[:100], 0600))
+	err = PatchFile(basePath, patchPath, outPath)
+	assert.Error(t, err)
+	exists, err := util.FileExists(outPath)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
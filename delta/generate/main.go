@@ -0,0 +1,84 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/delta"
+	"github.com/keybase/go-updater/util"
+)
+
+type flags struct {
+	base        string
+	target      string
+	baseVersion string
+	out         string
+	url         string
+}
+
+// This generates a delta (patch) from a base (installed) asset to a target
+// (new) asset, and outputs the delta JSON for the update.
+func main() {
+	f := flags{}
+	flag.StringVar(&f.base, "base", "", "Path to base (installed) asset")
+	flag.StringVar(&f.target, "target", "", "Path to target (new) asset")
+	flag.StringVar(&f.baseVersion, "base-version", "", "Version of the base asset")
+	flag.StringVar(&f.out, "out", "", "Path to write the patch to")
+	flag.StringVar(&f.url, "url", "", "URL the patch will be available at")
+	flag.Parse()
+
+	if err := generate(f); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func generate(f flags) error {
+	if f.base == "" || f.target == "" || f.out == "" {
+		return fmt.Errorf("Specify -base, -target and -out")
+	}
+	base, err := os.ReadFile(f.base)
+	if err != nil {
+		return err
+	}
+	target, err := os.ReadFile(f.target)
+	if err != nil {
+		return err
+	}
+
+	var patch bytes.Buffer
+	if err := delta.Diff(base, target, &patch); err != nil {
+		return err
+	}
+	if err := os.WriteFile(f.out, patch.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	patchDigest, err := util.Digest(bytes.NewReader(patch.Bytes()))
+	if err != nil {
+		return err
+	}
+	baseDigest, err := util.Digest(bytes.NewReader(base))
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(updater.Delta{
+		URL:         f.url,
+		Digest:      patchDigest,
+		BaseVersion: f.baseVersion,
+		BaseDigest:  baseDigest,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Patch is %d bytes (target is %d bytes)\n", patch.Len(), len(target))
+	fmt.Println(string(out))
+	return nil
+}
@@ -17,6 +17,8 @@ const (
 	ConfigError ErrorType = "config"
 	// ConfigError is for when the GUI is active
 	GUIBusyError ErrorType = "guiBusy"
+	// LockError is for when another updater holds the update lock
+	LockError ErrorType = "lock"
 )
 
 // Errors corresponding to each stage in the update process
@@ -31,6 +33,19 @@ const (
 	ApplyError ErrorType = "apply"
 	// VerifyError is an error verifing the update (signature or digest)
 	VerifyError ErrorType = "verify"
+	// RollbackError is an error after applying the update, where the previous
+	// install was restored (or we tried to restore it)
+	RollbackError ErrorType = "rollback"
+	// DowngradeError is for when an update was refused because it isn't newer
+	// than the current version
+	DowngradeError ErrorType = "downgrade"
+)
+
+// Error subtypes, which are more specific than the error type
+const (
+	// StaleError is a VerifyError subtype, for an update response that has
+	// expired, or is older than one we've seen (replayed or rolled back)
+	StaleError ErrorType = "stale"
 )
 
 func (t ErrorType) String() string {
@@ -40,7 +55,10 @@ func (t ErrorType) String() string {
 // Error is an update error with a type/category for reporting
 type Error struct {
 	errorType ErrorType
-	source    error
+	// subtype is a more specific type, if any (like StaleError for a
+	// VerifyError)
+	subtype ErrorType
+	source  error
 }
 
 // NewError constructs an Error from a source error
@@ -53,6 +71,11 @@ func (e Error) TypeString() string {
 	return e.errorType.String()
 }
 
+// SubtypeString returns the error subtype, or "" if there isn't one
+func (e Error) SubtypeString() string {
+	return e.subtype.String()
+}
+
 // IsCancel returns true if error was from a cancel
 func (e Error) IsCancel() bool {
 	return e.errorType == CancelError
@@ -63,6 +86,28 @@ func (e Error) IsGUIBusy() bool {
 	return e.errorType == GUIBusyError
 }
 
+// IsLock returns true if another updater held the update lock
+func (e Error) IsLock() bool {
+	return e.errorType == LockError
+}
+
+// IsDowngrade returns true if the update was refused for not being newer
+func (e Error) IsDowngrade() bool {
+	return e.errorType == DowngradeError
+}
+
+// IsVerify returns true if the update (or update response) didn't verify,
+// including if it was stale
+func (e Error) IsVerify() bool {
+	return e.errorType == VerifyError
+}
+
+// IsStale returns true if the update response expired, or was older than one
+// we've seen
+func (e Error) IsStale() bool {
+	return e.errorType == VerifyError && e.subtype == StaleError
+}
+
 // Error returns description for an UpdateError
 func (e Error) Error() string {
 	if e.source == nil {
@@ -96,10 +141,26 @@ func verifyErr(err error) Error {
 	return NewError(VerifyError, err)
 }
 
+func staleErr(err error) Error {
+	return Error{errorType: VerifyError, subtype: StaleError, source: err}
+}
+
 func applyErr(err error) Error {
 	return NewError(ApplyError, err)
 }
 
+func rollbackErr(err error) Error {
+	return NewError(RollbackError, err)
+}
+
+func downgradeErr(err error) Error {
+	return NewError(DowngradeError, err)
+}
+
+func lockErr(err error) Error {
+	return NewError(LockError, err)
+}
+
 func configErr(err error) Error {
 	return NewError(ConfigError, err)
 }
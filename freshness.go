@@ -0,0 +1,97 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/keybase/go-updater/util"
+)
+
+// FreshnessConfig is an optional interface for a Config, to remember the
+// newest update response we've seen on each channel. If the Config implements
+// it, an update response published before that one is rejected, so an old
+// response can't be replayed.
+type FreshnessConfig interface {
+	// GetLatestSeen returns the newest update seen on a channel ("" is the
+	// default channel)
+	GetLatestSeen(channel string) (SeenUpdate, bool)
+	// SetLatestSeen saves the newest update seen on a channel
+	SetLatestSeen(channel string, seen SeenUpdate) error
+}
+
+// SeenUpdate is an update response we've seen
+type SeenUpdate struct {
+	Version string `json:"version"`
+	// PublishedAt is in milliseconds since epoch, like Update.PublishedAt
+	PublishedAt int64 `json:"publishedAt"`
+}
+
+func timeFromMillis(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// maxClockSkew is how far in the future an update response can be published,
+// since our clock may be behind
+const maxClockSkew = time.Hour
+
+// checkFresh returns a stale error if the update response has expired, is
+// published in the future, or is older (published before, or with a lower
+// version) than the newest one we've seen on the channel. If it's the newest,
+// and verified (its manifest signature), we remember it. If the update doesn't
+// say when it was published, only its version is compared.
+func (u *Updater) checkFresh(update Update, options UpdateOptions, verified bool) error {
+	now := time.Now()
+	if update.ExpiresAt != 0 && !now.Before(timeFromMillis(update.ExpiresAt)) {
+		return staleErr(fmt.Errorf("Update response expired at %s", timeFromMillis(update.ExpiresAt).Format(time.RFC3339)))
+	}
+	published := update.PublishedAt != 0
+	publishedAt := timeFromMillis(update.PublishedAt)
+	if published && publishedAt.After(now.Add(maxClockSkew)) {
+		return staleErr(fmt.Errorf("Update %s is published in the future (at %s)", update.Version, publishedAt.Format(time.RFC3339)))
+	}
+
+	freshnessConfig, ok := u.config.(FreshnessConfig)
+	if !ok {
+		return nil
+	}
+	seen, ok := freshnessConfig.GetLatestSeen(options.Channel)
+	// If either version is invalid, we only compare when they were published
+	compare, compareErr := util.SemverCompare(update.Version, seen.Version)
+	if ok {
+		if published && update.PublishedAt < seen.PublishedAt {
+			return staleErr(fmt.Errorf("Update %s (published at %s) is older than update %s (published at %s)",
+				update.Version, publishedAt.Format(time.RFC3339),
+				seen.Version, timeFromMillis(seen.PublishedAt).Format(time.RFC3339)))
+		}
+		if compareErr == nil && compare < 0 {
+			return staleErr(fmt.Errorf("Update version %s is older than update %s", update.Version, seen.Version))
+		}
+	}
+
+	if !ok || update.PublishedAt > seen.PublishedAt || (compareErr == nil && compare > 0) {
+		if !verified {
+			u.log.Debugf("Not saving update %s as the latest seen, since its manifest isn't signed", update.Version)
+			return nil
+		}
+		// Keep when the latest seen was published, if this one doesn't say
+		latest := SeenUpdate{Version: update.Version, PublishedAt: update.PublishedAt}
+		if !published {
+			latest.PublishedAt = seen.PublishedAt
+		}
+		if err := freshnessConfig.SetLatestSeen(options.Channel, latest); err != nil {
+			u.log.Warningf("Error saving latest update seen: %s", err)
+		}
+	}
+	return nil
+}
+
+// reportUnverified reports an error if the update response didn't verify, for
+// where find errors aren't reported
+func reportUnverified(ctx Context, err error, options UpdateOptions) {
+	if e, ok := err.(Error); ok && e.IsVerify() {
+		ctx.ReportError(err, nil, options)
+	}
+}
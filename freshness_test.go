@@ -0,0 +1,141 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testFreshnessConfig struct {
+	*testConfig
+	seen map[string]SeenUpdate
+}
+
+func (c *testFreshnessConfig) GetLatestSeen(channel string) (SeenUpdate, bool) {
+	seen, ok := c.seen[channel]
+	return seen, ok
+}
+
+func (c *testFreshnessConfig) SetLatestSeen(channel string, seen SeenUpdate) error {
+	c.seen[channel] = seen
+	return nil
+}
+
+func TestUpdaterStale(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	cfg := &testFreshnessConfig{testConfig: &testConfig{}, seen: map[string]SeenUpdate{}}
+	update := testUpdate(testServer.URL)
+	update.PublishedAt = 1700000000000
+	upr, err := newTestUpdaterWithServer(t, testServer, update, cfg)
+	require.NoError(t, err)
+	ctx := &testManifestUI{
+		testUpdateUI: newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true}),
+	}
+
+	// Without a (verified) manifest signature, it isn't saved
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(cfg.seen))
+
+	update.Signature = "signed"
+	update.Platform = runtime.GOOS
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, SeenUpdate{Version: "1.0.1", PublishedAt: 1700000000000}, cfg.seen[""])
+
+	// The same response again is fine
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+
+	// An older response is rejected, and reported
+	ctx.successReported = false
+	update.Version = "1.0.0"
+	update.PublishedAt = 1600000000000
+	_, err = upr.Update(ctx)
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsStale())
+	assert.True(t, err.(Error).IsVerify())
+	assert.Equal(t, "verify", err.(Error).TypeString())
+	assert.Equal(t, "stale", err.(Error).SubtypeString())
+	assert.Contains(t, err.Error(), "Update 1.0.0 (published at ")
+	assert.Equal(t, err, ctx.errReported)
+	assert.False(t, ctx.successReported)
+	assert.Equal(t, "1.0.1", cfg.seen[""].Version)
+
+	// A lower version is rejected, even if published later
+	update.PublishedAt = 1750000000000
+	_, err = upr.Update(ctx)
+	assert.EqualError(t, err, "Update Error (verify): Update version 1.0.0 is older than update 1.0.1")
+
+	// Without a published time, the version is still compared
+	update.PublishedAt = 0
+	_, err = upr.Update(ctx)
+	assert.EqualError(t, err, "Update Error (verify): Update version 1.0.0 is older than update 1.0.1")
+
+	// Other channels are separate
+	options := newDefaultTestUpdateOptions()
+	options.Channel = "test"
+	options.Force = true
+	ctx.options = options
+	update.Channel = "test"
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.0", cfg.seen["test"].Version)
+}
+
+func TestUpdaterPublishedInFuture(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	cfg := &testFreshnessConfig{testConfig: &testConfig{}, seen: map[string]SeenUpdate{}}
+	update := testUpdate(testServer.URL)
+	update.Signature = "signed"
+	update.Platform = runtime.GOOS
+	update.PublishedAt = time.Now().Add(24*time.Hour).UnixNano() / int64(time.Millisecond)
+	upr, err := newTestUpdaterWithServer(t, testServer, update, cfg)
+	require.NoError(t, err)
+	ctx := &testManifestUI{
+		testUpdateUI: newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true}),
+	}
+	_, err = upr.Update(ctx)
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsStale())
+	assert.Contains(t, err.Error(), "Update 1.0.1 is published in the future")
+	assert.Equal(t, 0, len(cfg.seen))
+
+	// Within the allowed clock skew
+	update.PublishedAt = time.Now().Add(time.Minute).UnixNano() / int64(time.Millisecond)
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, update.PublishedAt, cfg.seen[""].PublishedAt)
+}
+
+func TestUpdaterExpired(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	update := testUpdate(testServer.URL)
+	update.ExpiresAt = time.Now().Add(-time.Minute).UnixNano() / int64(time.Millisecond)
+	upr, err := newTestUpdaterWithServer(t, testServer, update, &testConfig{})
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true})
+
+	// Reported, even though find errors aren't reported here
+	_, _, err = upr.CheckAndDownload(ctx)
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsStale())
+	assert.Contains(t, err.Error(), "Update response expired at ")
+	assert.Equal(t, err, ctx.errReported)
+
+	update.ExpiresAt = time.Now().Add(time.Hour).UnixNano() / int64(time.Millisecond)
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+}
@@ -0,0 +1,259 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/keybase/go-updater/util"
+)
+
+// DefaultHistoryLimit is the default size limit (in bytes) for the history
+const DefaultHistoryLimit int64 = 1024 * 1024
+
+// HistoryStage is a stage of an update attempt
+type HistoryStage string
+
+const (
+	// HistoryStageCheck is checking for an update
+	HistoryStageCheck HistoryStage = "check"
+	// HistoryStageDownload is downloading the update
+	HistoryStageDownload HistoryStage = "download"
+	// HistoryStagePrompt is prompting the user for the update
+	HistoryStagePrompt HistoryStage = "prompt"
+	// HistoryStageVerify is verifying the update
+	HistoryStageVerify HistoryStage = "verify"
+	// HistoryStageApply is applying the update
+	HistoryStageApply HistoryStage = "apply"
+	// HistoryStageDone is after the update was applied
+	HistoryStageDone HistoryStage = "done"
+)
+
+// HistoryEntry is the record of an update attempt
+type HistoryEntry struct {
+	// Time is when the attempt started
+	Time time.Time `json:"time"`
+	// FromVersion is the version we were updating from
+	FromVersion string `json:"fromVersion"`
+	// ToVersion is the version of the update, if one was found
+	ToVersion string `json:"toVersion,omitempty"`
+	// Stage is the last stage reached
+	Stage HistoryStage `json:"stage"`
+	// ErrorType is the type of error, if the attempt failed
+	ErrorType string `json:"errorType,omitempty"`
+	// Error is the error, if the attempt failed
+	Error string `json:"error,omitempty"`
+	// Durations is how long (in milliseconds) each stage took
+	Durations map[HistoryStage]int64 `json:"durationsMs"`
+	// BytesDownloaded is how much was downloaded
+	BytesDownloaded int64 `json:"bytesDownloaded"`
+}
+
+// Failed returns true if the attempt failed
+func (e HistoryEntry) Failed() bool {
+	return e.Error != ""
+}
+
+// HistoryQuery selects entries from the history. The zero value selects all
+// of them.
+type HistoryQuery struct {
+	// Since selects entries at or after a time
+	Since time.Time
+	// Version selects entries updating to a version
+	Version string
+	// Failed selects entries for attempts that failed
+	Failed bool
+	// Limit is the maximum number of (most recent) entries, if not 0
+	Limit int
+}
+
+func (q HistoryQuery) matches(entry HistoryEntry) bool {
+	if !q.Since.IsZero() && entry.Time.Before(q.Since) {
+		return false
+	}
+	if q.Version != "" && entry.ToVersion != q.Version {
+		return false
+	}
+	if q.Failed && !entry.Failed() {
+		return false
+	}
+	return true
+}
+
+// attempt records an update attempt as it goes, for the history
+type attempt struct {
+	entry      HistoryEntry
+	stageStart time.Time
+}
+
+func newAttempt(options UpdateOptions) *attempt {
+	now := time.Now()
+	return &attempt{
+		entry: HistoryEntry{
+			Time:        now,
+			FromVersion: options.Version,
+			Stage:       HistoryStageCheck,
+			Durations:   map[HistoryStage]int64{},
+		},
+		stageStart: now,
+	}
+}
+
+// stage ends the current stage, and starts the next
+func (a *attempt) stage(stage HistoryStage) {
+	now := time.Now()
+	a.entry.Durations[a.entry.Stage] += int64(now.Sub(a.stageStart) / time.Millisecond)
+	a.entry.Stage = stage
+	a.stageStart = now
+}
+
+// downloaded adds to the bytes downloaded
+func (a *attempt) downloaded(n int64) {
+	a.entry.BytesDownloaded += n
+}
+
+// finish ends the attempt, and returns the entry for it
+func (a *attempt) finish(update *Update, err error) HistoryEntry {
+	stage := a.entry.Stage
+	a.stage(stage)
+	if update != nil {
+		a.entry.ToVersion = update.Version
+	}
+	if err != nil {
+		a.entry.Error = err.Error()
+		if e, ok := err.(Error); ok {
+			a.entry.ErrorType = e.TypeString()
+		}
+	}
+	return a.entry
+}
+
+// SetHistoryPath sets where the update history is saved. If not set (the
+// default), there is no history.
+func (u *Updater) SetHistoryPath(path string) {
+	u.historyPath = path
+}
+
+// SetHistoryLimit sets the size limit (in bytes) for the history. If 0,
+// DefaultHistoryLimit is used.
+func (u *Updater) SetHistoryLimit(limit int64) {
+	u.historyLimit = limit
+}
+
+// recordAttempt adds an attempt to the history. A check that didn't need an
+// update (and had no error) isn't recorded. An error saving the history is
+// logged.
+func (u *Updater) recordAttempt(a *attempt, update *Update, err error) {
+	if u.historyPath == "" || (err == nil && (update == nil || !update.NeedUpdate)) {
+		return
+	}
+	limit := u.historyLimit
+	if limit <= 0 {
+		limit = DefaultHistoryLimit
+	}
+	if err := appendHistory(u.historyPath, a.finish(update, err), limit, u.log); err != nil {
+		u.log.Warningf("Error saving history: %s", err)
+	}
+}
+
+// History returns the entries in the update history (oldest first) selected
+// by query
+func (u *Updater) History(query HistoryQuery) ([]HistoryEntry, error) {
+	if u.historyPath == "" {
+		return nil, nil
+	}
+	return ReadHistory(u.historyPath, query)
+}
+
+// ReadHistory returns the entries in the history file at path (oldest first)
+// selected by query. Lines that aren't valid entries are skipped.
+func ReadHistory(path string, query HistoryQuery) ([]HistoryEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer util.Close(file)
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if query.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[len(entries)-query.Limit:]
+	}
+	return entries, nil
+}
+
+// appendHistory appends an entry to the history file at path. If the file is
+// then over limit, older entries are dropped, keeping the newest that fit in
+// half the limit.
+func appendHistory(path string, entry HistoryEntry, limit int64, log Log) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	if err := util.MakeParentDirs(path, 0700, log); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(line)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Size() <= limit {
+		return err
+	}
+	return truncateHistory(path, limit/2, log)
+}
+
+// truncateHistory drops the oldest entries in the history file, so it is at
+// most size bytes
+func truncateHistory(path string, size int64, log Log) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > size {
+		cut := int64(len(data)) - size
+		partial := data[cut-1] != '\n'
+		data = data[cut:]
+		// Drop the partial line at the start, if we didn't cut at the end of
+		// a line
+		if partial {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				return fmt.Errorf("History entry is larger than the limit")
+			}
+			data = data[i+1:]
+		}
+	}
+	return util.NewFile(path, data, 0600).Save(log)
+}
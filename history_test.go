@@ -0,0 +1,159 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testHistoryPath(t *testing.T) string {
+	dir, err := util.MakeTempDir("TestHistory.", 0700)
+	require.NoError(t, err)
+	t.Cleanup(func() { util.RemoveFileAtPath(dir) })
+	return filepath.Join(dir, "updater.history")
+}
+
+func TestUpdaterHistory(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+	require.NoError(t, err)
+	upr.SetHistoryPath(testHistoryPath(t))
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true})
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+
+	entries, err := upr.History(HistoryQuery{})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	entry := entries[0]
+	assert.Equal(t, "1.0.0", entry.FromVersion)
+	assert.Equal(t, "1.0.1", entry.ToVersion)
+	assert.Equal(t, HistoryStageDone, entry.Stage)
+	assert.False(t, entry.Failed())
+	assert.Equal(t, "", entry.ErrorType)
+	assert.Equal(t, fileSize(testZipPath), entry.BytesDownloaded)
+	for _, stage := range []HistoryStage{HistoryStageCheck, HistoryStageDownload, HistoryStagePrompt, HistoryStageVerify, HistoryStageApply} {
+		_, ok := entry.Durations[stage]
+		assert.True(t, ok, "missing duration for %s", stage)
+	}
+}
+
+func TestUpdaterHistoryError(t *testing.T) {
+	testServer := testServerForError(t, fmt.Errorf("bad response"))
+	defer testServer.Close()
+
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+	require.NoError(t, err)
+	upr.SetHistoryPath(testHistoryPath(t))
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true})
+	_, err = upr.Update(ctx)
+	require.Error(t, err)
+
+	entries, err := upr.History(HistoryQuery{Failed: true})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, HistoryStageDownload, entries[0].Stage)
+	assert.Equal(t, "download", entries[0].ErrorType)
+	assert.Equal(t, "Update Error (download): Responded with 500 Internal Server Error", entries[0].Error)
+}
+
+func TestUpdaterHistoryNotNeeded(t *testing.T) {
+	upr, err := newTestUpdaterWithServer(t, nil, newTestUpdate("", false), &testConfig{})
+	require.NoError(t, err)
+	upr.SetHistoryPath(testHistoryPath(t))
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true})
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+
+	entries, err := upr.History(HistoryQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(entries))
+}
+
+func TestReadHistoryQuery(t *testing.T) {
+	path := testHistoryPath(t)
+	now := time.Now()
+	for i, entry := range []HistoryEntry{
+		{Time: now.Add(-48 * time.Hour), ToVersion: "1.0.1", Stage: HistoryStageDone},
+		{Time: now.Add(-time.Hour), ToVersion: "1.0.2", Stage: HistoryStageVerify, Error: "bad signature", ErrorType: "verify"},
+		{Time: now, ToVersion: "1.0.2", Stage: HistoryStageDone},
+	} {
+		err := appendHistory(path, entry, DefaultHistoryLimit, testLog)
+		require.NoError(t, err, "entry %d", i)
+	}
+
+	entries, err := ReadHistory(path, HistoryQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(entries))
+
+	entries, err = ReadHistory(path, HistoryQuery{Since: now.Add(-24 * time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+
+	entries, err = ReadHistory(path, HistoryQuery{Version: "1.0.2", Failed: true})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "bad signature", entries[0].Error)
+
+	entries, err = ReadHistory(path, HistoryQuery{Limit: 1})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, HistoryStageDone, entries[0].Stage)
+	assert.Equal(t, "1.0.2", entries[0].ToVersion)
+
+	entries, err = ReadHistory(path+".missing", HistoryQuery{})
+	require.NoError(t, err)
+	assert.Nil(t, entries)
+}
+
+func TestHistoryLimit(t *testing.T) {
+	path := testHistoryPath(t)
+	var limit int64 = 2048
+	for i := 0; i < 100; i++ {
+		entry := HistoryEntry{Time: time.Now(), ToVersion: fmt.Sprintf("1.0.%d", i), Stage: HistoryStageDone}
+		err := appendHistory(path, entry, limit, testLog)
+		require.NoError(t, err)
+	}
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, info.Size() <= limit)
+
+	// The newest entries are kept
+	entries, err := ReadHistory(path, HistoryQuery{})
+	require.NoError(t, err)
+	require.True(t, len(entries) > 0)
+	for i, entry := range entries {
+		assert.Equal(t, fmt.Sprintf("1.0.%d", 100-len(entries)+i), entry.ToVersion)
+	}
+}
+
+func TestTruncateHistoryLineBoundary(t *testing.T) {
+	path := testHistoryPath(t)
+	err := os.WriteFile(path, []byte("one\ntwo\nthree\n"), 0600)
+	require.NoError(t, err)
+
+	// A cut at the end of a line keeps the whole line after it
+	err = truncateHistory(path, int64(len("two\nthree\n")), testLog)
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "two\nthree\n", string(data))
+
+	// A cut in a line drops the rest of it
+	err = truncateHistory(path, int64(len("wo\nthree\n")), testLog)
+	require.NoError(t, err)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "three\n", string(data))
+}
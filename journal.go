@@ -0,0 +1,209 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/keybase/go-updater/util"
+)
+
+// JournalState is the state of an update recorded in the journal
+type JournalState string
+
+const (
+	// JournalDownloaded is after the update asset was downloaded
+	JournalDownloaded JournalState = "downloaded"
+	// JournalVerified is after the update asset was verified
+	JournalVerified JournalState = "verified"
+	// JournalApplying is while the update is being applied. The previous
+	// install may have been moved aside to the backup path.
+	JournalApplying JournalState = "applying"
+	// JournalApplied is after the update was applied, but before it was checked
+	JournalApplied JournalState = "applied"
+	// JournalCommitted is after the update was applied and checked
+	JournalCommitted JournalState = "committed"
+)
+
+// Journal records the state of an update, so that if the updater is
+// interrupted while applying (say, by power loss), it can finish or undo the
+// update the next time it starts.
+type Journal struct {
+	State           JournalState `json:"state"`
+	Version         string       `json:"version"`
+	AssetPath       string       `json:"assetPath,omitempty"`
+	DestinationPath string       `json:"destinationPath,omitempty"`
+	TmpDir          string       `json:"tmpDir,omitempty"`
+	BackupPath      string       `json:"backupPath,omitempty"`
+	UpdatedAt       time.Time    `json:"updatedAt"`
+}
+
+// isPending returns true if the journal is for an update that was interrupted
+// while it was being applied
+func (j Journal) isPending() bool {
+	return j.State == JournalApplying || j.State == JournalApplied
+}
+
+// SetJournalPath sets where the update journal is saved. If not set (the
+// default), there is no journal.
+func (u *Updater) SetJournalPath(path string) {
+	u.journalPath = path
+}
+
+// ReadJournal returns the journal for the last update, or nil if there isn't
+// one.
+func (u *Updater) ReadJournal() (*Journal, error) {
+	if u.journalPath == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(u.journalPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var journal Journal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, fmt.Errorf("Invalid journal: %s", err)
+	}
+	return &journal, nil
+}
+
+// journal saves the update state. An error saving the journal is logged, but
+// doesn't stop the update.
+func (u *Updater) journal(state JournalState, update Update, options UpdateOptions, tmpDir string) {
+	if u.journalPath == "" {
+		return
+	}
+	journal := Journal{
+		State:           state,
+		Version:         update.Version,
+		DestinationPath: options.DestinationPath,
+		TmpDir:          tmpDir,
+		BackupPath:      backupPath(options, tmpDir),
+		UpdatedAt:       time.Now(),
+	}
+	if update.Asset != nil {
+		journal.AssetPath = update.Asset.LocalPath
+	}
+	data, err := json.MarshalIndent(journal, "", "  ")
+	if err != nil {
+		u.log.Warningf("Error encoding journal: %s", err)
+		return
+	}
+	if err := util.MakeParentDirs(u.journalPath, 0700, u.log); err != nil {
+		u.log.Warningf("Error creating journal dir: %s", err)
+		return
+	}
+	if err := util.NewFile(u.journalPath, data, 0600).Save(u.log); err != nil {
+		u.log.Warningf("Error saving journal: %s", err)
+	}
+}
+
+// clearJournal removes the journal
+func (u *Updater) clearJournal() {
+	if u.journalPath == "" {
+		return
+	}
+	if err := os.Remove(u.journalPath); err != nil && !os.IsNotExist(err) {
+		u.log.Warningf("Error removing journal: %s", err)
+	}
+}
+
+// pendingTmpDir returns the temp dir of an interrupted update (which may hold
+// the previous install), so it isn't cleaned up.
+func (u *Updater) pendingTmpDir() string {
+	journal, err := u.ReadJournal()
+	if err != nil || journal == nil || !journal.isPending() {
+		return ""
+	}
+	return journal.TmpDir
+}
+
+// Recover checks the journal for an update that was interrupted while it was
+// being applied. If the update was applied, it is finished, otherwise the
+// previous install is restored. This should be called on start, before
+// anything else. It holds the update lock while recovering.
+func (u *Updater) Recover() error {
+	lock, err := u.lock(context.Background())
+	if err != nil {
+		return err
+	}
+	defer lock.unlock()
+	return u.recoverJournal()
+}
+
+func (u *Updater) recoverJournal() error {
+	journal, err := u.ReadJournal()
+	if err != nil {
+		u.clearJournal()
+		return err
+	}
+	if journal == nil {
+		return nil
+	}
+	if journal.isPending() {
+		if err := u.recoverApply(*journal); err != nil {
+			return err
+		}
+	}
+	u.clearJournal()
+	return nil
+}
+
+func (u *Updater) recoverApply(journal Journal) error {
+	destExists, err := util.FileExists(journal.DestinationPath)
+	if err != nil {
+		return err
+	}
+	if journal.State == JournalApplied && destExists {
+		u.log.Infof("Finishing interrupted update to %s", journal.Version)
+		u.removeTempDir(journal.TmpDir)
+		return nil
+	}
+
+	backupExists := false
+	if journal.BackupPath != "" {
+		if backupExists, err = util.FileExists(journal.BackupPath); err != nil {
+			return err
+		}
+	}
+	if !backupExists {
+		if !destExists {
+			return fmt.Errorf("Update to %s was interrupted, and there is no install at %s or previous install to restore", journal.Version, journal.DestinationPath)
+		}
+		// The previous install wasn't moved aside, so there's nothing to undo
+		u.log.Infof("Update to %s was interrupted before the previous install was replaced", journal.Version)
+		u.removeTempDir(journal.TmpDir)
+		return nil
+	}
+
+	u.log.Warningf("Update to %s was interrupted, restoring previous install from %s", journal.Version, journal.BackupPath)
+	if err := util.MoveFile(journal.BackupPath, journal.DestinationPath, "", u.log); err != nil {
+		return fmt.Errorf("Error restoring previous install: %s", err)
+	}
+	u.removeTempDir(journal.TmpDir)
+	return nil
+}
+
+// recoverOnStart recovers from the journal, reporting (but otherwise ignoring)
+// an error. The caller should hold the update lock.
+func (u *Updater) recoverOnStart(ctx Context, options UpdateOptions) {
+	if err := u.recoverJournal(); err != nil {
+		u.log.Errorf("Error recovering interrupted update: %s", err)
+		ctx.ReportError(rollbackErr(err), nil, options)
+	}
+}
+
+// isPendingTmpDir returns true if the path is the temp dir of an interrupted
+// update
+func isPendingTmpDir(path string, pendingTmpDir string) bool {
+	return pendingTmpDir != "" && filepath.Clean(path) == filepath.Clean(pendingTmpDir)
+}
@@ -0,0 +1,263 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestJournal returns an updater (with a journal) and options for an
+// install at a temporary destination path
+func newTestJournal(t *testing.T) (*Updater, UpdateOptions, string) {
+	upr, err := newTestUpdater(t)
+	require.NoError(t, err)
+	dir, err := util.MakeTempDir("TestJournal.", 0700)
+	require.NoError(t, err)
+	upr.SetJournalPath(filepath.Join(dir, "updater.journal"))
+	options := newDefaultTestUpdateOptions()
+	options.DestinationPath = filepath.Join(dir, "Test")
+	return upr, options, dir
+}
+
+// interruptedUpdate saves a journal for an update in state, with "old" in the
+// backup (if backup) and "new" at the destination (if dest)
+func interruptedUpdate(t *testing.T, upr *Updater, options UpdateOptions, state JournalState, backup bool, dest bool) string {
+	tmpDir, err := util.MakeTempDir("KeybaseUpdater.", 0700)
+	require.NoError(t, err)
+	if backup {
+		err = os.WriteFile(backupPath(options, tmpDir), []byte("old"), 0600)
+		require.NoError(t, err)
+	}
+	if dest {
+		err = os.WriteFile(options.DestinationPath, []byte("new"), 0600)
+		require.NoError(t, err)
+	}
+	upr.journal(state, *testUpdate(""), options, tmpDir)
+	return tmpDir
+}
+
+func assertFile(t *testing.T, path string, expected string) {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(data))
+}
+
+func TestUpdaterJournal(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	upr, options, dir := newTestJournal(t)
+	defer util.RemoveFileAtPath(dir)
+	err := os.WriteFile(options.DestinationPath, []byte("old"), 0600)
+	require.NoError(t, err)
+	upr.source = testUpdateSource{testServer: testServer, config: upr.config, update: testUpdate(testServer.URL)}
+	ctx := &testHealthCheckUI{
+		testUpdateUI: newTestContext(options, upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true}),
+	}
+
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+	journal, err := upr.ReadJournal()
+	require.NoError(t, err)
+	require.NotNil(t, journal)
+	assert.Equal(t, JournalCommitted, journal.State)
+	assert.Equal(t, "1.0.1", journal.Version)
+	assert.Equal(t, options.DestinationPath, journal.DestinationPath)
+
+	// Recovering a committed update just clears the journal
+	err = upr.Recover()
+	require.NoError(t, err)
+	journal, err = upr.ReadJournal()
+	require.NoError(t, err)
+	assert.Nil(t, journal)
+	assertFile(t, options.DestinationPath, "new")
+
+	// A rollback clears the journal
+	err = os.WriteFile(options.DestinationPath, []byte("old"), 0600)
+	require.NoError(t, err)
+	ctx.healthCheckErr = fmt.Errorf("Test health check error")
+	_, err = upr.Update(ctx)
+	require.Error(t, err)
+	journal, err = upr.ReadJournal()
+	require.NoError(t, err)
+	assert.Nil(t, journal)
+}
+
+// testRestoreFailUI replaces the install, and then fails to apply, leaving a
+// file where the parent dir of the install was, so it can't be restored
+type testRestoreFailUI struct {
+	*testUpdateUI
+}
+
+func (u *testRestoreFailUI) Apply(update Update, options UpdateOptions, tmpDir string) error {
+	if err := util.MoveFile(options.DestinationPath, backupPath(options, tmpDir), "", testLog); err != nil {
+		return err
+	}
+	parent := filepath.Dir(options.DestinationPath)
+	if err := os.RemoveAll(parent); err != nil {
+		return err
+	}
+	if err := os.WriteFile(parent, []byte("broken"), 0600); err != nil {
+		return err
+	}
+	return fmt.Errorf("Test apply error")
+}
+
+func TestUpdaterRollbackRestoreFails(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	upr, options, dir := newTestJournal(t)
+	defer util.RemoveFileAtPath(dir)
+	options.DestinationPath = filepath.Join(dir, "install", "Test")
+	err := util.MakeParentDirs(options.DestinationPath, 0700, testLog)
+	require.NoError(t, err)
+	err = os.WriteFile(options.DestinationPath, []byte("old"), 0600)
+	require.NoError(t, err)
+	upr.source = testUpdateSource{testServer: testServer, config: upr.config, update: testUpdate(testServer.URL)}
+	ctx := &testRestoreFailUI{
+		testUpdateUI: newTestContext(options, upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true}),
+	}
+
+	_, err = upr.Update(ctx)
+	require.Error(t, err)
+	assert.Equal(t, RollbackError, err.(Error).errorType)
+
+	// The journal and backup are kept, for Recover
+	journal, err := upr.ReadJournal()
+	require.NoError(t, err)
+	require.NotNil(t, journal)
+	assert.Equal(t, JournalApplying, journal.State)
+	assertFile(t, journal.BackupPath, "old")
+	err = upr.CleanupPreviousUpdates()
+	require.NoError(t, err)
+	assertFile(t, journal.BackupPath, "old")
+
+	util.RemoveFileAtPath(filepath.Dir(options.DestinationPath))
+	err = upr.Recover()
+	require.NoError(t, err)
+	assertFile(t, options.DestinationPath, "old")
+	exists, err := util.FileExists(journal.TmpDir)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestUpdaterTempDir(t *testing.T) {
+	upr, err := newTestUpdater(t)
+	require.NoError(t, err)
+	tmpDir := upr.tempDir()
+	require.NotEqual(t, "", tmpDir)
+	defer util.RemoveFileAtPath(tmpDir)
+
+	// In the cache dir, so the backup survives a reboot
+	cacheDir, err := upr.openCache()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cacheDir, "tmp"), filepath.Dir(tmpDir))
+
+	// Left by a previous update
+	err = upr.CleanupPreviousUpdates()
+	require.NoError(t, err)
+	exists, err := util.FileExists(tmpDir)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestRecoverApplying(t *testing.T) {
+	upr, options, dir := newTestJournal(t)
+	defer util.RemoveFileAtPath(dir)
+
+	// Interrupted after the previous install was moved aside
+	tmpDir := interruptedUpdate(t, upr, options, JournalApplying, true, false)
+	err := upr.Recover()
+	require.NoError(t, err)
+	assertFile(t, options.DestinationPath, "old")
+	exists, err := util.FileExists(tmpDir)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	journal, err := upr.ReadJournal()
+	require.NoError(t, err)
+	assert.Nil(t, journal)
+
+	// Interrupted while the new install was being moved into place
+	interruptedUpdate(t, upr, options, JournalApplying, true, true)
+	err = upr.Recover()
+	require.NoError(t, err)
+	assertFile(t, options.DestinationPath, "old")
+
+	// Interrupted before the previous install was moved aside
+	interruptedUpdate(t, upr, options, JournalApplying, false, true)
+	err = upr.Recover()
+	require.NoError(t, err)
+	assertFile(t, options.DestinationPath, "new")
+}
+
+func TestRecoverApplied(t *testing.T) {
+	upr, options, dir := newTestJournal(t)
+	defer util.RemoveFileAtPath(dir)
+
+	tmpDir := interruptedUpdate(t, upr, options, JournalApplied, true, true)
+	err := upr.Recover()
+	require.NoError(t, err)
+	assertFile(t, options.DestinationPath, "new")
+	exists, err := util.FileExists(tmpDir)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Applied, but the new install is missing
+	util.RemoveFileAtPath(options.DestinationPath)
+	interruptedUpdate(t, upr, options, JournalApplied, true, false)
+	err = upr.Recover()
+	require.NoError(t, err)
+	assertFile(t, options.DestinationPath, "old")
+}
+
+func TestRecoverNoInstall(t *testing.T) {
+	upr, options, dir := newTestJournal(t)
+	defer util.RemoveFileAtPath(dir)
+
+	tmpDir := interruptedUpdate(t, upr, options, JournalApplying, false, false)
+	defer util.RemoveFileAtPath(tmpDir)
+	err := upr.Recover()
+	require.Error(t, err)
+
+	// The journal is kept, and so is the temp dir
+	journal, err := upr.ReadJournal()
+	require.NoError(t, err)
+	require.NotNil(t, journal)
+	err = upr.CleanupPreviousUpdates()
+	require.NoError(t, err)
+	exists, err := util.FileExists(tmpDir)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestRecoverInvalidJournal(t *testing.T) {
+	upr, _, dir := newTestJournal(t)
+	defer util.RemoveFileAtPath(dir)
+
+	err := os.WriteFile(upr.journalPath, []byte("invalid"), 0600)
+	require.NoError(t, err)
+	err = upr.Recover()
+	require.Error(t, err)
+	journal, err := upr.ReadJournal()
+	require.NoError(t, err)
+	assert.Nil(t, journal)
+}
+
+func TestRecoverNoJournal(t *testing.T) {
+	upr, err := newTestUpdater(t)
+	require.NoError(t, err)
+	err = upr.Recover()
+	require.NoError(t, err)
+	journal, err := upr.ReadJournal()
+	require.NoError(t, err)
+	assert.Nil(t, journal)
+}
@@ -11,6 +11,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/keybase/go-updater"
@@ -26,6 +27,13 @@ type Config interface {
 	notifyProgram() string
 	destinationPath() string
 	updaterOptions() updater.UpdateOptions
+	lockedValues() map[string]interface{}
+	promptDisabled() bool
+	updatesDisabled() bool
+	endpoints() Endpoints
+	caCerts() string
+	requireSignedManifest() bool
+	signatureThreshold() int
 }
 
 type config struct {
@@ -35,12 +43,28 @@ type config struct {
 	pathToKeybase string
 	// log is the logging location
 	log Log
+	// mtx guards store, autoOverride, managed and managedErr, since the update
+	// checker and the control API use the config at the same time
+	mtx sync.Mutex
 	// store is the config values
 	store store
 	// autoOverride is whether the current auto setting should be temporarily overridden
 	autoOverride bool
 	// ignoreSnooze corresponds to UpdateOptions.IgnoreSnooze
 	ignoreSnooze bool
+	// force corresponds to UpdateOptions.Force, see ContextOptions
+	force bool
+	// managedPolicyPath is where the system policy is loaded from
+	managedPolicyPath string
+	// managed is the system policy, which overrides (and locks) store values
+	managed managedPolicy
+	// managedErr is why the system policy couldn't be loaded, if it exists but
+	// couldn't be. Updates are disabled until it loads.
+	managedErr error
+	// urls are the endpoints to use
+	urls Endpoints
+	// certs is the PEM of the CA certificates to trust
+	certs string
 }
 
 // store is the config values
@@ -55,6 +79,49 @@ type store struct {
 	AutoSet bool `json:"autoSet"`
 	// LastAppliedVersion is for detecting upgrade error condition
 	LastAppliedVersion string `json:"lastAppliedVersion"`
+	// Channel is an alternative channel to get updates from
+	Channel string `json:"channel,omitempty"`
+	// CacheLimit is the size limit (in bytes) for downloaded updates. If 0, the
+	// updater default is used.
+	CacheLimit int64 `json:"cacheLimit,omitempty"`
+	// SnoozeVersion is the version the user snoozed
+	SnoozeVersion string `json:"snoozeVersion,omitempty"`
+	// SnoozeUntil is when the snooze is over (in milliseconds since epoch)
+	SnoozeUntil int64 `json:"snoozeUntil,omitempty"`
+	// LatestSeen is the newest update response seen, by channel
+	LatestSeen map[string]updater.SeenUpdate `json:"latestSeen,omitempty"`
+	// MaintenanceWindow is when updates can be applied automatically. If nil,
+	// updates can be applied any time.
+	MaintenanceWindow *updater.MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+	// LastCheck is the result of the last update check
+	LastCheck *updater.CheckResult `json:"lastCheck,omitempty"`
+	// Reporters are where update events are reported. If empty, they are
+	// reported to the Keybase API server.
+	Reporters []ReporterConfig `json:"reporters,omitempty"`
+	// Endpoints override the Keybase API endpoints (for those set)
+	Endpoints *Endpoints `json:"endpoints,omitempty"`
+	// CACerts are PEM files with CA certificates to trust, as well as the
+	// Keybase CA
+	CACerts []string `json:"caCerts,omitempty"`
+	// TUF enables verifying updates against signed metadata, if set
+	TUF *TUFConfig `json:"tuf,omitempty"`
+	// RequireSignedManifest only accepts updates with a signed manifest
+	RequireSignedManifest bool `json:"requireSignedManifest,omitempty"`
+	// SignatureThresholds is how many (distinct) code signers must sign an
+	// asset, by channel ("" is the default channel). If not set, it is 1.
+	SignatureThresholds map[string]int `json:"signatureThresholds,omitempty"`
+}
+
+// ReporterConfig configures where update events are reported
+type ReporterConfig struct {
+	// Type is keybase (the Keybase API server), http, file or syslog
+	Type string `json:"type"`
+	// URL is where events are posted, for http
+	URL string `json:"url,omitempty"`
+	// Path is the file events are appended to, for file
+	Path string `json:"path,omitempty"`
+	// Tag is the syslog tag, for syslog (keybase.updater if empty)
+	Tag string `json:"tag,omitempty"`
 }
 
 // newConfig loads a config, which is valid even if it has an error
@@ -66,15 +133,20 @@ func newConfig(appName string, pathToKeybase string, log Log, ignoreSnooze bool)
 
 func newDefaultConfig(appName string, pathToKeybase string, log Log, ignoreSnooze bool) config {
 	return config{
-		appName:       appName,
-		pathToKeybase: pathToKeybase,
-		log:           log,
-		ignoreSnooze:  ignoreSnooze,
+		appName:           appName,
+		pathToKeybase:     pathToKeybase,
+		log:               log,
+		ignoreSnooze:      ignoreSnooze,
+		managedPolicyPath: defaultManagedPolicyPath(),
+		urls:              defaultEndpoints,
+		certs:             caCert,
 	}
 }
 
-// load the config
+// load the config, and the system policy over it. An error loading the policy
+// is logged (see loadManagedPolicy).
 func (c *config) load() error {
+	_ = c.loadManagedPolicy()
 	path, err := c.path()
 	if err != nil {
 		return nil
@@ -97,11 +169,100 @@ func (c *config) loadFromPath(path string) error {
 	if err := decoder.Decode(&decodeStore); err != nil {
 		return err
 	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
 	c.store = decodeStore
 	return nil
 }
 
-func (c config) path() (string, error) {
+// loadManagedPolicy loads the system policy. If it exists but can't be
+// loaded, we fail closed: updates are disabled, and auto update can't be set,
+// until it loads (see reloadManagedPolicy).
+func (c *config) loadManagedPolicy() error {
+	policy, err := loadManagedPolicy(c.managedPolicyPath)
+	if err != nil {
+		c.log.Errorf("Error loading system policy (updates are disabled): %s", err)
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.managed, c.managedErr = policy, err
+	return err
+}
+
+// reloadManagedPolicy loads the system policy again, if it couldn't be loaded
+// before
+func (c *config) reloadManagedPolicy() error {
+	if _, err := c.systemPolicy(); err == nil {
+		return nil
+	}
+	return c.loadManagedPolicy()
+}
+
+// systemPolicy returns the system policy, and the error loading it, if any
+func (c *config) systemPolicy() (managedPolicy, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.managed, c.managedErr
+}
+
+// lockedValues returns the values the system policy sets, by key, which the
+// user can't change
+func (c *config) lockedValues() map[string]interface{} {
+	policy, _ := c.systemPolicy()
+	return policy.locked()
+}
+
+// promptDisabled is whether the system policy applies updates without
+// prompting
+func (c *config) promptDisabled() bool {
+	policy, _ := c.systemPolicy()
+	return policy.DisablePrompt != nil && *policy.DisablePrompt
+}
+
+// updatesDisabled is whether the system policy blocks updates, or couldn't be
+// loaded
+func (c *config) updatesDisabled() bool {
+	if err := c.reloadManagedPolicy(); err != nil {
+		return true
+	}
+	policy, _ := c.systemPolicy()
+	return policy.DisableUpdates != nil && *policy.DisableUpdates
+}
+
+// requireSignedManifest is whether updates must have a signed manifest, which
+// the system policy can lock
+func (c *config) requireSignedManifest() bool {
+	if policy, _ := c.systemPolicy(); policy.RequireSignedManifest != nil {
+		return *policy.RequireSignedManifest
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.store.RequireSignedManifest
+}
+
+// signatureThreshold is how many code signers must sign an asset, on the
+// current channel
+func (c *config) signatureThreshold() int {
+	channel := c.channel()
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if threshold := c.store.SignatureThresholds[channel]; threshold > 1 {
+		return threshold
+	}
+	return 1
+}
+
+// channel is the update channel, which the system policy can pin
+func (c *config) channel() string {
+	if policy, _ := c.systemPolicy(); policy.Channel != nil {
+		return *policy.Channel
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.store.Channel
+}
+
+func (c *config) path() (string, error) {
 	configDir, err := Dir(c.appName)
 	if err != nil {
 		return "", err
@@ -113,7 +274,80 @@ func (c config) path() (string, error) {
 	return path, nil
 }
 
-func (c config) updateCheckTouchPath() (string, error) {
+// cacheDir is where downloaded updates are kept
+func (c *config) cacheDir() (string, error) {
+	cacheDir, err := CacheDir(c.appName)
+	if err != nil {
+		return "", err
+	}
+	if cacheDir == "" {
+		return "", fmt.Errorf("No cache dir")
+	}
+	return filepath.Join(cacheDir, "updater-cache"), nil
+}
+
+// cacheLimit is the size limit for downloaded updates
+func (c *config) cacheLimit() int64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.store.CacheLimit
+}
+
+// journalPath is where the update journal is saved
+func (c *config) journalPath() (string, error) {
+	configDir, err := Dir(c.appName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "updater.journal"), nil
+}
+
+// lockPath is where the update lock is. It's next to the install (the
+// destination path), so updaters run by different users of an install don't
+// update it at the same time. If there is no destination path (on Linux), it's
+// "", and the updater default (in the cache dir) is used.
+func (c *config) lockPath() string {
+	destinationPath := c.destinationPath()
+	if destinationPath == "" {
+		return ""
+	}
+	destinationPath = filepath.Clean(destinationPath)
+	return filepath.Join(filepath.Dir(destinationPath), fmt.Sprintf(".%s.updater.lock", filepath.Base(destinationPath)))
+}
+
+// historyPath is where the update history is saved
+func (c *config) historyPath() (string, error) {
+	configDir, err := Dir(c.appName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "updater.history"), nil
+}
+
+// reportQueuePath is where reports waiting to be sent are saved
+func (c *config) reportQueuePath() (string, error) {
+	configDir, err := Dir(c.appName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "updater-reports.json"), nil
+}
+
+// policy loads how each type of update is applied, from update-policy.json in
+// the config dir. If there isn't one, returns nil.
+func (c *config) policy() (*updater.Policy, error) {
+	configDir, err := Dir(c.appName)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := updater.LoadPolicy(filepath.Join(configDir, "update-policy.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return policy, err
+}
+
+func (c *config) updateCheckTouchPath() (string, error) {
 	configDir, err := Dir(c.appName)
 	if err != nil {
 		return "", err
@@ -123,7 +357,7 @@ func (c config) updateCheckTouchPath() (string, error) {
 
 // IsLastUpdateCheckTimeRecent returns true if we've updated within duration.
 // If there is any kind of error, returns true.
-func (c config) IsLastUpdateCheckTimeRecent(d time.Duration) bool {
+func (c *config) IsLastUpdateCheckTimeRecent(d time.Duration) bool {
 	path, err := c.updateCheckTouchPath()
 	if err != nil {
 		c.log.Errorf("Error getting check path: %s", err)
@@ -143,8 +377,17 @@ func (c config) IsLastUpdateCheckTimeRecent(d time.Duration) bool {
 	return recent
 }
 
+// GetLastUpdateCheckTime returns when we last checked for an update
+func (c *config) GetLastUpdateCheckTime() (time.Time, error) {
+	path, err := c.updateCheckTouchPath()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return util.FileModTime(path)
+}
+
 // SetLastUpdateCheckTime touches file to set last update time.
-func (c config) SetLastUpdateCheckTime() {
+func (c *config) SetLastUpdateCheckTime() {
 	path, err := c.updateCheckTouchPath()
 	if err != nil {
 		c.log.Errorf("Error getting check path: %s", err)
@@ -158,15 +401,17 @@ func (c config) SetLastUpdateCheckTime() {
 	c.log.Debugf("Set last update time")
 }
 
-func (c config) save() error {
+func (c *config) save() error {
 	path, err := c.path()
 	if err != nil {
 		return err
 	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
 	return c.saveToPath(path)
 }
 
-func (c config) saveToPath(path string) error {
+func (c *config) saveToPath(path string) error {
 	b, err := json.MarshalIndent(c.store, "", "  ")
 	if err != nil {
 		return fmt.Errorf("Error marshaling config: %s", err)
@@ -181,49 +426,159 @@ func (c config) saveToPath(path string) error {
 
 // GetUpdateAuto is the whether to update automatically and whether the user has
 // set this value. Both should be true for an update to be automatically
-// applied.
-func (c config) GetUpdateAuto() (bool, bool) {
+// applied. If the system policy sets it, that value is used.
+func (c *config) GetUpdateAuto() (bool, bool) {
+	if policy, _ := c.systemPolicy(); policy.Auto != nil {
+		return *policy.Auto, true
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
 	return c.store.Auto, c.store.AutoSet
 }
 
+// SetUpdateAuto sets whether to update automatically. It errors if the
+// system policy sets a different value, or couldn't be loaded.
 func (c *config) SetUpdateAuto(auto bool) error {
+	if err := c.reloadManagedPolicy(); err != nil {
+		return fmt.Errorf("Auto update can't be set, the system policy couldn't be loaded: %s", err)
+	}
+	if policy, _ := c.systemPolicy(); policy.Auto != nil && *policy.Auto != auto {
+		return fmt.Errorf("Auto update is set by the system policy")
+	}
+	c.mtx.Lock()
 	c.store.Auto = auto
 	c.store.AutoSet = true
+	c.mtx.Unlock()
 	return c.save()
 }
 
 // For overriding the current Auto setting
-func (c config) GetUpdateAutoOverride() bool {
+func (c *config) GetUpdateAutoOverride() bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
 	return c.autoOverride
 }
 
 func (c *config) SetUpdateAutoOverride(auto bool) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
 	c.autoOverride = auto
 	return nil
 }
 
 // For reporting the last version applied
-func (c config) GetLastAppliedVersion() string {
+func (c *config) GetLastAppliedVersion() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
 	return c.store.LastAppliedVersion
 }
 
 func (c *config) SetLastAppliedVersion(version string) error {
+	c.mtx.Lock()
 	c.store.LastAppliedVersion = version
+	c.mtx.Unlock()
+	return c.save()
+}
+
+// GetSnooze returns the snoozed version and when the snooze is over
+func (c *config) GetSnooze() (string, time.Time) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.store.SnoozeVersion, time.Unix(0, c.store.SnoozeUntil*int64(time.Millisecond))
+}
+
+// SetSnooze snoozes version until a time, or clears the snooze if version is
+// empty
+func (c *config) SetSnooze(version string, until time.Time) error {
+	c.mtx.Lock()
+	c.store.SnoozeVersion = version
+	c.store.SnoozeUntil = 0
+	if version != "" {
+		c.store.SnoozeUntil = until.UnixNano() / int64(time.Millisecond)
+	}
+	c.mtx.Unlock()
+	return c.save()
+}
+
+// GetLatestSeen returns the newest update seen on a channel
+func (c *config) GetLatestSeen(channel string) (updater.SeenUpdate, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	seen, ok := c.store.LatestSeen[channel]
+	return seen, ok
+}
+
+// SetLatestSeen saves the newest update seen on a channel
+func (c *config) SetLatestSeen(channel string, seen updater.SeenUpdate) error {
+	c.mtx.Lock()
+	if c.store.LatestSeen == nil {
+		c.store.LatestSeen = map[string]updater.SeenUpdate{}
+	}
+	c.store.LatestSeen[channel] = seen
+	c.mtx.Unlock()
+	return c.save()
+}
+
+// reporterConfigs returns where update events are reported
+func (c *config) reporterConfigs() []ReporterConfig {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.store.Reporters
+}
+
+// GetMaintenanceWindow returns when updates can be applied automatically, or
+// nil for any time
+func (c *config) GetMaintenanceWindow() *updater.MaintenanceWindow {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.store.MaintenanceWindow
+}
+
+// SetMaintenanceWindow sets when updates can be applied automatically, or
+// clears it if window is nil
+func (c *config) SetMaintenanceWindow(window *updater.MaintenanceWindow) error {
+	if window != nil {
+		if err := window.Validate(); err != nil {
+			return err
+		}
+	}
+	c.mtx.Lock()
+	c.store.MaintenanceWindow = window
+	c.mtx.Unlock()
+	return c.save()
+}
+
+// GetLastCheckResult returns the result of the last update check, or nil
+func (c *config) GetLastCheckResult() *updater.CheckResult {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.store.LastCheck
+}
+
+// SetLastCheckResult saves the result of the last update check
+func (c *config) SetLastCheckResult(result updater.CheckResult) error {
+	c.mtx.Lock()
+	c.store.LastCheck = &result
+	c.mtx.Unlock()
 	return c.save()
 }
 
 // GetInstallID is an identifier returned by the API on first update that is a
 // sent on subsequent requests.
-func (c config) GetInstallID() string {
+func (c *config) GetInstallID() string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
 	return c.store.InstallID
 }
 
 func (c *config) SetInstallID(installID string) error {
+	c.mtx.Lock()
 	c.store.InstallID = installID
+	c.mtx.Unlock()
 	return c.save()
 }
 
-func (c config) updaterOptions() updater.UpdateOptions {
+func (c *config) updaterOptions() updater.UpdateOptions {
 	version := c.keybaseVersion()
 	osVersion := c.osVersion()
 	osArch := c.osArch()
@@ -241,14 +596,16 @@ func (c config) updaterOptions() updater.UpdateOptions {
 		OSVersion:       osVersion,
 		UpdaterVersion:  updater.Version,
 		IgnoreSnooze:    c.ignoreSnooze,
+		Channel:         c.channel(),
+		Force:           c.force,
 	}
 }
 
-func (c config) keybasePath() string {
+func (c *config) keybasePath() string {
 	return c.pathToKeybase
 }
 
-func (c config) keybaseVersion() string {
+func (c *config) keybaseVersion() string {
 	result, err := command.Exec(c.keybasePath(), []string{"version", "-S"}, 20*time.Second, c.log)
 	if err != nil {
 		c.log.Warningf("Couldn't get keybase version: %s (%s)", err, result.CombinedOutput())
@@ -4,10 +4,12 @@
 package keybase
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/keybase/go-updater"
 	"github.com/keybase/go-updater/util"
@@ -176,3 +178,219 @@ func TestKeybaseVersionInvalid(t *testing.T) {
 	version := cfg.keybaseVersion()
 	assert.Equal(t, "", version)
 }
+
+func TestConfigSnooze(t *testing.T) {
+	cfg, _ := testConfig(t)
+	configDir, err := Dir(cfg.appName)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(configDir)
+
+	version, _ := cfg.GetSnooze()
+	assert.Equal(t, "", version)
+
+	until := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	err = cfg.SetSnooze("1.0.1", until)
+	require.NoError(t, err)
+
+	path, err := cfg.path()
+	require.NoError(t, err)
+	loaded := newDefaultConfig(cfg.appName, cfg.pathToKeybase, testLog, false)
+	err = loaded.loadFromPath(path)
+	require.NoError(t, err)
+	version, snoozeUntil := loaded.GetSnooze()
+	assert.Equal(t, "1.0.1", version)
+	assert.True(t, until.Equal(snoozeUntil), "%s != %s", until, snoozeUntil)
+
+	err = cfg.SetSnooze("", time.Time{})
+	require.NoError(t, err)
+	version, _ = cfg.GetSnooze()
+	assert.Equal(t, "", version)
+}
+
+func TestConfigLatestSeen(t *testing.T) {
+	cfg, _ := testConfig(t)
+	configDir, err := Dir(cfg.appName)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(configDir)
+
+	_, ok := cfg.GetLatestSeen("")
+	assert.False(t, ok)
+	err = cfg.SetLatestSeen("", updater.SeenUpdate{Version: "1.0.1", PublishedAt: 1000})
+	require.NoError(t, err)
+	err = cfg.SetLatestSeen("prerelease", updater.SeenUpdate{Version: "1.0.2-1", PublishedAt: 2000})
+	require.NoError(t, err)
+
+	path, err := cfg.path()
+	require.NoError(t, err)
+	loaded := newDefaultConfig(cfg.appName, cfg.pathToKeybase, testLog, false)
+	err = loaded.loadFromPath(path)
+	require.NoError(t, err)
+	seen, ok := loaded.GetLatestSeen("")
+	assert.True(t, ok)
+	assert.Equal(t, updater.SeenUpdate{Version: "1.0.1", PublishedAt: 1000}, seen)
+	seen, _ = loaded.GetLatestSeen("prerelease")
+	assert.Equal(t, "1.0.2-1", seen.Version)
+}
+
+func TestConfigMaintenanceWindow(t *testing.T) {
+	cfg, _ := testConfig(t)
+	configDir, err := Dir(cfg.appName)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(configDir)
+
+	assert.Nil(t, cfg.GetMaintenanceWindow())
+
+	window := &updater.MaintenanceWindow{Start: "01:00", End: "05:00", Days: []string{"mon", "tue", "wed", "thu", "fri"}}
+	err = cfg.SetMaintenanceWindow(window)
+	require.NoError(t, err)
+
+	path, err := cfg.path()
+	require.NoError(t, err)
+	loaded := newDefaultConfig(cfg.appName, cfg.pathToKeybase, testLog, false)
+	err = loaded.loadFromPath(path)
+	require.NoError(t, err)
+	assert.Equal(t, window, loaded.GetMaintenanceWindow())
+
+	err = cfg.SetMaintenanceWindow(&updater.MaintenanceWindow{Start: "25:00", End: "05:00"})
+	require.Error(t, err)
+	assert.Equal(t, window, cfg.GetMaintenanceWindow())
+
+	err = cfg.SetMaintenanceWindow(nil)
+	require.NoError(t, err)
+	assert.Nil(t, cfg.GetMaintenanceWindow())
+}
+
+func TestConfigManagedPolicy(t *testing.T) {
+	cfg, _ := testConfig(t)
+	configDir, err := Dir(cfg.appName)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(configDir)
+
+	err = cfg.SetUpdateAuto(false)
+	require.NoError(t, err)
+	cfg.store.Channel = "test"
+	err = cfg.save()
+	require.NoError(t, err)
+
+	policyDir, err := util.MakeTempDir("TestConfigManagedPolicy.", 0700)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(policyDir)
+	cfg.managedPolicyPath = filepath.Join(policyDir, "updater-policy.json")
+	err = os.WriteFile(cfg.managedPolicyPath, []byte(`{"auto": true, "channel": "prerelease", "disablePrompt": true}`), 0600)
+	require.NoError(t, err)
+	err = cfg.load()
+	require.NoError(t, err)
+
+	// The policy is merged over the config
+	auto, autoSet := cfg.GetUpdateAuto()
+	assert.True(t, auto)
+	assert.True(t, autoSet)
+	assert.Equal(t, "prerelease", cfg.updaterOptions().Channel)
+	assert.True(t, cfg.promptDisabled())
+	assert.False(t, cfg.updatesDisabled())
+	assert.Equal(t, map[string]interface{}{"auto": true, "channel": "prerelease", "disablePrompt": true}, cfg.lockedValues())
+
+	// Locked values can't be changed
+	err = cfg.SetUpdateAuto(false)
+	require.EqualError(t, err, "Auto update is set by the system policy")
+	err = cfg.SetUpdateAuto(true)
+	require.NoError(t, err)
+
+	// The user's config is unchanged
+	path, err := cfg.path()
+	require.NoError(t, err)
+	loaded := newDefaultConfig(cfg.appName, cfg.pathToKeybase, testLog, false)
+	err = loaded.loadFromPath(path)
+	require.NoError(t, err)
+	assert.Equal(t, "test", loaded.store.Channel)
+
+	// An invalid policy fails closed
+	err = os.WriteFile(cfg.managedPolicyPath, []byte(`invalid`), 0600)
+	require.NoError(t, err)
+	err = cfg.load()
+	require.NoError(t, err)
+	assert.True(t, cfg.updatesDisabled())
+	err = cfg.SetUpdateAuto(true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Auto update can't be set, the system policy couldn't be loaded: Invalid system policy")
+
+	// Until it loads
+	err = os.WriteFile(cfg.managedPolicyPath, []byte(`{"channel": "prerelease"}`), 0600)
+	require.NoError(t, err)
+	assert.False(t, cfg.updatesDisabled())
+	err = cfg.SetUpdateAuto(true)
+	require.NoError(t, err)
+	assert.Equal(t, "prerelease", cfg.updaterOptions().Channel)
+
+	// As does a policy that can't be read
+	util.RemoveFileAtPath(cfg.managedPolicyPath)
+	err = os.Mkdir(cfg.managedPolicyPath, 0700)
+	require.NoError(t, err)
+	err = cfg.load()
+	require.NoError(t, err)
+	assert.True(t, cfg.updatesDisabled())
+
+	// But there doesn't have to be one
+	util.RemoveFileAtPath(cfg.managedPolicyPath)
+	err = cfg.load()
+	require.NoError(t, err)
+	assert.False(t, cfg.updatesDisabled())
+	assert.Empty(t, cfg.lockedValues())
+	assert.Equal(t, "test", cfg.updaterOptions().Channel)
+}
+
+func TestConfigLastCheck(t *testing.T) {
+	cfg, _ := testConfig(t)
+	configDir, err := Dir(cfg.appName)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(configDir)
+
+	_, err = cfg.GetLastUpdateCheckTime()
+	assert.True(t, os.IsNotExist(err))
+	err = cfg.save()
+	require.NoError(t, err)
+	cfg.SetLastUpdateCheckTime()
+	lastCheckTime, err := cfg.GetLastUpdateCheckTime()
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), lastCheckTime, time.Minute)
+
+	assert.Nil(t, cfg.GetLastCheckResult())
+	result := updater.CheckResult{Time: time.Now().Truncate(time.Second).UTC(), Version: "1.0.1", Error: "Test error"}
+	err = cfg.SetLastCheckResult(result)
+	require.NoError(t, err)
+
+	path, err := cfg.path()
+	require.NoError(t, err)
+	loaded := newDefaultConfig(cfg.appName, cfg.pathToKeybase, testLog, false)
+	err = loaded.loadFromPath(path)
+	require.NoError(t, err)
+	require.NotNil(t, loaded.GetLastCheckResult())
+	assert.Equal(t, result, *loaded.GetLastCheckResult())
+}
+
+func TestConfigConcurrent(t *testing.T) {
+	cfg, _ := testConfig(t)
+	configDir, err := Dir(cfg.appName)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(configDir)
+
+	// The update checker and the control API use the config at the same time
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			_ = cfg.SetLatestSeen("", updater.SeenUpdate{Version: fmt.Sprintf("1.0.%d", i)})
+			_ = cfg.SetSnooze(fmt.Sprintf("1.0.%d", i), time.Now())
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		_ = cfg.SetUpdateAuto(i%2 == 0)
+		cfg.GetLatestSeen("")
+		cfg.GetSnooze()
+	}
+	<-done
+
+	seen, ok := cfg.GetLatestSeen("")
+	require.True(t, ok)
+	assert.Equal(t, "1.0.19", seen.Version)
+}
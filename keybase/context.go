@@ -4,6 +4,8 @@
 package keybase
 
 import (
+	"bytes"
+	gocontext "context"
 	"fmt"
 	"os"
 	"time"
@@ -14,7 +16,7 @@ import (
 )
 
 // validCodeSigningKIDs are the list of valid code signing IDs for saltpack verify
-var validCodeSigningKIDs = map[string]bool{
+var validCodeSigningKIDs = saltpack.KIDs{
 	"01209092ae4e790763dc7343851b977930f35b16cf43ab0ad900a2af3d3ad5cea1a10a": true, // keybot (device)
 	"012045891a45f03cec001196ad05207f3f80045b2b9f0ca38288a85f8120ac74db960a": true, // max (tiber - 2019-01)
 	"012065ae849d1949a8b0021b165b0edaf722e2a7a9036e07817e056e2d721bddcc0e0a": true, // max (cry glass)
@@ -41,21 +43,17 @@ type context struct {
 	log Log
 	// isCheckCommand is whether the updater is being invoked with the check command
 	isCheckCommand bool
-}
-
-// endpoints define all the url locations for reporting, etc
-type endpoints struct {
-	update  string
-	action  string
-	success string
-	err     string
-}
-
-var defaultEndpoints = endpoints{
-	update:  "https://api-0.core.keybaseapi.com/_/api/1.0/pkg/update.json",
-	action:  "https://api-0.core.keybaseapi.com/_/api/1.0/pkg/act.json",
-	success: "https://api-0.core.keybaseapi.com/_/api/1.0/pkg/success.json",
-	err:     "https://api-0.core.keybaseapi.com/_/api/1.0/pkg/error.json",
+	// reports is the queue of reports to send, if any
+	reports *reportQueue
+	// reporter is where update events are reported. If nil, they are reported
+	// to the API server.
+	reporter updater.Reporter
+	// trust is the code signing trust store. If nil, validCodeSigningKIDs are
+	// the valid signers.
+	trust *trustStore
+	// cacheDir is the updater cache dir, where the asset that was last
+	// installed is kept (as the base for delta updates)
+	cacheDir string
 }
 
 func newContext(cfg Config, log Log) *context {
@@ -94,12 +92,23 @@ func (m UpdaterMode) IgnoreSnooze() bool {
 
 // NewUpdaterContext returns an updater context for Keybase
 func NewUpdaterContext(appName string, pathToKeybase string, log Log, mode UpdaterMode) (updater.Context, *updater.Updater) {
+	return NewUpdaterContextWithOptions(appName, pathToKeybase, log, mode, ContextOptions{})
+}
+
+// NewUpdaterContextWithOptions returns an updater context for Keybase, with
+// options that override the config
+func NewUpdaterContextWithOptions(appName string, pathToKeybase string, log Log, mode UpdaterMode, options ContextOptions) (updater.Context, *updater.Updater) {
 	cfg, err := newConfig(appName, pathToKeybase, log, mode.IgnoreSnooze())
 	if err != nil {
 		log.Warningf("Error loading config for context: %s", err)
 	}
+	cfg.applyEndpoints(options)
+	cfg.force = options.Force
 
-	src := NewUpdateSource(cfg, log)
+	trust := loadTrustStore(cfg, log)
+	keybaseSrc := NewUpdateSource(cfg, log)
+	keybaseSrc.trust = trust
+	src := tufUpdateSource(cfg, keybaseSrc, log)
 
 	// For testing, you can use a local updater source.
 	// Add your local device signing key to `validCodeSigningKIDs` above (note that the first and last byte are stripped off).
@@ -115,7 +124,45 @@ func NewUpdaterContext(appName string, pathToKeybase string, log Log, mode Updat
 	// keybase update check
 
 	upd := updater.NewUpdater(src, cfg, log)
-	return newContextCheckCmd(cfg, log, mode.IsCheck()), upd
+	if journalPath, err := cfg.journalPath(); err != nil {
+		log.Warningf("Error getting journal path: %s", err)
+	} else {
+		upd.SetJournalPath(journalPath)
+	}
+	if historyPath, err := cfg.historyPath(); err != nil {
+		log.Warningf("Error getting history path: %s", err)
+	} else {
+		upd.SetHistoryPath(historyPath)
+	}
+	cacheDir, err := cfg.cacheDir()
+	if err != nil {
+		log.Warningf("Error getting cache dir: %s", err)
+	} else {
+		upd.SetCacheDir(cacheDir)
+	}
+	upd.SetCacheLimit(cfg.cacheLimit())
+	if lockPath := cfg.lockPath(); lockPath != "" {
+		upd.SetLockPath(lockPath)
+	}
+	if policy, err := cfg.policy(); err != nil {
+		log.Warningf("Error loading update policy: %s", err)
+	} else if policy != nil {
+		upd.SetPolicy(*policy)
+	}
+	ctx := newContextCheckCmd(cfg, log, mode.IsCheck())
+	ctx.trust = trust
+	ctx.cacheDir = cacheDir
+	if reportQueuePath, err := cfg.reportQueuePath(); err != nil {
+		log.Warningf("Error getting report queue path: %s", err)
+	} else {
+		ctx.reports = newReportQueue(reportQueuePath, func(report queuedReport) error {
+			return ctx.sendReport(report)
+		}, log)
+		// Send reports left from earlier runs
+		go ctx.reports.flush()
+	}
+	ctx.reporter = ctx.newReporter(cfg.reporterConfigs())
+	return ctx, upd
 }
 
 // UpdateOptions returns update options
@@ -133,18 +180,61 @@ func (c context) GetLog() Log {
 	return c.log
 }
 
-// Verify verifies the signature
+// Verify verifies the signature, or signatures if more than one signer is
+// required (see signatureThreshold)
 func (c context) Verify(update updater.Update) error {
-	return saltpack.VerifyDetachedFileAtPath(update.Asset.LocalPath, update.Asset.Signature, validCodeSigningKIDs, c.log)
+	signatures := update.Asset.AllSignatures()
+	threshold := c.config.signatureThreshold()
+	if threshold <= 1 && len(signatures) <= 1 {
+		signature := update.Asset.Signature
+		if len(signatures) == 1 {
+			signature = signatures[0]
+		}
+		return saltpack.VerifyDetachedFileAtPathWithSigners(update.Asset.LocalPath, signature, c.trust.signers(), c.log)
+	}
+	return saltpack.VerifyDetachedFileAtPathThreshold(update.Asset.LocalPath, signatures, threshold, c.trust.signers(), c.log)
+}
+
+// VerifyManifest verifies the signature of the update manifest (see
+// updater.Update.ManifestJSON). Updates without a signature are accepted,
+// unless the config requires one.
+func (c context) VerifyManifest(update updater.Update) error {
+	if update.Signature == "" {
+		if c.config.requireSignedManifest() {
+			return fmt.Errorf("No signature, and a signed manifest is required")
+		}
+		c.log.Debugf("Update manifest isn't signed")
+		return nil
+	}
+	manifest, err := update.ManifestJSON()
+	if err != nil {
+		return err
+	}
+	return saltpack.VerifyDetachedWithSigners(bytes.NewReader(manifest), update.Signature, c.trust.signers(), c.log)
+}
+
+// DeltaBasePath returns the asset that was last installed, which is kept in
+// the cache, as the base for a delta update. The installed app (the
+// destination path) isn't the archive that a delta applies to.
+func (c context) DeltaBasePath(update updater.Update, options updater.UpdateOptions) string {
+	if c.cacheDir == "" {
+		return ""
+	}
+	path, err := updater.FindInstalledAsset(c.cacheDir)
+	if err != nil {
+		c.log.Warningf("Error finding installed asset: %s", err)
+		return ""
+	}
+	return path
 }
 
 type checkInUseResult struct {
 	InUse bool `json:"in_use"`
 }
 
-func (c context) checkInUse() (bool, error) {
+func (c context) checkInUse(goCtx gocontext.Context) (bool, error) {
 	var result checkInUseResult
-	if err := command.ExecForJSON(c.config.keybasePath(), []string{"update", "check-in-use"}, &result, time.Minute, c.log); err != nil {
+	if err := command.ExecForJSONContext(goCtx, c.config.keybasePath(), []string{"update", "check-in-use"}, &result, time.Minute, c.log); err != nil {
 		return false, err
 	}
 	return result.InUse, nil
@@ -152,14 +242,26 @@ func (c context) checkInUse() (bool, error) {
 
 // BeforeApply is called before an update is applied
 func (c context) BeforeApply(update updater.Update) error {
-	inUse, err := c.checkInUse()
+	return c.BeforeApplyContext(gocontext.Background(), update)
+}
+
+// BeforeApplyContext is called before an update is applied. The in use check
+// and paused prompt stop if goCtx is done.
+func (c context) BeforeApplyContext(goCtx gocontext.Context, update updater.Update) error {
+	inUse, err := c.checkInUse(goCtx)
 	if err != nil {
+		if goCtx.Err() != nil {
+			return goCtx.Err()
+		}
 		c.log.Warningf("Error trying to check in use: %s", err)
 	}
 	if inUse {
-		if cancel := c.PausedPrompt(); cancel {
+		if cancel := c.pausedPromptContext(goCtx); cancel {
 			return fmt.Errorf("Canceled by user from paused prompt")
 		}
+		if err := goCtx.Err(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -176,4 +278,9 @@ func (c context) AfterUpdateCheck(update *updater.Update) {
 		time.Sleep(2 * time.Second)
 		os.Exit(0)
 	}
+	// The update checker calls this after every check, so reports that failed
+	// are retried, even if none are added
+	if c.reports != nil {
+		go c.reports.flush()
+	}
 }
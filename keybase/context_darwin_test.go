@@ -23,7 +23,7 @@ type testConfigPausedPrompt struct {
 	force bool
 }
 
-func (c testConfigPausedPrompt) promptProgram() (command.Program, error) {
+func (c *testConfigPausedPrompt) promptProgram() (command.Program, error) {
 	if c.force {
 		return command.Program{
 			Path: filepath.Join(os.Getenv("GOPATH"), "bin", "test"),
@@ -36,7 +36,7 @@ func (c testConfigPausedPrompt) promptProgram() (command.Program, error) {
 	}, nil
 }
 
-func (c testConfigPausedPrompt) keybasePath() string {
+func (c *testConfigPausedPrompt) keybasePath() string {
 	_, filename, _, _ := runtime.Caller(0)
 	if c.inUse {
 		return filepath.Join(filepath.Dir(filename), "../test/keybase-check-in-use-true.sh")
@@ -44,11 +44,11 @@ func (c testConfigPausedPrompt) keybasePath() string {
 	return filepath.Join(filepath.Dir(filename), "../test/keybase-check-in-use-false.sh")
 }
 
-func (c testConfigPausedPrompt) updaterOptions() updater.UpdateOptions {
+func (c *testConfigPausedPrompt) updaterOptions() updater.UpdateOptions {
 	return updater.UpdateOptions{}
 }
 
-func (c testConfigPausedPrompt) destinationPath() string {
+func (c *testConfigPausedPrompt) destinationPath() string {
 	return "/Applications/Test.app"
 }
 
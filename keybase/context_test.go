@@ -4,11 +4,18 @@
 package keybase
 
 import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
 
 	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/delta"
+	"github.com/keybase/go-updater/saltpack/saltpacktest"
+	"github.com/keybase/go-updater/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -85,3 +92,164 @@ func TestContextVerifyBadSignature(t *testing.T) {
 	err := ctx.Verify(testContextUpdate(testMessagePath, "BEGIN KEYBASE SALTPACK DETACHED SIGNATURE. END KEYBASE SALTPACK DETACHED SIGNATURE."))
 	require.Error(t, err)
 }
+
+// testSigner returns a func that signs messages with a new key, which is a
+// valid code signing key for the test
+func testSigner(t *testing.T) func(message []byte) string {
+	signer, err := saltpacktest.NewSigner()
+	require.NoError(t, err)
+	validCodeSigningKIDs[signer.KID] = true
+	t.Cleanup(func() { delete(validCodeSigningKIDs, signer.KID) })
+	return func(message []byte) string {
+		signature, err := signer.Sign(message)
+		require.NoError(t, err)
+		return signature
+	}
+}
+
+func TestVerifyManifest(t *testing.T) {
+	cfg, _ := testConfig(t)
+	ctx := newContext(cfg, testLog)
+	update := testUpdate
+	sign := testSigner(t)
+
+	// Not signed, and not required
+	require.NoError(t, ctx.VerifyManifest(update))
+	cfg.store.RequireSignedManifest = true
+	assert.EqualError(t, ctx.VerifyManifest(update), "No signature, and a signed manifest is required")
+
+	manifest, err := update.ManifestJSON()
+	require.NoError(t, err)
+	update.Signature = sign(manifest)
+	require.NoError(t, ctx.VerifyManifest(update))
+
+	// A signature can't be replayed with another version
+	replayed := update
+	replayed.Version = "1.2.4"
+	assert.EqualError(t, ctx.VerifyManifest(replayed), "invalid signature")
+
+	// Signed by a key that isn't valid
+	update.Signature = testSignatureInvalidSigner
+	require.Error(t, ctx.VerifyManifest(update))
+
+	// The system policy overrides the config
+	disabled := false
+	cfg.managed.RequireSignedManifest = &disabled
+	update.Signature = ""
+	require.NoError(t, ctx.VerifyManifest(update))
+}
+
+func TestContextVerifyThreshold(t *testing.T) {
+	cfg, _ := testConfig(t)
+	ctx := newContext(cfg, testLog)
+	message := []byte("This is a test message\n")
+	signA, signB := testSigner(t), testSigner(t)
+	update := testContextUpdate(testMessagePath, signA(message))
+
+	// 1 signer by default
+	assert.Equal(t, 1, cfg.signatureThreshold())
+	require.NoError(t, ctx.Verify(update))
+
+	cfg.store.SignatureThresholds = map[string]int{"": 2, "test": 1}
+	assert.Equal(t, 2, cfg.signatureThreshold())
+	err := ctx.Verify(update)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "error verifying signature: signed by 1 valid signers, needs 2 (valid: [")
+
+	update.Asset.Signatures = []string{signB(message)}
+	require.NoError(t, ctx.Verify(update))
+
+	// The same signer twice isn't enough
+	update.Asset.Signatures = []string{signA(message)}
+	require.Error(t, ctx.Verify(update))
+
+	// The threshold is by channel
+	cfg.store.Channel = "test"
+	assert.Equal(t, 1, cfg.signatureThreshold())
+	require.NoError(t, ctx.Verify(update))
+}
+
+// testConfigVersion is a config for a keybase version
+type testConfigVersion struct {
+	*config
+	version string
+}
+
+func (c testConfigVersion) updaterOptions() updater.UpdateOptions {
+	options := c.config.updaterOptions()
+	options.Version = c.version
+	return options
+}
+
+func TestContextDeltaBase(t *testing.T) {
+	_, filename, _, _ := runtime.Caller(0)
+	target, err := os.ReadFile(filepath.Join(filepath.Dir(filename), "../test/test.zip"))
+	require.NoError(t, err)
+	// The installed asset is the test zip with some changes
+	base := append([]byte{}, target...)
+	base[len(base)/2] ^= 0xff
+	var patch bytes.Buffer
+	err = delta.Diff(base, target, &patch)
+	require.NoError(t, err)
+	targetDigest, err := util.Digest(bytes.NewReader(target))
+	require.NoError(t, err)
+	patchDigest, err := util.Digest(bytes.NewReader(patch.Bytes()))
+	require.NoError(t, err)
+	baseDigest, err := util.Digest(bytes.NewReader(base))
+	require.NoError(t, err)
+
+	fullRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/Keybase.delta" {
+			_, _ = w.Write(patch.Bytes())
+			return
+		}
+		fullRequests++
+		_, _ = w.Write(target)
+	}))
+	defer server.Close()
+
+	cacheDir, err := util.MakeTempDir("TestContextDeltaBase.", 0700)
+	require.NoError(t, err)
+	t.Cleanup(func() { util.RemoveFileAtPath(cacheDir) })
+	installedDir := updater.InstalledAssetDir(cacheDir)
+	err = util.MakeDirs(installedDir, 0700, testLog)
+	require.NoError(t, err)
+	err = os.WriteFile(filepath.Join(installedDir, "Keybase-1.0.0.zip"), base, 0600)
+	require.NoError(t, err)
+
+	cfg, _ := testConfig(t)
+	ctx := newContext(testConfigVersion{config: cfg, version: "1.0.0"}, testLog)
+	ctx.cacheDir = cacheDir
+	sign := testSigner(t)
+	update := &updater.Update{
+		Version:    "1.0.1",
+		NeedUpdate: true,
+		Asset: &updater.Asset{
+			Name:      "Keybase-1.0.1.zip",
+			URL:       server.URL + "/Keybase-1.0.1.zip",
+			Digest:    targetDigest,
+			Signature: sign(target),
+		},
+		Delta: &updater.Delta{
+			URL:         server.URL + "/Keybase.delta",
+			Digest:      patchDigest,
+			BaseVersion: "1.0.0",
+			BaseDigest:  baseDigest,
+		},
+	}
+	upd := updater.NewUpdater(testSource{update: update}, cfg, testLog)
+	upd.SetCacheDir(cacheDir)
+
+	updateAvailable, updateWasDownloaded, err := upd.CheckAndDownload(ctx)
+	require.NoError(t, err)
+	assert.True(t, updateAvailable)
+	assert.True(t, updateWasDownloaded)
+	// Reconstructed from the delta, without downloading the full asset
+	assert.Equal(t, 0, fullRequests)
+	path, err := upd.FindDownloadedAsset(*update.Asset)
+	require.NoError(t, err)
+	downloaded, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, target, downloaded)
+}
@@ -0,0 +1,209 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package keybase
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Endpoints define all the url locations for updates, reporting, etc
+type Endpoints struct {
+	// Update is where we check for updates
+	Update string `json:"update,omitempty"`
+	// Action is where prompt actions are reported
+	Action string `json:"action,omitempty"`
+	// Success is where successful updates are reported
+	Success string `json:"success,omitempty"`
+	// Error is where update errors are reported
+	Error string `json:"error,omitempty"`
+}
+
+var defaultEndpoints = Endpoints{
+	Update:  "https://api-0.core.keybaseapi.com/_/api/1.0/pkg/update.json",
+	Action:  "https://api-0.core.keybaseapi.com/_/api/1.0/pkg/act.json",
+	Success: "https://api-0.core.keybaseapi.com/_/api/1.0/pkg/success.json",
+	Error:   "https://api-0.core.keybaseapi.com/_/api/1.0/pkg/error.json",
+}
+
+// Environment variables for the endpoints and CA certs
+const (
+	envUpdateURL  = "KEYBASE_UPDATER_UPDATE_URL"
+	envActionURL  = "KEYBASE_UPDATER_ACTION_URL"
+	envSuccessURL = "KEYBASE_UPDATER_SUCCESS_URL"
+	envErrorURL   = "KEYBASE_UPDATER_ERROR_URL"
+	// envCACerts is a list of PEM files, separated like PATH
+	envCACerts = "KEYBASE_UPDATER_CA_CERTS"
+)
+
+// each calls fn with the name, and a pointer to the value, of each endpoint
+func (e *Endpoints) each(fn func(name string, value *string)) {
+	fn("update", &e.Update)
+	fn("action", &e.Action)
+	fn("success", &e.Success)
+	fn("error", &e.Error)
+}
+
+// Validate returns an error if an endpoint (that is set) isn't valid
+func (e Endpoints) Validate() error {
+	var err error
+	e.each(func(name string, value *string) {
+		if err == nil && *value != "" {
+			if verr := validateEndpoint(*value); verr != nil {
+				err = fmt.Errorf("Invalid %s endpoint: %s", name, verr)
+			}
+		}
+	})
+	return err
+}
+
+// merge sets the endpoints in other (that are set and valid). Invalid
+// endpoints are logged and skipped.
+func (e *Endpoints) merge(other Endpoints, source string, log Log) {
+	values := map[string]string{}
+	other.each(func(name string, value *string) { values[name] = *value })
+	e.each(func(name string, value *string) {
+		v := values[name]
+		if v == "" {
+			return
+		}
+		if err := validateEndpoint(v); err != nil {
+			log.Warningf("Ignoring %s endpoint from %s: %s", name, source, err)
+			return
+		}
+		*value = v
+	})
+}
+
+// validateEndpoint returns an error if uri isn't an absolute https URL. Plain
+// http is only allowed for a loopback host (for testing).
+func validateEndpoint(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return err
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", uri)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if isLoopback(u.Hostname()) {
+			return nil
+		}
+		return fmt.Errorf("%q isn't https", uri)
+	default:
+		return fmt.Errorf("%q has unsupported scheme %q", uri, u.Scheme)
+	}
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// envEndpoints returns the endpoints set in the environment
+func envEndpoints() Endpoints {
+	return Endpoints{
+		Update:  os.Getenv(envUpdateURL),
+		Action:  os.Getenv(envActionURL),
+		Success: os.Getenv(envSuccessURL),
+		Error:   os.Getenv(envErrorURL),
+	}
+}
+
+// envCACertPaths returns the CA cert files set in the environment
+func envCACertPaths() []string {
+	var paths []string
+	for _, path := range filepath.SplitList(os.Getenv(envCACerts)) {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// loadCACert returns the PEM at path, or an error if it has no certificates
+func loadCACert(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if ok := x509.NewCertPool().AppendCertsFromPEM(data); !ok {
+		return "", fmt.Errorf("No certificates in %s", path)
+	}
+	return string(data), nil
+}
+
+// ContextOptions override the config for the updater context, for example
+// from command line flags
+type ContextOptions struct {
+	// Endpoints are the endpoints to use (for those set)
+	Endpoints Endpoints
+	// CACertPaths are PEM files with CA certificates to trust, as well as the
+	// Keybase CA
+	CACertPaths []string
+	// Force applies an update even if it isn't newer, or we aren't in its
+	// rollout. It's only set explicitly, never from the environment.
+	Force bool
+}
+
+// Validate returns an error if the endpoints or CA certs aren't valid
+func (o ContextOptions) Validate() error {
+	if err := o.Endpoints.Validate(); err != nil {
+		return err
+	}
+	for _, path := range o.CACertPaths {
+		if _, err := loadCACert(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyEndpoints sets the endpoints and CA certs, from (in order of
+// precedence) options, the environment, and the config file. Values that
+// aren't valid are logged and skipped, so by default we use the Keybase
+// endpoints and only trust the Keybase CA.
+func (c *config) applyEndpoints(options ContextOptions) {
+	urls := defaultEndpoints
+	if c.store.Endpoints != nil {
+		urls.merge(*c.store.Endpoints, "config", c.log)
+	}
+	urls.merge(envEndpoints(), "environment", c.log)
+	urls.merge(options.Endpoints, "options", c.log)
+	c.urls = urls
+
+	var pems []string
+	for _, paths := range [][]string{c.store.CACerts, envCACertPaths(), options.CACertPaths} {
+		for _, path := range paths {
+			pem, err := loadCACert(path)
+			if err != nil {
+				c.log.Warningf("Ignoring CA cert: %s", err)
+				continue
+			}
+			pems = append(pems, pem)
+		}
+	}
+	c.certs = strings.Join(append([]string{caCert}, pems...), "\n")
+}
+
+// endpoints returns the endpoints to use
+func (c *config) endpoints() Endpoints {
+	return c.urls
+}
+
+// caCerts returns the PEM of the CA certificates to trust
+func (c *config) caCerts() string {
+	return c.certs
+}
@@ -0,0 +1,132 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package keybase
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateEndpoint(t *testing.T) {
+	assert.NoError(t, validateEndpoint("https://updates.example.com/update.json"))
+	assert.NoError(t, validateEndpoint("http://localhost:8080/update.json"))
+	assert.NoError(t, validateEndpoint("http://127.0.0.1/update.json"))
+	assert.NoError(t, validateEndpoint("http://[::1]/update.json"))
+	assert.Error(t, validateEndpoint("http://updates.example.com/update.json"))
+	assert.Error(t, validateEndpoint("ftp://updates.example.com/update.json"))
+	assert.Error(t, validateEndpoint("/update.json"))
+	assert.Error(t, validateEndpoint("https://%zz"))
+
+	assert.NoError(t, Endpoints{Update: "https://updates.example.com"}.Validate())
+	assert.EqualError(t, Endpoints{Error: "http://example.com"}.Validate(), `Invalid error endpoint: "http://example.com" isn't https`)
+}
+
+// writeTestCACert writes the certificate of a TLS test server to a PEM file
+func writeTestCACert(t *testing.T, server *httptest.Server, dir string) string {
+	path := filepath.Join(dir, "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+func TestApplyEndpoints(t *testing.T) {
+	cfg, _ := testConfig(t)
+	cfg.applyEndpoints(ContextOptions{})
+	assert.Equal(t, defaultEndpoints, cfg.endpoints())
+	assert.Equal(t, caCert, cfg.caCerts())
+
+	cfg.store.Endpoints = &Endpoints{
+		Update: "https://config.example.com/update.json",
+		Action: "https://config.example.com/act.json",
+		Error:  "http://insecure.example.com/error.json",
+	}
+	t.Setenv(envActionURL, "https://env.example.com/act.json")
+	t.Setenv(envSuccessURL, "https://env.example.com/success.json")
+	cfg.applyEndpoints(ContextOptions{Endpoints: Endpoints{Success: "https://flag.example.com/success.json"}})
+	assert.Equal(t, Endpoints{
+		Update:  "https://config.example.com/update.json",
+		Action:  "https://env.example.com/act.json",
+		Success: "https://flag.example.com/success.json",
+		// Invalid, so the default is used
+		Error: defaultEndpoints.Error,
+	}, cfg.endpoints())
+}
+
+func TestApplyCACerts(t *testing.T) {
+	dir, err := util.MakeTempDir("TestApplyCACerts.", 0700)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(dir)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, updateJSONResponse)
+	}))
+	defer server.Close()
+	certPath := writeTestCACert(t, server, dir)
+	invalidPath := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("invalid"), 0600))
+
+	assert.NoError(t, ContextOptions{CACertPaths: []string{certPath}}.Validate())
+	assert.Error(t, ContextOptions{CACertPaths: []string{invalidPath}}.Validate())
+	assert.Error(t, ContextOptions{CACertPaths: []string{filepath.Join(dir, "missing.pem")}}.Validate())
+
+	cfg, _ := testConfig(t)
+	updateSource := newUpdateSource(cfg, server.URL, testLog)
+	// Only the Keybase CA is trusted by default
+	_, err = updateSource.FindUpdate(testOptions)
+	require.Error(t, err)
+
+	t.Setenv(envCACerts, invalidPath+string(os.PathListSeparator)+certPath)
+	cfg.applyEndpoints(ContextOptions{})
+	assert.True(t, strings.HasPrefix(cfg.caCerts(), caCert))
+	update, err := updateSource.FindUpdate(testOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.15-20160414190014+fdfce90", update.Version)
+}
+
+func TestUpdateSourceOptionsURL(t *testing.T) {
+	server := newServer(updateJSONResponse)
+	defer server.Close()
+
+	cfg, _ := testConfig(t)
+	updateSource := newUpdateSource(cfg, "https://invalid.example.com", testLog)
+	options := testOptions
+	options.URL = server.URL
+	update, err := updateSource.FindUpdate(options)
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.15-20160414190014+fdfce90", update.Version)
+
+	options.URL = "http://updates.example.com"
+	_, err = updateSource.FindUpdate(options)
+	assert.EqualError(t, err, `Invalid update URL: "http://updates.example.com" isn't https`)
+}
+
+func TestNewUpdaterContextWithOptions(t *testing.T) {
+	ctx, _ := NewUpdaterContextWithOptions("KeybaseTest", "keybase", testLog, Check, ContextOptions{
+		Endpoints: Endpoints{Update: "https://updates.example.com/update.json"},
+	})
+	c, ok := ctx.(*context)
+	require.True(t, ok)
+	assert.Equal(t, "https://updates.example.com/update.json", c.config.endpoints().Update)
+	assert.Equal(t, defaultEndpoints.Action, c.config.endpoints().Action)
+}
+
+func TestNewUpdaterContextForce(t *testing.T) {
+	// The environment doesn't force an update
+	t.Setenv("KEYBASE_UPDATER_FORCE", "true")
+	ctx, _ := NewUpdaterContextWithOptions("KeybaseTest", "keybase", testLog, Check, ContextOptions{})
+	assert.False(t, ctx.UpdateOptions().Force)
+
+	ctx, _ = NewUpdaterContextWithOptions("KeybaseTest", "keybase", testLog, Check, ContextOptions{Force: true})
+	assert.True(t, ctx.UpdateOptions().Force)
+}
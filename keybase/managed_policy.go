@@ -0,0 +1,67 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package keybase
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// managedPolicy is a system policy, set by an administrator, which overrides
+// the user's config. A value that is set is locked, and the user can't change
+// it.
+type managedPolicy struct {
+	// Auto forces auto update on (or off)
+	Auto *bool `json:"auto,omitempty"`
+	// Channel pins the update channel
+	Channel *string `json:"channel,omitempty"`
+	// DisablePrompt applies updates without prompting
+	DisablePrompt *bool `json:"disablePrompt,omitempty"`
+	// DisableUpdates blocks updates
+	DisableUpdates *bool `json:"disableUpdates,omitempty"`
+	// RequireSignedManifest only accepts updates with a signed manifest
+	RequireSignedManifest *bool `json:"requireSignedManifest,omitempty"`
+}
+
+// loadManagedPolicy loads the policy at path. If there isn't one, returns an
+// empty policy.
+func loadManagedPolicy(path string) (managedPolicy, error) {
+	var policy managedPolicy
+	if path == "" {
+		return policy, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return policy, nil
+	}
+	if err != nil {
+		return policy, fmt.Errorf("Unable to read system policy: %s", err)
+	}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return managedPolicy{}, fmt.Errorf("Invalid system policy: %s", err)
+	}
+	return policy, nil
+}
+
+// locked returns the locked values, by key
+func (p managedPolicy) locked() map[string]interface{} {
+	locked := map[string]interface{}{}
+	if p.Auto != nil {
+		locked["auto"] = *p.Auto
+	}
+	if p.Channel != nil {
+		locked["channel"] = *p.Channel
+	}
+	if p.DisablePrompt != nil {
+		locked["disablePrompt"] = *p.DisablePrompt
+	}
+	if p.DisableUpdates != nil {
+		locked["disableUpdates"] = *p.DisableUpdates
+	}
+	if p.RequireSignedManifest != nil {
+		locked["requireSignedManifest"] = *p.RequireSignedManifest
+	}
+	return locked
+}
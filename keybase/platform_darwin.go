@@ -5,6 +5,7 @@ package keybase
 
 import (
 	"bytes"
+	gocontext "context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -22,7 +23,7 @@ import (
 )
 
 // execPath returns the app bundle path where this executable is located
-func (c config) execPath() string {
+func (c *config) execPath() string {
 	path, err := osext.Executable()
 	if err != nil {
 		c.log.Warningf("Error trying to determine our executable path: %s", err)
@@ -32,7 +33,7 @@ func (c config) execPath() string {
 }
 
 // destinationPath returns the app bundle path where this executable is located
-func (c config) destinationPath() string {
+func (c *config) destinationPath() string {
 	return appBundleForPath(c.execPath())
 }
 
@@ -48,6 +49,11 @@ func appBundleForPath(path string) string {
 	return paths[0] + ".app"
 }
 
+// defaultManagedPolicyPath is where an administrator can put a system policy
+func defaultManagedPolicyPath() string {
+	return "/etc/keybase/updater-policy.json"
+}
+
 func libraryDir() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -85,7 +91,7 @@ func LogDir(appName string) (string, error) {
 	return filepath.Join(libDir, "Logs"), nil
 }
 
-func (c config) osVersion() string {
+func (c *config) osVersion() string {
 	result, err := command.Exec("/usr/bin/sw_vers", []string{"-productVersion"}, 5*time.Second, c.log)
 	if err != nil {
 		c.log.Warningf("Error trying to determine OS version: %s (%s)", err, result.CombinedOutput())
@@ -94,7 +100,7 @@ func (c config) osVersion() string {
 	return strings.TrimSpace(result.Stdout.String())
 }
 
-func (c config) osArch() string {
+func (c *config) osArch() string {
 	r, err := syscall.Sysctl("sysctl.proc_translated")
 	if err == nil {
 		if r == "\x00\x00\x00" || r == "\x01\x00\x00" {
@@ -115,7 +121,7 @@ func (c config) osArch() string {
 	return strings.TrimSuffix(buf.String(), "\n")
 }
 
-func (c config) promptProgram() (command.Program, error) {
+func (c *config) promptProgram() (command.Program, error) {
 	destinationPath := c.destinationPath()
 	if destinationPath == "" {
 		return command.Program{}, fmt.Errorf("No destination path")
@@ -125,7 +131,7 @@ func (c config) promptProgram() (command.Program, error) {
 	}, nil
 }
 
-func (c config) notifyProgram() string {
+func (c *config) notifyProgram() string {
 	// No notify program for Darwin
 	return ""
 }
@@ -136,22 +142,34 @@ func (c context) BeforeUpdatePrompt(update updater.Update, options updater.Updat
 
 // UpdatePrompt is called when the user needs to accept an update
 func (c context) UpdatePrompt(update updater.Update, options updater.UpdateOptions, promptOptions updater.UpdatePromptOptions) (*updater.UpdatePromptResponse, error) {
+	return c.UpdatePromptContext(gocontext.Background(), update, options, promptOptions)
+}
+
+// UpdatePromptContext is UpdatePrompt, which stops if goCtx is done
+func (c context) UpdatePromptContext(goCtx gocontext.Context, update updater.Update, options updater.UpdateOptions, promptOptions updater.UpdatePromptOptions) (*updater.UpdatePromptResponse, error) {
+	if response := c.promptDisabledResponse(); response != nil {
+		return response, nil
+	}
 	promptProgram, err := c.config.promptProgram()
 	if err != nil {
 		return nil, err
 	}
-	return c.updatePrompt(promptProgram, update, options, promptOptions, time.Hour)
+	return c.updatePrompt(goCtx, promptProgram, update, options, promptOptions, time.Hour)
 }
 
 // PausedPrompt is called when the we can't update cause the app is in use.
 // We return true if the use wants to cancel the update.
 func (c context) PausedPrompt() bool {
+	return c.pausedPromptContext(gocontext.Background())
+}
+
+func (c context) pausedPromptContext(goCtx gocontext.Context) bool {
 	promptProgram, err := c.config.promptProgram()
 	if err != nil {
 		c.log.Warningf("Error trying to get prompt path: %s", err)
 		return false
 	}
-	cancelUpdate, err := c.pausedPrompt(promptProgram, 5*time.Minute)
+	cancelUpdate, err := c.pausedPrompt(goCtx, promptProgram, 5*time.Minute)
 	if err != nil {
 		c.log.Warningf("Error in paused prompt: %s", err)
 		return false
@@ -253,6 +271,10 @@ func (c context) start(wait time.Duration, delay time.Duration) error {
 	}
 
 	// Check to make sure processes started
+	return c.checkProcesses(procPaths, wait, delay)
+}
+
+func (c context) checkProcesses(procPaths processPaths, wait time.Duration, delay time.Duration) error {
 	c.log.Debugf("Checking processes: %#v", procPaths)
 	serviceProcErr := c.checkProcess(procPaths.serviceProcPath, wait, delay)
 	kbfsProcErr := c.checkProcess(procPaths.kbfsProcPath, wait, delay)
@@ -261,6 +283,22 @@ func (c context) start(wait time.Duration, delay time.Duration) error {
 	return util.CombineErrors(serviceProcErr, kbfsProcErr, appProcErr)
 }
 
+// HealthCheck is called after AfterApply to make sure the updated app and
+// services are running. If not, the previous app is restored.
+func (c context) HealthCheck(update updater.Update, options updater.UpdateOptions) error {
+	procPaths, err := c.lookupProcessPaths()
+	if err != nil {
+		return err
+	}
+	return c.checkProcesses(procPaths, 10*time.Second, time.Second)
+}
+
+// AfterRollback is called after the previous app was restored, so we restart
+// it like after an apply.
+func (c context) AfterRollback(update updater.Update, options updater.UpdateOptions) error {
+	return c.AfterApply(update)
+}
+
 func (c context) checkProcess(match string, wait time.Duration, delay time.Duration) error {
 	matcher := process.NewMatcher(match, process.PathContains, c.log)
 	procs, err := process.FindProcesses(matcher, wait, delay, c.log)
@@ -35,7 +35,7 @@ type testConfigDarwin struct {
 	testConfigPlatform
 }
 
-func (c testConfigDarwin) destinationPath() string {
+func (c *testConfigDarwin) destinationPath() string {
 	_, filename, _, _ := runtime.Caller(0)
 	return filepath.Join(filepath.Dir(filename), "../test/Test.app")
 }
@@ -5,6 +5,7 @@ package keybase
 
 import (
 	"bytes"
+	gocontext "context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -18,11 +19,16 @@ import (
 	"github.com/keybase/go-updater/command"
 )
 
-func (c config) destinationPath() string {
+func (c *config) destinationPath() string {
 	// No destination path for Linux
 	return ""
 }
 
+// defaultManagedPolicyPath is where an administrator can put a system policy
+func defaultManagedPolicyPath() string {
+	return "/etc/keybase/updater-policy.json"
+}
+
 // Dir returns where to store config and log files
 func Dir(appName string) (string, error) {
 	dir := os.Getenv("XDG_CONFIG_HOME")
@@ -60,7 +66,7 @@ func LogDir(appName string) (string, error) {
 	return filepath.Join(usr.HomeDir, ".cache", appName), nil
 }
 
-func (c config) osVersion() string {
+func (c *config) osVersion() string {
 	result, err := command.Exec("uname", []string{"-mrs"}, 5*time.Second, c.log)
 	if err != nil {
 		c.log.Warningf("Error trying to determine OS version: %s (%s)", err, result.CombinedOutput())
@@ -69,7 +75,7 @@ func (c config) osVersion() string {
 	return strings.TrimSpace(result.Stdout.String())
 }
 
-func (c config) osArch() string {
+func (c *config) osArch() string {
 	cmd := exec.Command("uname", "-m")
 	var buf bytes.Buffer
 	cmd.Stdout = &buf
@@ -81,11 +87,11 @@ func (c config) osArch() string {
 	return strings.TrimSuffix(buf.String(), "\n")
 }
 
-func (c config) promptProgram() (command.Program, error) {
+func (c *config) promptProgram() (command.Program, error) {
 	return command.Program{}, fmt.Errorf("Unsupported")
 }
 
-func (c config) notifyProgram() string {
+func (c *config) notifyProgram() string {
 	return "notify-send"
 }
 
@@ -116,6 +122,10 @@ func (c context) PausedPrompt() bool {
 	return false
 }
 
+func (c context) pausedPromptContext(goCtx gocontext.Context) bool {
+	return false
+}
+
 func (c context) Apply(update updater.Update, options updater.UpdateOptions, tmpDir string) error {
 	return nil
 }
@@ -16,7 +16,7 @@ type testConfigPlatform struct {
 	Args        []string
 }
 
-func (c testConfigPlatform) promptProgram() (command.Program, error) {
+func (c *testConfigPlatform) promptProgram() (command.Program, error) {
 	programPath, args := c.ProgramPath, c.Args
 	if programPath == "" {
 		programPath = filepath.Join(os.Getenv("GOPATH"), "bin", "test")
@@ -28,10 +28,10 @@ func (c testConfigPlatform) promptProgram() (command.Program, error) {
 	}, nil
 }
 
-func (c testConfigPlatform) notifyProgram() string {
+func (c *testConfigPlatform) notifyProgram() string {
 	return "echo"
 }
 
-func (c testConfigPlatform) keybasePath() string {
+func (c *testConfigPlatform) keybasePath() string {
 	return filepath.Join(os.Getenv("GOPATH"), "bin", "test")
 }
@@ -4,6 +4,7 @@
 package keybase
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -84,7 +85,16 @@ func roamingDataDir() (string, error) {
 	return getDataDir(folderIDRoamingAppData)
 }
 
-func (c config) destinationPath() string {
+// defaultManagedPolicyPath is where an administrator can put a system policy
+func defaultManagedPolicyPath() string {
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	return filepath.Join(programData, "Keybase", "updater-policy.json")
+}
+
+func (c *config) destinationPath() string {
 	pathName, err := osext.Executable()
 	if err != nil {
 		c.log.Warningf("Error trying to determine our executable path: %s", err)
@@ -119,7 +129,7 @@ func LogDir(appName string) (string, error) {
 	return Dir(appName)
 }
 
-func (c config) osVersion() string {
+func (c *config) osVersion() string {
 	result, err := command.Exec("cmd", []string{"/c", "ver"}, 5*time.Second, c.log)
 	if err != nil {
 		c.log.Warningf("Error trying to determine OS version: %s (%s)", err, result.CombinedOutput())
@@ -128,7 +138,7 @@ func (c config) osVersion() string {
 	return strings.TrimSpace(result.Stdout.String())
 }
 
-func (c config) osArch() string {
+func (c *config) osArch() string {
 	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `Hardware\Description\System\CentralProcessor\0`, registry.QUERY_VALUE)
 	if err != nil {
 		return err.Error()
@@ -146,7 +156,7 @@ func (c config) osArch() string {
 	return strings.TrimSuffix(words[0], "\n")
 }
 
-func (c config) notifyProgram() string {
+func (c *config) notifyProgram() string {
 	// No notify program for Windows
 	return runtime.GOARCH
 }
@@ -155,7 +165,7 @@ func (c *context) BeforeUpdatePrompt(update updater.Update, options updater.Upda
 	return nil
 }
 
-func (c config) promptProgram() (command.Program, error) {
+func (c *config) promptProgram() (command.Program, error) {
 	destinationPath := c.destinationPath()
 	if destinationPath == "" {
 		return command.Program{}, fmt.Errorf("No destination path")
@@ -167,6 +177,14 @@ func (c config) promptProgram() (command.Program, error) {
 }
 
 func (c context) UpdatePrompt(update updater.Update, options updater.UpdateOptions, promptOptions updater.UpdatePromptOptions) (*updater.UpdatePromptResponse, error) {
+	return c.UpdatePromptContext(gocontext.Background(), update, options, promptOptions)
+}
+
+// UpdatePromptContext is UpdatePrompt, which stops if goCtx is done
+func (c context) UpdatePromptContext(goCtx gocontext.Context, update updater.Update, options updater.UpdateOptions, promptOptions updater.UpdatePromptOptions) (*updater.UpdatePromptResponse, error) {
+	if response := c.promptDisabledResponse(); response != nil {
+		return response, nil
+	}
 	promptProgram, err := c.config.promptProgram()
 	if err != nil {
 		return nil, err
@@ -185,7 +203,7 @@ func (c context) UpdatePrompt(update updater.Update, options updater.UpdateOptio
 		return nil, fmt.Errorf("Error generating input: %s", err)
 	}
 
-	_, err = command.Exec(promptProgram.Path, promptProgram.ArgsWith([]string{string(promptJSONInput)}), time.Hour, c.log)
+	_, err = command.ExecContext(goCtx, promptProgram.Path, promptProgram.ArgsWith([]string{string(promptJSONInput)}), time.Hour, c.log)
 	if err != nil {
 		return nil, fmt.Errorf("Error running command: %s", err)
 	}
@@ -215,6 +233,10 @@ func (c context) PausedPrompt() bool {
 	return false
 }
 
+func (c context) pausedPromptContext(goCtx gocontext.Context) bool {
+	return false
+}
+
 type componentProductFunc func(componentKey registry.Key, productValueName, componentPath string)
 
 type ComponentsChecker struct {
@@ -4,6 +4,7 @@
 package keybase
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -19,6 +20,9 @@ type updaterPromptInput struct {
 	Description string `json:"description"`
 	AutoUpdate  bool   `json:"autoUpdate"`
 	OutPath     string `json:"outPath"` // Used for windows instead of stdout
+	// Locked are the values set by the system policy (by key), which the UI
+	// shouldn't let the user change
+	Locked map[string]interface{} `json:"locked,omitempty"`
 }
 
 type updaterPromptInputResult struct {
@@ -41,11 +45,12 @@ func (c context) promptInput(update updater.Update, options updater.UpdateOption
 		Description: description,
 		AutoUpdate:  promptOptions.AutoUpdate,
 		OutPath:     promptOptions.OutPath,
+		Locked:      c.config.lockedValues(),
 	})
 	return string(promptJSONInput), err
 }
 
-func (c context) updatePrompt(promptProgram command.Program, update updater.Update, options updater.UpdateOptions, promptOptions updater.UpdatePromptOptions, timeout time.Duration) (*updater.UpdatePromptResponse, error) {
+func (c context) updatePrompt(goCtx gocontext.Context, promptProgram command.Program, update updater.Update, options updater.UpdateOptions, promptOptions updater.UpdatePromptOptions, timeout time.Duration) (*updater.UpdatePromptResponse, error) {
 
 	promptJSONInput, err := c.promptInput(update, options, promptOptions)
 	if err != nil {
@@ -53,12 +58,22 @@ func (c context) updatePrompt(promptProgram command.Program, update updater.Upda
 	}
 
 	var result updaterPromptInputResult
-	if err := command.ExecForJSON(promptProgram.Path, promptProgram.ArgsWith([]string{promptJSONInput}), &result, timeout, c.log); err != nil {
+	if err := command.ExecForJSONContext(goCtx, promptProgram.Path, promptProgram.ArgsWith([]string{promptJSONInput}), &result, timeout, c.log); err != nil {
 		return nil, fmt.Errorf("Error running command: %s", err)
 	}
 	return c.responseForResult(result)
 }
 
+// promptDisabledResponse returns a response that applies the update, if the
+// system policy disables the prompt, otherwise nil
+func (c context) promptDisabledResponse() *updater.UpdatePromptResponse {
+	if !c.config.promptDisabled() {
+		return nil
+	}
+	c.log.Info("Update prompt is disabled by the system policy, applying update")
+	return &updater.UpdatePromptResponse{Action: updater.UpdateActionContinue}
+}
+
 func (c context) responseForResult(result updaterPromptInputResult) (*updater.UpdatePromptResponse, error) {
 	autoUpdate := false
 
@@ -94,7 +109,7 @@ type promptInputResult struct {
 // pausedPrompt returns whether to cancel update and/or error.
 // If the user explicit wants to cancel the update, this may be different from
 // an error occurring, in which case
-func (c context) pausedPrompt(promptProgram command.Program, timeout time.Duration) (bool, error) {
+func (c context) pausedPrompt(goCtx gocontext.Context, promptProgram command.Program, timeout time.Duration) (bool, error) {
 	const btnForce = "Force update"
 	const btnCancel = "Try again later"
 	promptJSONInput, err := json.Marshal(promptInput{
@@ -108,7 +123,7 @@ func (c context) pausedPrompt(promptProgram command.Program, timeout time.Durati
 	}
 
 	var result promptInputResult
-	if err := command.ExecForJSON(promptProgram.Path, promptProgram.ArgsWith([]string{string(promptJSONInput)}), &result, timeout, c.log); err != nil {
+	if err := command.ExecForJSONContext(goCtx, promptProgram.Path, promptProgram.ArgsWith([]string{string(promptJSONInput)}), &result, timeout, c.log); err != nil {
 		return false, fmt.Errorf("Error running command: %s", err)
 	}
 
@@ -4,6 +4,7 @@
 package keybase
 
 import (
+	gocontext "context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -28,7 +29,7 @@ func testPromptWithProgram(t *testing.T, promptProgram command.Program, timeout
 	updaterOptions := cfg.updaterOptions()
 
 	promptOptions := updater.UpdatePromptOptions{AutoUpdate: false}
-	return ctx.updatePrompt(promptProgram, update, updaterOptions, promptOptions, timeout)
+	return ctx.updatePrompt(gocontext.Background(), promptProgram, update, updaterOptions, promptOptions, timeout)
 }
 
 func TestPromptTimeout(t *testing.T) {
@@ -41,6 +42,22 @@ func TestPromptTimeout(t *testing.T) {
 	assert.Nil(t, resp)
 }
 
+func TestPromptCanceled(t *testing.T) {
+	promptProgram := command.Program{
+		Path: filepath.Join(os.Getenv("GOPATH"), "bin", "test"),
+		Args: []string{"sleep"},
+	}
+	cfg, _ := testConfig(t)
+	ctx := newContext(cfg, testLog)
+	goCtx, cancel := gocontext.WithTimeout(gocontext.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	resp, err := ctx.updatePrompt(goCtx, promptProgram, updater.Update{Version: "1.2.3"}, cfg.updaterOptions(), updater.UpdatePromptOptions{}, time.Hour)
+	assert.Error(t, err)
+	assert.Nil(t, resp)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
 func TestPromptInvalidResponse(t *testing.T) {
 	promptProgram := command.Program{
 		Path: filepath.Join(os.Getenv("GOPATH"), "bin", "test"),
@@ -126,7 +143,7 @@ func testPausedPromptWithProgram(t *testing.T, promptProgram command.Program, ti
 	cfg, _ := testConfig(t)
 	ctx := newContext(cfg, testLog)
 	assert.NotNil(t, ctx)
-	return ctx.pausedPrompt(promptProgram, timeout)
+	return ctx.pausedPrompt(gocontext.Background(), promptProgram, timeout)
 }
 
 func TestPausedPromptForce(t *testing.T) {
@@ -148,3 +165,21 @@ func TestPausedPromptCancel(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, cancel)
 }
+
+func TestPromptInputLocked(t *testing.T) {
+	cfg, _ := testConfig(t)
+	auto := true
+	cfg.managed.Auto = &auto
+	ctx := newContext(cfg, testLog)
+	input, err := ctx.promptInput(updater.Update{Version: "1.2.3"}, cfg.updaterOptions(), updater.UpdatePromptOptions{AutoUpdate: true})
+	assert.NoError(t, err)
+	assert.Contains(t, input, `"locked":{"auto":true}`)
+
+	// No prompt if the policy disables it
+	assert.Nil(t, ctx.promptDisabledResponse())
+	disablePrompt := true
+	cfg.managed.DisablePrompt = &disablePrompt
+	response := ctx.promptDisabledResponse()
+	assert.NotNil(t, response)
+	assert.Equal(t, updater.UpdateActionContinue, response.Action)
+}
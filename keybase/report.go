@@ -11,39 +11,116 @@ import (
 	"time"
 
 	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/reporters"
 	"github.com/keybase/go-updater/util"
 )
 
-// ReportError notifies the API server of a client updater error
+// ReportError reports a client updater error, to the configured reporter
+// (the API server by default)
 func (c context) ReportError(err error, update *updater.Update, options updater.UpdateOptions) {
-	if reportErr := c.reportError(err, update, options, defaultEndpoints.err, time.Minute); reportErr != nil {
-		c.log.Warningf("Error notifying about an error: %s", reportErr)
+	c.getReporter().ReportError(err, update, options)
+}
+
+// ReportAction reports a client updater action, to the configured reporter
+// (the API server by default)
+func (c context) ReportAction(actionResponse updater.UpdatePromptResponse, update *updater.Update, options updater.UpdateOptions) {
+	c.getReporter().ReportAction(actionResponse, update, options)
+}
+
+// ReportSuccess reports a successful update, to the configured reporter (the
+// API server by default)
+func (c context) ReportSuccess(update *updater.Update, options updater.UpdateOptions) {
+	c.getReporter().ReportSuccess(update, options)
+}
+
+func (c context) getReporter() updater.Reporter {
+	if c.reporter == nil {
+		return apiReporter{c: c}
+	}
+	return c.reporter
+}
+
+// newReporter returns a reporter for configs, or nil (for the default) if
+// there are none. A config that isn't valid is logged and skipped.
+func (c context) newReporter(configs []ReporterConfig) updater.Reporter {
+	if len(configs) == 0 {
+		return nil
+	}
+	var sinks []updater.Reporter
+	for _, config := range configs {
+		reporter, err := c.newReporterForConfig(config)
+		if err != nil {
+			c.log.Warningf("Skipping reporter (%s): %s", config.Type, err)
+			continue
+		}
+		sinks = append(sinks, reporter)
+	}
+	if len(sinks) == 1 {
+		return sinks[0]
+	}
+	return reporters.NewMultiReporter(sinks...)
+}
+
+func (c context) newReporterForConfig(config ReporterConfig) (updater.Reporter, error) {
+	switch config.Type {
+	case "keybase":
+		return apiReporter{c: c}, nil
+	case "http":
+		if config.URL == "" {
+			return nil, fmt.Errorf("No url")
+		}
+		return httpReporter{c: c, url: config.URL}, nil
+	case "file":
+		if config.Path == "" {
+			return nil, fmt.Errorf("No path")
+		}
+		return reporters.NewFileReporter(config.Path, c.log), nil
+	case "syslog":
+		tag := config.Tag
+		if tag == "" {
+			tag = "keybase.updater"
+		}
+		return reporters.NewSyslogReporter(tag, c.log)
+	default:
+		return nil, fmt.Errorf("Unknown reporter type")
 	}
 }
 
-func (c context) reportError(err error, update *updater.Update, options updater.UpdateOptions, uri string, timeout time.Duration) error {
-	var errorType string
+// apiReporter reports to the Keybase API server
+type apiReporter struct {
+	c context
+}
+
+// ReportError notifies the API server of a client updater error
+func (r apiReporter) ReportError(err error, update *updater.Update, options updater.UpdateOptions) {
+	r.c.queueReport(reportEventError, errorReportData(err), update, options, r.c.config.endpoints().Error)
+}
+
+func errorReportData(err error) url.Values {
+	var errorType, errorSubtype string
 	switch uerr := err.(type) {
 	case updater.Error:
 		errorType = uerr.TypeString()
+		errorSubtype = uerr.SubtypeString()
 	default:
 		errorType = string(updater.UnknownError)
 	}
 
 	data := url.Values{}
 	data.Add("error_type", errorType)
+	if errorSubtype != "" {
+		data.Add("error_subtype", errorSubtype)
+	}
 	data.Add("description", err.Error())
-	return c.report(data, update, options, uri, timeout)
+	return data
 }
 
 // ReportAction notifies the API server of a client updater action
-func (c context) ReportAction(actionResponse updater.UpdatePromptResponse, update *updater.Update, options updater.UpdateOptions) {
-	if err := c.reportAction(actionResponse, update, options, defaultEndpoints.action, time.Minute); err != nil {
-		c.log.Warningf("Error notifying about an action (%s): %s", actionResponse.Action, err)
-	}
+func (r apiReporter) ReportAction(actionResponse updater.UpdatePromptResponse, update *updater.Update, options updater.UpdateOptions) {
+	r.c.queueReport(reportEventAction, r.c.actionReportData(actionResponse), update, options, r.c.config.endpoints().Action)
 }
 
-func (c context) reportAction(actionResponse updater.UpdatePromptResponse, update *updater.Update, options updater.UpdateOptions, uri string, timeout time.Duration) error {
+func (c context) actionReportData(actionResponse updater.UpdatePromptResponse) url.Values {
 	data := url.Values{}
 	data.Add("action", actionResponse.Action.String())
 	autoUpdate, _ := c.config.GetUpdateAuto()
@@ -51,34 +128,115 @@ func (c context) reportAction(actionResponse updater.UpdatePromptResponse, updat
 	if actionResponse.SnoozeDuration > 0 {
 		data.Add("snooze_duration", fmt.Sprintf("%d", actionResponse.SnoozeDuration))
 	}
-	return c.report(data, update, options, uri, timeout)
+	return data
 }
 
-func (c context) ReportSuccess(update *updater.Update, options updater.UpdateOptions) {
-	if err := c.reportSuccess(update, options, defaultEndpoints.success, time.Minute); err != nil {
-		c.log.Warningf("Error notifying about success: %s", err)
+// ReportSuccess notifies the API server of a successful update
+func (r apiReporter) ReportSuccess(update *updater.Update, options updater.UpdateOptions) {
+	r.c.queueReport(reportEventSuccess, url.Values{}, update, options, r.c.config.endpoints().Success)
+}
+
+// httpReporter posts events (as JSON) to a URL, such as a self-hosted
+// collector, through the report queue (like the API server reports), so a slow
+// or offline collector doesn't block the update
+type httpReporter struct {
+	c   context
+	url string
+}
+
+// ReportError reports an update error
+func (r httpReporter) ReportError(err error, update *updater.Update, options updater.UpdateOptions) {
+	r.queueEvent(reporters.ErrorEvent(err, update, options))
+}
+
+// ReportAction reports the action chosen at the update prompt
+func (r httpReporter) ReportAction(actionResponse updater.UpdatePromptResponse, update *updater.Update, options updater.UpdateOptions) {
+	r.queueEvent(reporters.ActionEvent(actionResponse, update, options))
+}
+
+// ReportSuccess reports that an update was applied
+func (r httpReporter) ReportSuccess(update *updater.Update, options updater.UpdateOptions) {
+	r.queueEvent(reporters.SuccessEvent(update, options))
+}
+
+func (r httpReporter) queueEvent(event reporters.Event) {
+	r.c.addReport(queuedReport{
+		// Reports for the API server (and other collectors) don't replace these
+		Key:       r.url + " " + reportKey(reportEvent(event.Type), event.RequestID, event.Time),
+		Event:     reportEvent(event.Type),
+		URI:       r.url,
+		Sink:      reportSinkHTTP,
+		SinkEvent: &event,
+		Created:   event.Time,
+	})
+}
+
+// queueReport saves a report for the API server to the report queue (see
+// addReport)
+func (c context) queueReport(event reportEvent, data url.Values, update *updater.Update, options updater.UpdateOptions, uri string) {
+	addReportData(data, update, options)
+	var requestID string
+	if update != nil {
+		requestID = update.RequestID
 	}
+	now := time.Now()
+	c.addReport(queuedReport{
+		Key:     reportKey(event, requestID, now),
+		Event:   event,
+		URI:     uri,
+		Data:    data,
+		Created: now,
+	})
 }
 
-func (c context) reportSuccess(update *updater.Update, options updater.UpdateOptions, uri string, timeout time.Duration) error {
-	data := url.Values{}
-	return c.report(data, update, options, uri, timeout)
+// addReport saves a report to the report queue, and sends the queued reports
+// in the background, so reporting doesn't block the update. Without a report
+// queue, the report is sent now.
+func (c context) addReport(report queuedReport) {
+	if c.reports == nil {
+		if err := c.sendReport(report); err != nil {
+			c.log.Warningf("Error notifying about %s: %s", report.Event, err)
+		}
+		return
+	}
+	if err := c.reports.add(report); err != nil {
+		c.log.Warningf("Error queueing %s report: %s", report.Event, err)
+	}
+	go c.reports.flush()
+}
+
+// sendReport sends a queued report to its sink
+func (c context) sendReport(report queuedReport) error {
+	switch report.Sink {
+	case "":
+		return c.post(report.Data, report.URI, time.Minute)
+	case reportSinkHTTP:
+		if report.SinkEvent == nil {
+			return fmt.Errorf("No event")
+		}
+		return reporters.PostEvent(report.URI, nil, *report.SinkEvent)
+	default:
+		return fmt.Errorf("Unknown report sink %q", report.Sink)
+	}
 }
 
-func (c context) report(data url.Values, update *updater.Update, options updater.UpdateOptions, uri string, timeout time.Duration) error {
+func addReportData(data url.Values, update *updater.Update, options updater.UpdateOptions) {
 	if update != nil {
 		data.Add("install_id", update.InstallID)
 		data.Add("request_id", update.RequestID)
 	}
 	data.Add("version", options.Version)
 	data.Add("upd_version", options.UpdaterVersion)
+}
 
+// post sends a report to the API server
+func (c context) post(data url.Values, uri string, timeout time.Duration) error {
 	req, err := http.NewRequest("POST", uri, bytes.NewBufferString(data.Encode()))
 	if err != nil {
 		return err
 	}
 	req.Header.Add("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
-	client, err := httpClient(timeout)
+	client, err := httpClientWithCert(c.config.caCerts(), timeout)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,214 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package keybase
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/keybase/go-updater/reporters"
+	"github.com/keybase/go-updater/util"
+)
+
+const (
+	// maxQueuedReports is how many reports we keep; the oldest are dropped
+	maxQueuedReports = 100
+	// maxReportAge is how long we keep trying to send a report
+	maxReportAge = 7 * 24 * time.Hour
+	// reportRetryDelay is the delay after the first failed send, which doubles
+	// after each failure, up to maxReportRetryDelay
+	reportRetryDelay    = time.Minute
+	maxReportRetryDelay = 6 * time.Hour
+)
+
+// reportEvent is the type of report
+type reportEvent string
+
+const (
+	reportEventError   reportEvent = "error"
+	reportEventAction  reportEvent = "action"
+	reportEventSuccess reportEvent = "success"
+)
+
+// reportSinkHTTP is the sink for reports (events) posted to an http reporter
+const reportSinkHTTP = "http"
+
+// queuedReport is a report waiting to be sent
+type queuedReport struct {
+	// Key identifies the report. Reports for the same request and event have
+	// the same key, so a newer report replaces an older one.
+	Key   string      `json:"key"`
+	Event reportEvent `json:"event"`
+	URI   string      `json:"uri"`
+	Data  url.Values  `json:"data"`
+	// Sink is where the report is sent: empty for the API server (Data is
+	// posted to URI), or reportSinkHTTP (SinkEvent is posted to URI)
+	Sink        string           `json:"sink,omitempty"`
+	SinkEvent   *reporters.Event `json:"sinkEvent,omitempty"`
+	Created     time.Time        `json:"created"`
+	Attempts    int              `json:"attempts"`
+	NextAttempt time.Time        `json:"nextAttempt"`
+}
+
+func (r queuedReport) same(other queuedReport) bool {
+	return r.Key == other.Key && r.Created.Equal(other.Created)
+}
+
+// reportKey returns the key for a report. Without a request ID, the report
+// isn't deduplicated.
+func reportKey(event reportEvent, requestID string, created time.Time) string {
+	if requestID == "" {
+		return fmt.Sprintf("%s:%d", event, created.UnixNano())
+	}
+	return fmt.Sprintf("%s:%s", event, requestID)
+}
+
+// retryDelay is how long to wait to send a report after attempts failures
+func retryDelay(attempts int) time.Duration {
+	delay := reportRetryDelay
+	for i := 1; i < attempts && delay < maxReportRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxReportRetryDelay {
+		delay = maxReportRetryDelay
+	}
+	return delay
+}
+
+// reportQueue saves reports to disk, and sends them, retrying (with backoff)
+// the ones that fail, so reports aren't lost if we are offline or restarted
+type reportQueue struct {
+	path string
+	log  Log
+	send func(report queuedReport) error
+	// mtx protects the file, and flushing
+	mtx      sync.Mutex
+	flushing bool
+}
+
+func newReportQueue(path string, send func(report queuedReport) error, log Log) *reportQueue {
+	return &reportQueue{path: path, send: send, log: log}
+}
+
+func (q *reportQueue) load() ([]queuedReport, error) {
+	data, err := os.ReadFile(q.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var reports []queuedReport
+	if err := json.Unmarshal(data, &reports); err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func (q *reportQueue) save(reports []queuedReport) error {
+	if len(reports) > maxQueuedReports {
+		reports = reports[len(reports)-maxQueuedReports:]
+	}
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := util.MakeParentDirs(q.path, 0700, q.log); err != nil {
+		return err
+	}
+	return util.NewFile(q.path, data, 0600).Save(q.log)
+}
+
+// add saves a report to send. It replaces a queued report with the same key.
+func (q *reportQueue) add(report queuedReport) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	reports, err := q.load()
+	if err != nil {
+		q.log.Warningf("Dropping invalid report queue: %s", err)
+		reports = nil
+	}
+	kept := reports[:0]
+	for _, r := range reports {
+		if r.Key != report.Key {
+			kept = append(kept, r)
+		}
+	}
+	return q.save(append(kept, report))
+}
+
+// flush sends the reports that are due. Reports that fail are tried again
+// later, until they are too old. If a flush is in progress, it does nothing.
+func (q *reportQueue) flush() {
+	q.mtx.Lock()
+	if q.flushing {
+		q.mtx.Unlock()
+		return
+	}
+	q.flushing = true
+	reports, err := q.load()
+	q.mtx.Unlock()
+	defer func() {
+		q.mtx.Lock()
+		q.flushing = false
+		q.mtx.Unlock()
+	}()
+	if err != nil {
+		q.log.Warningf("Error loading report queue: %s", err)
+		return
+	}
+
+	now := time.Now()
+	var sent, failed []queuedReport
+	for _, report := range reports {
+		if now.Sub(report.Created) > maxReportAge || now.Before(report.NextAttempt) {
+			continue
+		}
+		if err := q.send(report); err != nil {
+			q.log.Warningf("Error sending %s report (attempt %d): %s", report.Event, report.Attempts+1, err)
+			report.Attempts++
+			report.NextAttempt = time.Now().Add(retryDelay(report.Attempts))
+			failed = append(failed, report)
+			continue
+		}
+		sent = append(sent, report)
+	}
+
+	// Reports may have been added while we were sending, so reload
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	reports, err = q.load()
+	if err != nil {
+		q.log.Warningf("Error loading report queue: %s", err)
+		return
+	}
+	var kept []queuedReport
+	for _, report := range reports {
+		if containsReport(sent, report) || now.Sub(report.Created) > maxReportAge {
+			continue
+		}
+		for _, f := range failed {
+			if f.same(report) {
+				report = f
+			}
+		}
+		kept = append(kept, report)
+	}
+	if err := q.save(kept); err != nil {
+		q.log.Warningf("Error saving report queue: %s", err)
+	}
+}
+
+func containsReport(reports []queuedReport, report queuedReport) bool {
+	for _, r := range reports {
+		if r.same(report) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,217 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package keybase
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/reporters"
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testReportSender records the reports sent, and fails if err is set
+type testReportSender struct {
+	sync.Mutex
+	sent []queuedReport
+	err  error
+}
+
+func (s *testReportSender) send(report queuedReport) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.sent = append(s.sent, report)
+	return nil
+}
+
+func (s *testReportSender) sentCount() int {
+	s.Lock()
+	defer s.Unlock()
+	return len(s.sent)
+}
+
+func testReportQueue(t *testing.T, sender *testReportSender) *reportQueue {
+	dir, err := util.MakeTempDir("TestReportQueue.", 0700)
+	require.NoError(t, err)
+	t.Cleanup(func() { util.RemoveFileAtPath(dir) })
+	return newReportQueue(filepath.Join(dir, "updater-reports.json"), sender.send, testLog)
+}
+
+func testQueuedReport(event reportEvent, requestID string, description string) queuedReport {
+	now := time.Now()
+	return queuedReport{
+		Key:     reportKey(event, requestID, now),
+		Event:   event,
+		URI:     "https://localhost/report",
+		Data:    url.Values{"request_id": {requestID}, "description": {description}},
+		Created: now,
+	}
+}
+
+func TestReportQueueDedup(t *testing.T) {
+	q := testReportQueue(t, &testReportSender{})
+	require.NoError(t, q.add(testQueuedReport(reportEventError, "cafedead", "first")))
+	require.NoError(t, q.add(testQueuedReport(reportEventError, "cafedead", "second")))
+	require.NoError(t, q.add(testQueuedReport(reportEventAction, "cafedead", "action")))
+	// Without a request ID, reports aren't deduplicated
+	require.NoError(t, q.add(testQueuedReport(reportEventError, "", "no request 1")))
+	require.NoError(t, q.add(testQueuedReport(reportEventError, "", "no request 2")))
+
+	reports, err := q.load()
+	require.NoError(t, err)
+	require.Equal(t, 4, len(reports))
+	assert.Equal(t, "second", reports[0].Data.Get("description"))
+	assert.Equal(t, reportEventAction, reports[1].Event)
+}
+
+func TestReportQueueFlush(t *testing.T) {
+	sender := &testReportSender{}
+	q := testReportQueue(t, sender)
+	require.NoError(t, q.add(testQueuedReport(reportEventSuccess, "cafedead", "")))
+	q.flush()
+
+	assert.Equal(t, 1, sender.sentCount())
+	reports, err := q.load()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(reports))
+}
+
+func TestReportQueueRetry(t *testing.T) {
+	sender := &testReportSender{err: fmt.Errorf("Offline")}
+	q := testReportQueue(t, sender)
+	require.NoError(t, q.add(testQueuedReport(reportEventSuccess, "cafedead", "")))
+	q.flush()
+
+	reports, err := q.load()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(reports))
+	assert.Equal(t, 1, reports[0].Attempts)
+	assert.True(t, reports[0].NextAttempt.After(time.Now()))
+
+	// Not due yet, so not sent
+	sender.err = nil
+	q.flush()
+	assert.Equal(t, 0, sender.sentCount())
+
+	reports[0].NextAttempt = time.Now()
+	require.NoError(t, q.save(reports))
+	q.flush()
+	assert.Equal(t, 1, sender.sentCount())
+	reports, err = q.load()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(reports))
+}
+
+func TestReportQueueExpired(t *testing.T) {
+	sender := &testReportSender{}
+	q := testReportQueue(t, sender)
+	report := testQueuedReport(reportEventError, "cafedead", "old")
+	report.Created = time.Now().Add(-maxReportAge - time.Hour)
+	require.NoError(t, q.save([]queuedReport{report}))
+	q.flush()
+
+	assert.Equal(t, 0, sender.sentCount())
+	reports, err := q.load()
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(reports))
+}
+
+func TestReportQueueLimit(t *testing.T) {
+	q := testReportQueue(t, &testReportSender{})
+	for i := 0; i < maxQueuedReports+10; i++ {
+		require.NoError(t, q.add(testQueuedReport(reportEventError, fmt.Sprintf("request%d", i), "")))
+	}
+	reports, err := q.load()
+	require.NoError(t, err)
+	require.Equal(t, maxQueuedReports, len(reports))
+	assert.Equal(t, "request10", reports[0].Data.Get("request_id"))
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, time.Minute, retryDelay(1))
+	assert.Equal(t, 2*time.Minute, retryDelay(2))
+	assert.Equal(t, 4*time.Minute, retryDelay(3))
+	assert.Equal(t, maxReportRetryDelay, retryDelay(100))
+}
+
+func TestQueueReport(t *testing.T) {
+	sender := &testReportSender{}
+	ctx := testContext(t)
+	ctx.reports = testReportQueue(t, sender)
+	ctx.queueReport(reportEventError, errorReportData(fmt.Errorf("Test error")), &testUpdate, testOptions, "https://localhost/report")
+
+	// Sent in the background
+	require.Eventually(t, func() bool { return sender.sentCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+	data := sender.sent[0].Data
+	assert.Equal(t, "cafedead", data.Get("request_id"))
+	assert.Equal(t, "1.2.3-400+abcdef", data.Get("version"))
+	assert.Equal(t, string(updater.UnknownError), data.Get("error_type"))
+}
+
+func TestQueueHTTPReporter(t *testing.T) {
+	sender := &testReportSender{}
+	ctx := testContext(t)
+	ctx.reports = testReportQueue(t, sender)
+	ctx.reporter = ctx.newReporter([]ReporterConfig{{Type: "keybase"}, {Type: "http", URL: "https://localhost/events"}})
+	ctx.ReportSuccess(&testUpdate, testOptions)
+
+	// Both are queued, and the event for the collector doesn't replace the
+	// report for the API server
+	require.Eventually(t, func() bool { return sender.sentCount() == 2 }, 5*time.Second, 10*time.Millisecond)
+	sender.Lock()
+	defer sender.Unlock()
+	var report *queuedReport
+	for i := range sender.sent {
+		if sender.sent[i].Sink == reportSinkHTTP {
+			report = &sender.sent[i]
+		}
+	}
+	require.NotNil(t, report)
+	assert.Equal(t, "https://localhost/events", report.URI)
+	require.NotNil(t, report.SinkEvent)
+	assert.Equal(t, reporters.EventSuccess, report.SinkEvent.Type)
+	assert.Equal(t, "cafedead", report.SinkEvent.RequestID)
+}
+
+func TestSendReportHTTP(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	ctx := testContext(t)
+	event := reporters.SuccessEvent(&testUpdate, testOptions)
+	err := ctx.sendReport(queuedReport{Event: reportEventSuccess, URI: server.URL, Sink: reportSinkHTTP, SinkEvent: &event})
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(body), `"type":"success"`), string(body))
+
+	err = ctx.sendReport(queuedReport{Event: reportEventSuccess, URI: server.URL, Sink: "unknown"})
+	assert.EqualError(t, err, `Unknown report sink "unknown"`)
+}
+
+func TestFlushAfterUpdateCheck(t *testing.T) {
+	ctx, sender := testReportContext(t)
+	// Left from an earlier run
+	err := ctx.reports.add(testQueuedReport(reportEventError, "request1", ""))
+	require.NoError(t, err)
+
+	ctx.AfterUpdateCheck(nil)
+	report := sentReport(t, sender)
+	assert.Equal(t, "request1", report.Data.Get("request_id"))
+}
@@ -6,11 +6,14 @@ package keybase
 import (
 	"fmt"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -27,25 +30,45 @@ var testOptions = updater.UpdateOptions{
 	Version: "1.2.3-400+abcdef",
 }
 
-func TestReportError(t *testing.T) {
-	server := newServer("{}")
-	defer server.Close()
+// testReportContext returns a context whose reports are queued to sender
+func testReportContext(t *testing.T) (*context, *testReportSender) {
+	sender := &testReportSender{}
+	ctx := testContext(t)
+	ctx.reports = testReportQueue(t, sender)
+	return ctx, sender
+}
+
+// sentReport waits for the report to be sent (in the background)
+func sentReport(t *testing.T, sender *testReportSender) queuedReport {
+	require.Eventually(t, func() bool { return sender.sentCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+	sender.Lock()
+	defer sender.Unlock()
+	return sender.sent[0]
+}
 
+func TestReportError(t *testing.T) {
 	updateErr := updater.NewError(updater.PromptError, fmt.Errorf("Test error"))
-	ctx := testContext(t)
-	err := ctx.reportError(updateErr, &testUpdate, testOptions, server.URL, testReportTimeout)
-	assert.NoError(t, err)
+	ctx, sender := testReportContext(t)
+	ctx.ReportError(updateErr, &testUpdate, testOptions)
+	report := sentReport(t, sender)
+	assert.Equal(t, reportEventError, report.Event)
+	assert.Equal(t, ctx.config.endpoints().Error, report.URI)
+	assert.Equal(t, "prompt", report.Data.Get("error_type"))
+	assert.Equal(t, updateErr.Error(), report.Data.Get("description"))
+	assert.Equal(t, "deadbeef", report.Data.Get("install_id"))
+	assert.Equal(t, "cafedead", report.Data.Get("request_id"))
+	assert.Equal(t, "1.2.3-400+abcdef", report.Data.Get("version"))
 }
 
 func TestReportErrorEmpty(t *testing.T) {
-	server := newServer("{}")
-	defer server.Close()
-
 	updateErr := updater.NewError(updater.UnknownError, nil)
 	emptyOptions := updater.UpdateOptions{}
-	ctx := testContext(t)
-	err := ctx.reportError(updateErr, nil, emptyOptions, server.URL, testReportTimeout)
-	assert.NoError(t, err)
+	ctx, sender := testReportContext(t)
+	ctx.ReportError(updateErr, nil, emptyOptions)
+	report := sentReport(t, sender)
+	assert.Equal(t, "unknown", report.Data.Get("error_type"))
+	_, ok := report.Data["request_id"]
+	assert.False(t, ok)
 }
 
 func TestReportBadResponse(t *testing.T) {
@@ -53,7 +76,7 @@ func TestReportBadResponse(t *testing.T) {
 	defer server.Close()
 
 	ctx := testContext(t)
-	err := ctx.report(url.Values{}, &testUpdate, testOptions, server.URL, testReportTimeout)
+	err := ctx.post(url.Values{}, server.URL, testReportTimeout)
 	assert.EqualError(t, err, "Notify error returned bad HTTP status 500 Internal Server Error")
 }
 
@@ -62,44 +85,82 @@ func TestReportTimeout(t *testing.T) {
 	defer server.Close()
 
 	ctx := testContext(t)
-	err := ctx.report(url.Values{}, &testUpdate, testOptions, server.URL, 2*time.Millisecond)
+	err := ctx.post(url.Values{}, server.URL, 2*time.Millisecond)
 	require.Error(t, err)
 	assert.True(t, strings.Contains(err.Error(), "context deadline exceeded"), err.Error())
 }
 
 func TestReportActionApply(t *testing.T) {
-	server := newServer("{}")
-	defer server.Close()
-
-	ctx := testContext(t)
+	ctx, sender := testReportContext(t)
 	actionResponse := updater.UpdatePromptResponse{
 		Action:         updater.UpdateActionApply,
 		AutoUpdate:     false,
 		SnoozeDuration: 0,
 	}
-	err := ctx.reportAction(actionResponse, &testUpdate, testOptions, server.URL, testReportTimeout)
-	assert.NoError(t, err)
+	ctx.ReportAction(actionResponse, &testUpdate, testOptions)
+	report := sentReport(t, sender)
+	assert.Equal(t, reportEventAction, report.Event)
+	assert.Equal(t, ctx.config.endpoints().Action, report.URI)
+	assert.Equal(t, "apply", report.Data.Get("action"))
+	assert.Equal(t, "0", report.Data.Get("auto_update"))
 }
 
 func TestReportActionEmpty(t *testing.T) {
-	server := newServer("{}")
-	defer server.Close()
-
-	ctx := testContext(t)
+	ctx, sender := testReportContext(t)
 	actionResponse := updater.UpdatePromptResponse{
 		Action:         "",
 		AutoUpdate:     false,
 		SnoozeDuration: 0,
 	}
-	err := ctx.reportAction(actionResponse, &testUpdate, testOptions, server.URL, testReportTimeout)
-	assert.NoError(t, err)
+	ctx.ReportAction(actionResponse, &testUpdate, testOptions)
+	report := sentReport(t, sender)
+	assert.Equal(t, "", report.Data.Get("action"))
 }
 
 func TestReportSuccess(t *testing.T) {
+	ctx, sender := testReportContext(t)
+	ctx.ReportSuccess(&testUpdate, testOptions)
+	report := sentReport(t, sender)
+	assert.Equal(t, reportEventSuccess, report.Event)
+	assert.Equal(t, ctx.config.endpoints().Success, report.URI)
+	assert.Equal(t, "cafedead", report.Data.Get("request_id"))
+}
+
+func TestSendReport(t *testing.T) {
 	server := newServer("{}")
 	defer server.Close()
 
 	ctx := testContext(t)
-	err := ctx.reportSuccess(&testUpdate, testOptions, server.URL, testReportTimeout)
+	err := ctx.sendReport(queuedReport{Event: reportEventSuccess, URI: server.URL, Data: url.Values{}})
 	assert.NoError(t, err)
 }
+
+func TestNewReporter(t *testing.T) {
+	ctx := testContext(t)
+	assert.Nil(t, ctx.newReporter(nil))
+	assert.NotNil(t, ctx.getReporter())
+
+	dir, err := util.MakeTempDir("TestNewReporter.", 0700)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(dir)
+	path := filepath.Join(dir, "events.jsonl")
+
+	reporter := ctx.newReporter([]ReporterConfig{{Type: "file", Path: path}})
+	require.NotNil(t, reporter)
+	ctx.reporter = reporter
+	ctx.ReportSuccess(&testUpdate, testOptions)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(data), `"type":"success"`), string(data))
+
+	// Reports go to each reporter, and invalid configs are skipped
+	path2 := filepath.Join(dir, "events2.jsonl")
+	ctx.reporter = ctx.newReporter([]ReporterConfig{{Type: "file", Path: path}, {Type: "http"}, {Type: "unknown"}, {Type: "file", Path: path2}})
+	ctx.ReportError(fmt.Errorf("Test error"), &testUpdate, testOptions)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+	data, err = os.ReadFile(path2)
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(string(data), `"errorType":"unknown"`), string(data))
+}
@@ -4,6 +4,7 @@
 package keybase
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -20,11 +21,17 @@ type UpdateSource struct {
 	cfg      *config
 	log      Log
 	endpoint string
+	// trust is updated if the response has a newer trust store
+	trust *trustStore
 }
 
+// trustStoreResponseKey is the field of the API server response with a newer
+// code signing trust store, if there is one. The other fields are the update.
+const trustStoreResponseKey = "trustStore"
+
 // NewUpdateSource contructs an update source for keybase.io
 func NewUpdateSource(cfg *config, log Log) UpdateSource {
-	return newUpdateSource(cfg, defaultEndpoints.update, log)
+	return newUpdateSource(cfg, cfg.endpoints().Update, log)
 }
 
 func newUpdateSource(cfg *config, endpoint string, log Log) UpdateSource {
@@ -42,15 +49,28 @@ func (k UpdateSource) Description() string {
 
 // FindUpdate returns update for updater and options
 func (k UpdateSource) FindUpdate(options updater.UpdateOptions) (*updater.Update, error) {
-	return k.findUpdate(options, time.Minute)
+	return k.FindUpdateContext(gocontext.Background(), options)
+}
+
+// FindUpdateContext returns update for updater and options
+func (k UpdateSource) FindUpdateContext(ctx gocontext.Context, options updater.UpdateOptions) (*updater.Update, error) {
+	return k.findUpdate(ctx, options, time.Minute)
 }
 
-func (k UpdateSource) findUpdate(options updater.UpdateOptions, timeout time.Duration) (*updater.Update, error) {
+func (k UpdateSource) findUpdate(ctx gocontext.Context, options updater.UpdateOptions, timeout time.Duration) (*updater.Update, error) {
+	endpoint := k.endpoint
 	if options.URL != "" {
-		return nil, fmt.Errorf("Custom URLs not supported for this update source")
+		if err := validateEndpoint(options.URL); err != nil {
+			return nil, fmt.Errorf("Invalid update URL: %s", err)
+		}
+		endpoint = options.URL
+	}
+	if k.cfg.updatesDisabled() {
+		k.log.Info("Updates are disabled by the system policy")
+		return nil, nil
 	}
 
-	u, err := url.Parse(k.endpoint)
+	u, err := url.Parse(endpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -64,10 +84,16 @@ func (k UpdateSource) findUpdate(options updater.UpdateOptions, timeout time.Dur
 	urlValues.Add("upd_version", options.UpdaterVersion)
 	urlValues.Add("arch", options.Arch)
 	urlValues.Add("ignore_snooze", util.URLValueForBool(options.IgnoreSnooze))
+	if options.Channel != "" {
+		urlValues.Add("channel", options.Channel)
+	}
 
+	// KEYBASE_UPDATER_FORCE only asks the server for an update, the update
+	// is still checked here, unless it's forced explicitly (see
+	// ContextOptions)
 	force := util.EnvBool("KEYBASE_UPDATER_FORCE", false)
 	if force {
-		k.log.Info("KEYBASE_UPDATER_FORCE is true, will force update")
+		k.log.Warningf("KEYBASE_UPDATER_FORCE is true, asking the server to force an update")
 		urlValues.Add("force", util.URLValueForBool(force))
 	}
 
@@ -77,11 +103,11 @@ func (k UpdateSource) findUpdate(options updater.UpdateOptions, timeout time.Dur
 	u.RawQuery = urlValues.Encode()
 	urlString := u.String()
 
-	req, err := http.NewRequest("GET", urlString, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", urlString, nil)
 	if err != nil {
 		return nil, err
 	}
-	client, err := httpClient(timeout)
+	client, err := httpClientWithCert(k.cfg.caCerts(), timeout)
 	if err != nil {
 		return nil, err
 	}
@@ -97,11 +123,29 @@ func (k UpdateSource) findUpdate(options updater.UpdateOptions, timeout time.Dur
 	}
 
 	var reader io.Reader = resp.Body
+	var response map[string]json.RawMessage
+	if err = json.NewDecoder(reader).Decode(&response); err != nil {
+		return nil, fmt.Errorf("Invalid API response %s", err)
+	}
+	// The trust store is signed separately, so it isn't part of the update
+	// (and its manifest)
+	trustStore := response[trustStoreResponseKey]
+	delete(response, trustStoreResponseKey)
+	updateJSON, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
 	var update updater.Update
-	if err = json.NewDecoder(reader).Decode(&update); err != nil {
+	if err = json.Unmarshal(updateJSON, &update); err != nil {
 		return nil, fmt.Errorf("Invalid API response %s", err)
 	}
 
+	if len(trustStore) > 0 && string(trustStore) != "null" && k.trust != nil {
+		if err := k.trust.update(trustStore); err != nil {
+			k.log.Warningf("Error updating trust store: %s", err)
+		}
+	}
+
 	k.log.Debugf("Received update response: %#v", update)
 
 	return &update, nil
@@ -5,6 +5,7 @@ package keybase
 
 import (
 	"bytes"
+	gocontext "context"
 	"fmt"
 	"io"
 	"net/http"
@@ -93,7 +94,7 @@ func TestUpdateSourceTimeout(t *testing.T) {
 
 	cfg, _ := testConfig(t)
 	updateSource := newUpdateSource(cfg, server.URL, testLog)
-	update, err := updateSource.findUpdate(testOptions, 2*time.Millisecond)
+	update, err := updateSource.findUpdate(gocontext.Background(), testOptions, 2*time.Millisecond)
 	require.Error(t, err)
 	assert.True(t, strings.Contains(err.Error(), "context deadline exceeded"), err.Error())
 	assert.Nil(t, update)
@@ -121,7 +122,7 @@ func TestUpdateSourceRequest(t *testing.T) {
 	update, err := updateSource.FindUpdate(options)
 	require.NoError(t, err)
 	require.NotNil(t, testAPIServer.lastRequest)
-	require.Equal(t, "/?arch=arch&auto_update=0&ignore_snooze=0&install_id=&os_version=100.1&platform=platform&run_mode=env&upd_version=200.2&version=1.2.3-400%2Babcdef", testAPIServer.lastRequest.RequestURI)
+	require.Equal(t, "/?arch=arch&auto_update=0&channel=channel&ignore_snooze=0&install_id=&os_version=100.1&platform=platform&run_mode=env&upd_version=200.2&version=1.2.3-400%2Babcdef", testAPIServer.lastRequest.RequestURI)
 
 	// Change install ID and auto update
 	require.Equal(t, "deadbeef", update.InstallID)
@@ -134,5 +135,19 @@ func TestUpdateSourceRequest(t *testing.T) {
 	_, err = updateSource.FindUpdate(options)
 	require.NoError(t, err)
 	require.NotNil(t, testAPIServer.lastRequest)
-	assert.Equal(t, "/?arch=arch&auto_update=1&ignore_snooze=0&install_id=deadbeef&os_version=100.1&platform=platform&run_mode=env&upd_version=200.2&version=1.2.3-400%2Babcdef", testAPIServer.lastRequest.RequestURI)
+	assert.Equal(t, "/?arch=arch&auto_update=1&channel=channel&ignore_snooze=0&install_id=deadbeef&os_version=100.1&platform=platform&run_mode=env&upd_version=200.2&version=1.2.3-400%2Babcdef", testAPIServer.lastRequest.RequestURI)
+}
+
+func TestUpdateSourceDisabled(t *testing.T) {
+	testAPIServer := newTestAPIServer(t, updateJSONResponse)
+	defer testAPIServer.shutdown()
+
+	cfg, _ := testConfig(t)
+	disabled := true
+	cfg.managed.DisableUpdates = &disabled
+	updateSource := newUpdateSource(cfg, testAPIServer.server.URL, testLog)
+	update, err := updateSource.FindUpdate(testOptions)
+	require.NoError(t, err)
+	assert.Nil(t, update)
+	assert.Nil(t, testAPIServer.lastRequest)
 }
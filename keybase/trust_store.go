@@ -0,0 +1,104 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package keybase
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/keybase/go-updater/saltpack"
+	"github.com/keybase/go-updater/util"
+)
+
+// trustStoreRootKIDs are the (offline) root keys, which sign the code signing
+// trust store. Until a trust store is saved, validCodeSigningKIDs are the
+// valid signers.
+var trustStoreRootKIDs = saltpack.KIDs{
+	"01207c7d9d428f71d9880912822f18d28492c3373554895d0ab0137732164c579aee0a": true, // updater trust store root (offline)
+}
+
+// trustStorePath is where the code signing trust store is saved
+func (c *config) trustStorePath() (string, error) {
+	configDir, err := Dir(c.appName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "updater-trust-store.json"), nil
+}
+
+// trustStore is the code signing trust store, which can be updated by the
+// update source
+type trustStore struct {
+	path  string
+	roots saltpack.Signers
+	log   Log
+	// mtx protects store, and the file
+	mtx   sync.Mutex
+	store *saltpack.TrustStore
+}
+
+// newTrustStore loads the trust store saved at path, if there is one (and it's
+// signed by one of the roots)
+func newTrustStore(path string, roots saltpack.Signers, log Log) *trustStore {
+	t := &trustStore{path: path, roots: roots, log: log}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return t
+	}
+	if err == nil {
+		t.store, err = saltpack.ParseTrustStore(data, roots, log)
+	}
+	if err != nil {
+		log.Warningf("Ignoring trust store: %s", err)
+	}
+	return t
+}
+
+func loadTrustStore(cfg *config, log Log) *trustStore {
+	path, err := cfg.trustStorePath()
+	if err != nil {
+		log.Warningf("Error getting trust store path: %s", err)
+		return nil
+	}
+	return newTrustStore(path, trustStoreRootKIDs, log)
+}
+
+// signers returns the valid code signers: the trust store, if there is one,
+// and otherwise validCodeSigningKIDs
+func (t *trustStore) signers() saltpack.Signers {
+	if t == nil {
+		return validCodeSigningKIDs
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.store == nil {
+		return validCodeSigningKIDs
+	}
+	return *t.store
+}
+
+// update replaces the trust store with a (signed) newer version, and saves
+// it. An older (or the same) version is ignored.
+func (t *trustStore) update(data []byte) error {
+	store, err := saltpack.ParseTrustStore(data, t.roots, t.log)
+	if err != nil {
+		return err
+	}
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	if t.store != nil && store.Version <= t.store.Version {
+		t.log.Debugf("Ignoring trust store version %d, we have version %d", store.Version, t.store.Version)
+		return nil
+	}
+	if err := util.MakeParentDirs(t.path, 0700, t.log); err != nil {
+		return err
+	}
+	if err := util.NewFile(t.path, data, 0600).Save(t.log); err != nil {
+		return err
+	}
+	t.log.Infof("Updated trust store to version %d", store.Version)
+	t.store = store
+	return nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package keybase
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keybase/go-updater/saltpack"
+	"github.com/keybase/go-updater/saltpack/saltpacktest"
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTrustStorePath(t *testing.T) string {
+	dir, err := util.MakeTempDir("TestTrustStore.", 0700)
+	require.NoError(t, err)
+	t.Cleanup(func() { util.RemoveFileAtPath(dir) })
+	return filepath.Join(dir, "updater-trust-store.json")
+}
+
+func TestTrustStore(t *testing.T) {
+	root, err := saltpacktest.NewSigner()
+	require.NoError(t, err)
+	signer, err := saltpacktest.NewSigner()
+	require.NoError(t, err)
+	path := testTrustStorePath(t)
+	roots := saltpack.KIDs{root.KID: true}
+
+	// No trust store yet
+	trust := newTrustStore(path, roots, testLog)
+	assert.Equal(t, validCodeSigningKIDs, trust.signers())
+	var nilTrust *trustStore
+	assert.Equal(t, validCodeSigningKIDs, nilTrust.signers())
+
+	data, err := root.SignTrustStore(saltpack.TrustStore{Version: 2, Keys: []saltpack.TrustedKey{{KID: signer.KID}}})
+	require.NoError(t, err)
+	err = trust.update(data)
+	require.NoError(t, err)
+	assert.NoError(t, trust.signers().CheckSigner(signer.KID))
+	// The trust store replaces the compiled in keys
+	assert.Error(t, trust.signers().CheckSigner("01209092ae4e790763dc7343851b977930f35b16cf43ab0ad900a2af3d3ad5cea1a10a"))
+
+	// An older version is ignored
+	data, err = root.SignTrustStore(saltpack.TrustStore{Version: 1})
+	require.NoError(t, err)
+	err = trust.update(data)
+	require.NoError(t, err)
+	assert.NoError(t, trust.signers().CheckSigner(signer.KID))
+
+	// Not signed by a root
+	data, err = signer.SignTrustStore(saltpack.TrustStore{Version: 3})
+	require.NoError(t, err)
+	err = trust.update(data)
+	assert.EqualError(t, err, "error verifying trust store: unknown signer KID: "+signer.KID)
+
+	// It was saved
+	loaded := newTrustStore(path, roots, testLog)
+	assert.NoError(t, loaded.signers().CheckSigner(signer.KID))
+	// And isn't trusted with other roots
+	loaded = newTrustStore(path, saltpack.KIDs{}, testLog)
+	assert.Equal(t, validCodeSigningKIDs, loaded.signers())
+}
+
+func TestTrustStoreRoot(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(testMessagePath), "trust-store.json"))
+	require.NoError(t, err)
+	trust := newTrustStore(testTrustStorePath(t), trustStoreRootKIDs, testLog)
+	err = trust.update(data)
+	require.NoError(t, err)
+	store, ok := trust.signers().(saltpack.TrustStore)
+	require.True(t, ok)
+	assert.Equal(t, int64(1), store.Version)
+
+	ctx := testContext(t)
+	ctx.trust = trust
+	assert.NoError(t, ctx.Verify(testContextUpdate(testMessagePath, testSignatureKeybot)))
+}
+
+func TestTrustStoreVerify(t *testing.T) {
+	root, err := saltpacktest.NewSigner()
+	require.NoError(t, err)
+	signer, err := saltpacktest.NewSigner()
+	require.NoError(t, err)
+	revoked, err := saltpacktest.NewSigner()
+	require.NoError(t, err)
+	expired, err := saltpacktest.NewSigner()
+	require.NoError(t, err)
+	yesterday := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Second)
+	trust := newTrustStore(testTrustStorePath(t), saltpack.KIDs{root.KID: true}, testLog)
+	data, err := root.SignTrustStore(saltpack.TrustStore{Version: 1, Keys: []saltpack.TrustedKey{
+		{KID: signer.KID},
+		{KID: revoked.KID, Revoked: true},
+		{KID: expired.KID, NotAfter: &yesterday},
+	}})
+	require.NoError(t, err)
+	err = trust.update(data)
+	require.NoError(t, err)
+
+	ctx := testContext(t)
+	ctx.trust = trust
+	message := []byte("This is a test message\n")
+	signature, err := signer.Sign(message)
+	require.NoError(t, err)
+	update := testContextUpdate(testMessagePath, signature)
+	assert.NoError(t, ctx.Verify(update))
+
+	update.Asset.Signature, err = revoked.Sign(message)
+	require.NoError(t, err)
+	assert.EqualError(t, ctx.Verify(update), "error verifying signature: revoked signer KID: "+revoked.KID)
+
+	update.Asset.Signature, err = expired.Sign(message)
+	require.NoError(t, err)
+	assert.EqualError(t, ctx.Verify(update), fmt.Sprintf("error verifying signature: signer KID %s expired at %s", expired.KID, yesterday.Format(time.RFC3339)))
+
+	// Keybot is a compiled in signer, but not in the trust store
+	update.Asset.Signature = testSignatureKeybot
+	assert.Error(t, ctx.Verify(update))
+}
+
+func TestUpdateSourceTrustStore(t *testing.T) {
+	root, err := saltpacktest.NewSigner()
+	require.NoError(t, err)
+	signer, err := saltpacktest.NewSigner()
+	require.NoError(t, err)
+	signed, err := root.SignTrustStore(saltpack.TrustStore{Version: 1, Keys: []saltpack.TrustedKey{{KID: signer.KID}}})
+	require.NoError(t, err)
+	server := newServer(fmt.Sprintf(`{"version": "1.0.15", "extra": true, "trustStore": %s}`, signed))
+	defer server.Close()
+
+	cfg, _ := testConfig(t)
+	updateSource := newUpdateSource(cfg, server.URL, testLog)
+	updateSource.trust = newTrustStore(testTrustStorePath(t), saltpack.KIDs{root.KID: true}, testLog)
+	update, err := updateSource.FindUpdate(testOptions)
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.15", update.Version)
+	assert.NoError(t, updateSource.trust.signers().CheckSigner(signer.KID))
+
+	// The trust store isn't part of the update manifest
+	manifest, err := update.ManifestJSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"extra":true,"version":"1.0.15"}`, string(manifest))
+}
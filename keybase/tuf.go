@@ -0,0 +1,81 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package keybase
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/tuf"
+)
+
+// TUFConfig enables verifying updates against signed (TUF) metadata
+type TUFConfig struct {
+	// URL is where the metadata files are
+	URL string `json:"url"`
+	// Root is the path to the initial root metadata, the root of trust
+	Root string `json:"root"`
+}
+
+// tufDir is where the trusted metadata is saved
+func (c *config) tufDir() (string, error) {
+	configDir, err := Dir(c.appName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "tuf"), nil
+}
+
+// errorUpdateSource is an update source that always errors, so we don't accept
+// updates we can't verify
+type errorUpdateSource struct {
+	description string
+	err         error
+}
+
+func (s errorUpdateSource) Description() string {
+	return s.description
+}
+
+func (s errorUpdateSource) FindUpdate(options updater.UpdateOptions) (*updater.Update, error) {
+	return nil, s.err
+}
+
+// tufUpdateSource returns source, verified against the TUF metadata (if
+// configured). If the metadata client can't be created, finding an update
+// fails.
+func tufUpdateSource(cfg *config, source updater.UpdateSource, log Log) updater.UpdateSource {
+	tufConfig := cfg.store.TUF
+	if tufConfig == nil {
+		return source
+	}
+	client, err := newTUFClient(cfg, *tufConfig, log)
+	if err != nil {
+		log.Errorf("Error loading TUF metadata: %s", err)
+		return errorUpdateSource{description: source.Description(), err: fmt.Errorf("TUF metadata isn't available: %s", err)}
+	}
+	return tuf.NewUpdateSource(source, client)
+}
+
+func newTUFClient(cfg *config, tufConfig TUFConfig, log Log) (*tuf.Client, error) {
+	if err := validateEndpoint(tufConfig.URL); err != nil {
+		return nil, err
+	}
+	root, err := os.ReadFile(tufConfig.Root)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := cfg.tufDir()
+	if err != nil {
+		return nil, err
+	}
+	client, err := httpClientWithCert(cfg.caCerts(), time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	return tuf.NewClient(tuf.NewHTTPFetcher(tufConfig.URL, client), dir, root, log)
+}
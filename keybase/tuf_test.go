@@ -0,0 +1,92 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package keybase
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSource is an update source that returns an update
+type testSource struct {
+	update *updater.Update
+}
+
+func (s testSource) Description() string {
+	return "Test"
+}
+
+func (s testSource) FindUpdate(options updater.UpdateOptions) (*updater.Update, error) {
+	return s.update, nil
+}
+
+func testTUFDir() string {
+	_, filename, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(filename), "../test/tuf")
+}
+
+func TestTUFUpdateSource(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir(testTUFDir())))
+	defer server.Close()
+
+	data, err := os.ReadFile(filepath.Join(testTUFDir(), "../update.json"))
+	require.NoError(t, err)
+	var update updater.Update
+	require.NoError(t, json.Unmarshal(data, &update))
+
+	cfg, _ := testConfig(t)
+	configDir, err := Dir(cfg.appName)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(configDir)
+
+	// Not configured, so not wrapped
+	src := testSource{update: &update}
+	assert.Equal(t, src, tufUpdateSource(cfg, src, testLog))
+
+	cfg.store.TUF = &TUFConfig{URL: server.URL, Root: filepath.Join(testTUFDir(), "1.root.json")}
+	tufSrc := tufUpdateSource(cfg, src, testLog)
+	assert.Equal(t, "Test (TUF)", tufSrc.Description())
+	found, err := tufSrc.FindUpdate(updater.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, &update, found)
+	tufDir, err := cfg.tufDir()
+	require.NoError(t, err)
+	exists, err := util.FileExists(filepath.Join(tufDir, "targets.json"))
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// An update that isn't in the signed metadata
+	other := update
+	other.Version = "1.2.4"
+	_, err = tufUpdateSource(cfg, testSource{update: &other}, testLog).FindUpdate(updater.UpdateOptions{})
+	assert.EqualError(t, err, "Target Test-1.2.3-400+abcdef.zip isn't trusted: not for version 1.2.4")
+}
+
+func TestTUFUpdateSourceInvalid(t *testing.T) {
+	cfg, _ := testConfig(t)
+	configDir, err := Dir(cfg.appName)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(configDir)
+
+	// We fail closed if the root of trust isn't available
+	cfg.store.TUF = &TUFConfig{URL: "https://localhost/tuf", Root: filepath.Join(testTUFDir(), "missing.root.json")}
+	src := tufUpdateSource(cfg, testSource{update: &updater.Update{}}, testLog)
+	assert.Equal(t, "Test", src.Description())
+	_, err = src.FindUpdate(updater.UpdateOptions{})
+	require.Error(t, err)
+
+	cfg.store.TUF = &TUFConfig{URL: "http://example.com/tuf", Root: filepath.Join(testTUFDir(), "1.root.json")}
+	_, err = tufUpdateSource(cfg, testSource{update: &updater.Update{}}, testLog).FindUpdate(updater.UpdateOptions{})
+	require.Error(t, err)
+}
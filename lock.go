@@ -0,0 +1,191 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/keybase/go-updater/util"
+)
+
+// lockPollInterval is how often we try to acquire the update lock while
+// waiting for it
+const lockPollInterval = 100 * time.Millisecond
+
+// errLockHeld is returned by lockFile if another process (or file) holds the
+// lock
+var errLockHeld = errors.New("Lock is held")
+
+// updateLock is a cross-process lock, so only one updater touches an install
+// at a time. The lock file contains the PID of the process holding the lock.
+type updateLock struct {
+	file *os.File
+	log  Log
+	// readOnly is whether the lock file was opened read only (see
+	// openLockFile), so we don't write our PID to it
+	readOnly bool
+}
+
+// SetLockTimeout sets how long to wait for another updater to finish before
+// giving up with a lock error. If 0 (the default), we don't wait.
+func (u *Updater) SetLockTimeout(timeout time.Duration) {
+	u.lockTimeout = timeout
+}
+
+// SetLockPath sets the path to the update lock. It should be the same for all
+// updaters of an install, whichever user runs them, like a path next to the
+// install. If not set (the default), the lock is in the cache dir, which is
+// per user.
+func (u *Updater) SetLockPath(path string) {
+	u.lockFilePath = path
+}
+
+// lockPath is the path to the update lock
+func (u *Updater) lockPath() (string, error) {
+	if u.lockFilePath != "" {
+		return u.lockFilePath, nil
+	}
+	dir, err := u.openCache()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "updater.lock"), nil
+}
+
+// lock acquires the update lock, waiting for it up to the lock timeout (or
+// until goCtx is done). If another updater holds the lock, it returns a lock
+// error.
+func (u *Updater) lock(goCtx context.Context) (*updateLock, error) {
+	path, err := u.lockPath()
+	if err != nil {
+		return nil, lockErr(err)
+	}
+	file, readOnly, err := openLockFile(path)
+	if err != nil {
+		return nil, lockErr(err)
+	}
+	deadline := time.Now().Add(u.lockTimeout)
+	for {
+		err := lockFile(file)
+		if err == nil {
+			break
+		}
+		if err != errLockHeld {
+			_ = file.Close()
+			return nil, lockErr(err)
+		}
+		if !time.Now().Before(deadline) {
+			_ = file.Close()
+			return nil, lockErr(fmt.Errorf("Another updater is running%s", lockHolderDescription(path)))
+		}
+		select {
+		case <-goCtx.Done():
+			_ = file.Close()
+			return nil, CancelErr(goCtx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	if !readOnly {
+		if err := file.Truncate(0); err != nil {
+			u.log.Warningf("Error clearing lock file: %s", err)
+		}
+		if _, err := file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0); err != nil {
+			u.log.Warningf("Error writing pid to lock file: %s", err)
+		}
+	}
+	u.log.Debugf("Acquired update lock: %s", path)
+	return &updateLock{file: file, log: u.log, readOnly: readOnly}, nil
+}
+
+// openLockFile opens (or creates) the lock file. If another user created it,
+// we may only be able to open it for reading, which is enough to lock it, but
+// not to write our PID. It returns whether it was opened read only, or isn't
+// ours, so we shouldn't write to it. The path may be shared, so a symlink
+// isn't followed, and it must be a regular file.
+func openLockFile(path string) (*os.File, bool, error) {
+	readOnly := false
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|openNoFollow, 0644)
+	if os.IsPermission(err) {
+		readOnly = true
+		file, err = os.OpenFile(path, os.O_RDONLY|openNoFollow, 0)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, false, err
+	}
+	if !info.Mode().IsRegular() {
+		_ = file.Close()
+		return nil, false, fmt.Errorf("%s isn't a regular file", path)
+	}
+	if !ownedByCurrentUser(info) {
+		readOnly = true
+	}
+	return file, readOnly, nil
+}
+
+// unlock releases the lock. The lock file isn't removed, since another process
+// may have it open (waiting for the lock).
+func (l *updateLock) unlock() {
+	if !l.readOnly {
+		if err := l.file.Truncate(0); err != nil {
+			l.log.Warningf("Error clearing lock file: %s", err)
+		}
+	}
+	if err := unlockFile(l.file); err != nil {
+		l.log.Warningf("Error unlocking: %s", err)
+	}
+	if err := l.file.Close(); err != nil {
+		l.log.Warningf("Error closing lock file: %s", err)
+	}
+}
+
+// lockHolder returns the PID of the process holding the lock at path, or 0 if
+// unknown
+func lockHolder(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
+
+// updateLockHolder returns whether a process holds the update lock, and its
+// PID (or 0 if unknown). It doesn't lock the lock file at all (even shared),
+// so it can't get in the way of an updater trying to take the lock. The lock
+// file only has a PID while the lock is held, or if the holder exited without
+// unlocking, so the lock is held if the process with the PID exists.
+func (u *Updater) updateLockHolder() (bool, int) {
+	path, err := u.lockPath()
+	if err != nil {
+		return false, 0
+	}
+	pid := lockHolder(path)
+	if !util.ProcessExists(pid) {
+		return false, 0
+	}
+	return true, pid
+}
+
+func lockHolderDescription(path string) string {
+	if pid := lockHolder(path); pid != 0 {
+		return fmt.Sprintf(" (pid %d)", pid)
+	}
+	return ""
+}
@@ -0,0 +1,36 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+//go:build !windows
+// +build !windows
+
+package updater
+
+import (
+	"os"
+	"syscall"
+)
+
+// openNoFollow makes open fail if the path is a symlink
+const openNoFollow = syscall.O_NOFOLLOW
+
+// ownedByCurrentUser returns true if the file (info) is owned by the current
+// user
+func ownedByCurrentUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Getuid()
+}
+
+func lockFile(file *os.File) error {
+	// LOCK_EX = exclusive
+	// LOCK_NB = nonblocking
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return errLockHeld
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
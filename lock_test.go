@@ -0,0 +1,185 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdaterLock(t *testing.T) {
+	upr, err := newTestUpdater(t)
+	require.NoError(t, err)
+
+	lock, err := upr.lock(context.Background())
+	require.NoError(t, err)
+	path, err := upr.lockPath()
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(os.Getpid()), string(data))
+
+	// Fail immediately if held
+	_, err = upr.lock(context.Background())
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsLock())
+	assert.EqualError(t, err, "Update Error (lock): Another updater is running (pid "+strconv.Itoa(os.Getpid())+")")
+
+	lock.unlock()
+	lock, err = upr.lock(context.Background())
+	require.NoError(t, err)
+	lock.unlock()
+}
+
+func TestUpdaterLockWait(t *testing.T) {
+	upr, err := newTestUpdater(t)
+	require.NoError(t, err)
+	upr.SetLockTimeout(10 * time.Second)
+
+	lock, err := upr.lock(context.Background())
+	require.NoError(t, err)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		lock.unlock()
+	}()
+	lock2, err := upr.lock(context.Background())
+	require.NoError(t, err)
+
+	// Stop waiting if canceled
+	goCtx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = upr.lock(goCtx)
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsCancel())
+	lock2.unlock()
+}
+
+func TestUpdaterLockPath(t *testing.T) {
+	dir, err := util.MakeTempDir("TestUpdaterLockPath.", 0700)
+	require.NoError(t, err)
+	t.Cleanup(func() { util.RemoveFileAtPath(dir) })
+	path := filepath.Join(dir, "updater.lock")
+
+	// Updaters with different cache dirs (users) share the lock
+	upr, err := newTestUpdater(t)
+	require.NoError(t, err)
+	upr.SetLockPath(path)
+	upr2, err := newTestUpdater(t)
+	require.NoError(t, err)
+	upr2.SetLockPath(path)
+
+	lock, err := upr.lock(context.Background())
+	require.NoError(t, err)
+	_, err = upr2.lock(context.Background())
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsLock())
+	lock.unlock()
+}
+
+func TestUpdaterLockSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Symlinks aren't checked on Windows")
+	}
+	dir, err := util.MakeTempDir("TestUpdaterLockSymlink.", 0700)
+	require.NoError(t, err)
+	t.Cleanup(func() { util.RemoveFileAtPath(dir) })
+	target := filepath.Join(dir, "target")
+	err = os.WriteFile(target, []byte("important"), 0600)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "updater.lock")
+	err = os.Symlink(target, path)
+	require.NoError(t, err)
+
+	upr, err := newTestUpdater(t)
+	require.NoError(t, err)
+	upr.SetLockPath(path)
+	_, err = upr.lock(context.Background())
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsLock())
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "important", string(data))
+
+	// Nor a dir
+	upr.SetLockPath(dir)
+	_, err = upr.lock(context.Background())
+	require.Error(t, err)
+}
+
+func TestUpdaterLocked(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+
+	// Another updater is running
+	lock, err := upr.lock(context.Background())
+	require.NoError(t, err)
+
+	_, err = upr.Update(ctx)
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsLock())
+	assert.Nil(t, ctx.errReported)
+
+	_, _, err = upr.CheckAndDownload(ctx)
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsLock())
+
+	_, err = upr.ApplyDownloaded(ctx)
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsLock())
+
+	err = upr.Recover()
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsLock())
+
+	// Snoozing the available update checks for it
+	err = upr.Snooze(ctx, "", time.Hour)
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsLock())
+
+	lock.unlock()
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+}
+
+func TestUpdaterLockHolder(t *testing.T) {
+	upr, err := newTestUpdater(t)
+	require.NoError(t, err)
+	path, err := upr.lockPath()
+	require.NoError(t, err)
+
+	locked, pid := upr.updateLockHolder()
+	assert.False(t, locked)
+	assert.Equal(t, 0, pid)
+
+	// A PID left by an updater that exited without unlocking
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	err = cmd.Run()
+	require.NoError(t, err)
+	err = os.WriteFile(path, []byte(strconv.Itoa(cmd.Process.Pid)), 0600)
+	require.NoError(t, err)
+	locked, pid = upr.updateLockHolder()
+	assert.False(t, locked)
+	assert.Equal(t, 0, pid)
+
+	lock, err := upr.lock(context.Background())
+	require.NoError(t, err)
+	locked, pid = upr.updateLockHolder()
+	assert.True(t, locked)
+	assert.Equal(t, os.Getpid(), pid)
+	lock.unlock()
+}
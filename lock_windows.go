@@ -0,0 +1,39 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+//go:build windows
+// +build windows
+
+package updater
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockOffset is where we lock a byte in the lock file. It's past the contents
+// (the PID), so other processes can still read it.
+var lockOffset = windows.Overlapped{OffsetHigh: 1}
+
+// openNoFollow is 0, since symlinks on Windows need privileges to create
+const openNoFollow = 0
+
+// ownedByCurrentUser returns true, since we don't check owners on Windows
+func ownedByCurrentUser(info os.FileInfo) bool {
+	return true
+}
+
+func lockFile(file *os.File) error {
+	overlapped := lockOffset
+	err := windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &overlapped)
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return errLockHeld
+	}
+	return err
+}
+
+func unlockFile(file *os.File) error {
+	overlapped := lockOffset
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &overlapped)
+}
@@ -0,0 +1,132 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaintenanceWindow is the local time of day (and days of the week) when
+// updates can be applied automatically. For example, a Start of "01:00", an
+// End of "05:00" and Days of ["mon", "tue", "wed", "thu", "fri"] is 1am to 5am
+// on weekdays. If End is before Start, the window ends the next day. If they
+// are the same, the window is the whole day.
+type MaintenanceWindow struct {
+	// Start is when the window opens, as HH:MM (local time)
+	Start string `json:"start"`
+	// End is when the window closes, as HH:MM (local time)
+	End string `json:"end"`
+	// Days are the days (sun, mon, tue, wed, thu, fri, sat) the window opens
+	// on. If empty, it opens every day.
+	Days []string `json:"days,omitempty"`
+}
+
+// MaintenanceWindowConfig is an optional interface for a Config, to only
+// apply updates during a maintenance window. Updates are still downloaded
+// outside the window.
+type MaintenanceWindowConfig interface {
+	// GetMaintenanceWindow returns the maintenance window, or nil if updates
+	// can be applied any time
+	GetMaintenanceWindow() *MaintenanceWindow
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// parseTimeOfDay parses HH:MM to a duration since midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid time of day %q, should be HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Validate returns an error if the window is invalid
+func (w MaintenanceWindow) Validate() error {
+	if _, err := parseTimeOfDay(w.Start); err != nil {
+		return err
+	}
+	if _, err := parseTimeOfDay(w.End); err != nil {
+		return err
+	}
+	for _, day := range w.Days {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("Invalid day %q", day)
+		}
+	}
+	return nil
+}
+
+// opensOn returns true if the window opens on day
+func (w MaintenanceWindow) opensOn(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpen returns true if t (in its location) is in the window
+func (w MaintenanceWindow) IsOpen(t time.Time) (bool, error) {
+	if err := w.Validate(); err != nil {
+		return false, err
+	}
+	start, _ := parseTimeOfDay(w.Start)
+	end, _ := parseTimeOfDay(w.End)
+	timeOfDay := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+	switch {
+	case start == end:
+		return w.opensOn(t.Weekday()), nil
+	case start < end:
+		return w.opensOn(t.Weekday()) && timeOfDay >= start && timeOfDay < end, nil
+	default:
+		// The window ends the next day, so after midnight it's open if it
+		// opened the day before
+		if timeOfDay >= start {
+			return w.opensOn(t.Weekday()), nil
+		}
+		return timeOfDay < end && w.opensOn(t.AddDate(0, 0, -1).Weekday()), nil
+	}
+}
+
+// inMaintenanceWindow returns true if the update can be applied now. Critical
+// updates, and updates from the check command (which the user asked for), can
+// be applied outside the window. If the window is invalid, it is ignored.
+func (u *Updater) inMaintenanceWindow(ctx Context, update Update) bool {
+	windowConfig, ok := u.config.(MaintenanceWindowConfig)
+	if !ok {
+		return true
+	}
+	window := windowConfig.GetMaintenanceWindow()
+	if window == nil {
+		return true
+	}
+	if update.Type == UpdateTypeCritical || ctx.IsCheckCommand() {
+		u.log.Infof("Ignoring maintenance window (%s-%s) for %s", window.Start, window.End, update.Version)
+		return true
+	}
+	isOpen, err := window.IsOpen(time.Now())
+	if err != nil {
+		u.log.Warningf("Ignoring invalid maintenance window: %s", err)
+		return true
+	}
+	if !isOpen {
+		u.log.Infof("Outside maintenance window (%s-%s), not applying %s", window.Start, window.End, update.Version)
+	}
+	return isOpen
+}
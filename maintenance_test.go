@@ -0,0 +1,114 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"testing"
+	"time"
+
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceWindowIsOpen(t *testing.T) {
+	// 2026-01-05 is a Monday
+	at := func(day int, hour int, min int) time.Time {
+		return time.Date(2026, 1, day, hour, min, 0, 0, time.Local)
+	}
+	weekdays := []string{"mon", "tue", "wed", "thu", "fri"}
+	cases := []struct {
+		window MaintenanceWindow
+		t      time.Time
+		isOpen bool
+	}{
+		{MaintenanceWindow{Start: "01:00", End: "05:00", Days: weekdays}, at(5, 1, 0), true},
+		{MaintenanceWindow{Start: "01:00", End: "05:00", Days: weekdays}, at(5, 4, 59), true},
+		{MaintenanceWindow{Start: "01:00", End: "05:00", Days: weekdays}, at(5, 5, 0), false},
+		{MaintenanceWindow{Start: "01:00", End: "05:00", Days: weekdays}, at(5, 0, 59), false},
+		// Saturday
+		{MaintenanceWindow{Start: "01:00", End: "05:00", Days: weekdays}, at(10, 2, 0), false},
+		{MaintenanceWindow{Start: "01:00", End: "05:00"}, at(10, 2, 0), true},
+		// Ends the next day
+		{MaintenanceWindow{Start: "22:00", End: "02:00", Days: []string{"Fri"}}, at(9, 23, 0), true},
+		{MaintenanceWindow{Start: "22:00", End: "02:00", Days: []string{"Fri"}}, at(10, 1, 0), true},
+		{MaintenanceWindow{Start: "22:00", End: "02:00", Days: []string{"Fri"}}, at(10, 23, 0), false},
+		{MaintenanceWindow{Start: "22:00", End: "02:00", Days: []string{"Fri"}}, at(9, 1, 0), false},
+		// Whole day
+		{MaintenanceWindow{Start: "00:00", End: "00:00", Days: []string{"sun"}}, at(11, 12, 0), true},
+		{MaintenanceWindow{Start: "00:00", End: "00:00", Days: []string{"sun"}}, at(12, 12, 0), false},
+	}
+	for _, c := range cases {
+		isOpen, err := c.window.IsOpen(c.t)
+		require.NoError(t, err)
+		assert.Equal(t, c.isOpen, isOpen, "%#v at %s", c.window, c.t)
+	}
+}
+
+func TestMaintenanceWindowInvalid(t *testing.T) {
+	for _, window := range []MaintenanceWindow{
+		{Start: "", End: "05:00"},
+		{Start: "1am", End: "05:00"},
+		{Start: "01:00", End: "24:00"},
+		{Start: "01:00", End: "05:00", Days: []string{"monday"}},
+	} {
+		assert.Error(t, window.Validate(), "%#v", window)
+		_, err := window.IsOpen(time.Now())
+		assert.Error(t, err)
+	}
+}
+
+// testMaintenanceWindow returns a window that is open now (or not)
+func testMaintenanceWindow(isOpen bool) *MaintenanceWindow {
+	now := time.Now()
+	if isOpen {
+		return &MaintenanceWindow{Start: now.Add(-time.Hour).Format("15:04"), End: now.Add(time.Hour).Format("15:04")}
+	}
+	return &MaintenanceWindow{Start: now.Add(2 * time.Hour).Format("15:04"), End: now.Add(3 * time.Hour).Format("15:04")}
+}
+
+func TestUpdaterMaintenanceWindow(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	cfg := &testConfig{window: testMaintenanceWindow(false)}
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), cfg)
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+
+	// Outside the window, the update is downloaded but not applied
+	update, err := upr.Update(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, update)
+	assert.False(t, ctx.successReported)
+	path, err := upr.FindDownloadedAsset(*testUpdate(testServer.URL).Asset)
+	require.NoError(t, err)
+	require.NotEqual(t, "", path)
+
+	// In the window, the download is applied
+	cfg.window = testMaintenanceWindow(true)
+	update, err = upr.Update(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, update)
+	assert.Equal(t, path, update.Asset.LocalPath)
+	assert.True(t, ctx.successReported)
+	exists, err := util.FileExists(path)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestUpdaterMaintenanceWindowCritical(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	critical := testUpdate(testServer.URL)
+	critical.Type = UpdateTypeCritical
+	upr, err := newTestUpdaterWithServer(t, testServer, critical, &testConfig{window: testMaintenanceWindow(false)})
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+	update, err := upr.Update(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, update)
+	assert.True(t, ctx.successReported)
+}
@@ -0,0 +1,75 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// UnmarshalJSON decodes an update, and keeps the JSON, which the manifest is
+// made from (see ManifestJSON)
+func (u *Update) UnmarshalJSON(data []byte) error {
+	// update doesn't have this method, so we don't recurse
+	type update Update
+	var decoded update
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*u = Update(decoded)
+	u.raw = append([]byte{}, data...)
+	return nil
+}
+
+// ManifestJSON returns the canonical encoding of the update, which is what
+// Signature signs. It is the JSON the update was decoded from (or if it
+// wasn't, the JSON of the update), with object keys sorted, no whitespace and
+// HTML characters unescaped. Fields we don't know about are included, so they
+// can't be added without breaking the signature. The fields for a request
+// (installId, requestId and needUpdate), the asset localPath and the signature
+// itself are left out, so a manifest can be signed once, when it's published.
+func (u Update) ManifestJSON() ([]byte, error) {
+	data := u.raw
+	if data == nil {
+		var err error
+		if data, err = json.Marshal(u); err != nil {
+			return nil, err
+		}
+	}
+
+	// Decode into maps, which are encoded with sorted keys
+	var manifest map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"installId", "requestId", "needUpdate", "signature"} {
+		delete(manifest, key)
+	}
+	if asset, ok := manifest["asset"].(map[string]interface{}); ok {
+		delete(asset, "localPath")
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(manifest); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// checkManifestTarget checks that a signed update is for our channel and
+// platform, so an update signed for another one can't be replayed to us
+func checkManifestTarget(update Update, options UpdateOptions) error {
+	if update.Platform != options.Platform {
+		return fmt.Errorf("Update is for platform %q, not %q", update.Platform, options.Platform)
+	}
+	if update.Channel != options.Channel {
+		return fmt.Errorf("Update is for channel %q, not %q", update.Channel, options.Channel)
+	}
+	return nil
+}
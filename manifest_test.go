@@ -0,0 +1,136 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestJSON(t *testing.T) {
+	update := Update{
+		Version:     "1.0.1",
+		Name:        "Test",
+		Description: "Bug fixes <&>",
+		InstallID:   "deadbeef",
+		RequestID:   "cafedead",
+		Type:        UpdateTypeCritical,
+		PublishedAt: 1700000000123,
+		Asset: &Asset{
+			Name:      "test.zip",
+			URL:       "https://localhost/test.zip",
+			Digest:    "abcdef",
+			Signature: "sig",
+			LocalPath: "/tmp/test.zip",
+		},
+		NeedUpdate: true,
+		Signature:  "manifest sig",
+	}
+	data, err := update.ManifestJSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"asset":{"digest":"abcdef","name":"test.zip","signature":"sig","url":"https://localhost/test.zip"},"description":"Bug fixes <&>","name":"Test","publishedAt":1700000000123,"type":2,"version":"1.0.1"}`, string(data))
+
+	// Fields for the request aren't signed
+	update.InstallID = "other"
+	update.RequestID = "other"
+	update.NeedUpdate = false
+	update.Asset.LocalPath = ""
+	update.Signature = ""
+	other, err := update.ManifestJSON()
+	require.NoError(t, err)
+	assert.Equal(t, data, other)
+
+	update.Version = "1.0.2"
+	other, err = update.ManifestJSON()
+	require.NoError(t, err)
+	assert.NotEqual(t, data, other)
+}
+
+func TestManifestJSONRaw(t *testing.T) {
+	var update Update
+	err := json.Unmarshal([]byte(`{
+		"version": "1.0.1",
+		"platform": "linux",
+		"channel": "test",
+		"extra": {"b": 1, "a": "<&>"},
+		"installId": "deadbeef",
+		"asset": {"name": "test.zip", "localPath": "/tmp/test.zip", "other": true},
+		"signature": "manifest sig"
+	}`), &update)
+	require.NoError(t, err)
+	assert.Equal(t, "linux", update.Platform)
+	assert.Equal(t, "test", update.Channel)
+
+	// Fields we don't know about are signed too
+	data, err := update.ManifestJSON()
+	require.NoError(t, err)
+	assert.Equal(t, `{"asset":{"name":"test.zip","other":true},"channel":"test","extra":{"a":"<&>","b":1},"platform":"linux","version":"1.0.1"}`, string(data))
+}
+
+type testManifestUI struct {
+	*testUpdateUI
+	manifestErr error
+	verified    *Update
+}
+
+func (u *testManifestUI) VerifyManifest(update Update) error {
+	u.verified = &update
+	return u.manifestErr
+}
+
+func TestUpdaterVerifyManifest(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+	require.NoError(t, err)
+	ctx := &testManifestUI{
+		testUpdateUI: newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true}),
+		manifestErr:  fmt.Errorf("Test manifest error"),
+	}
+	update, err := upr.Update(ctx)
+	assert.EqualError(t, err, "Update Error (verify): Invalid update manifest: Test manifest error")
+	assert.Nil(t, update)
+	require.NotNil(t, ctx.verified)
+	assert.Equal(t, "1.0.1", ctx.verified.Version)
+	// Nothing from the update was used
+	assert.Equal(t, "", upr.config.GetInstallID())
+	assert.False(t, ctx.successReported)
+
+	ctx.manifestErr = nil
+	update, err = upr.Update(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, update)
+	assert.True(t, ctx.successReported)
+}
+
+func TestUpdaterManifestTarget(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	update := testUpdate(testServer.URL)
+	update.Signature = "signed"
+	update.Platform = "other"
+	upr, err := newTestUpdaterWithServer(t, testServer, update, &testConfig{})
+	require.NoError(t, err)
+	ctx := &testManifestUI{
+		testUpdateUI: newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true}),
+	}
+	_, err = upr.Update(ctx)
+	assert.EqualError(t, err, fmt.Sprintf("Update Error (verify): Invalid update manifest: Update is for platform \"other\", not %q", runtime.GOOS))
+
+	update.Platform = runtime.GOOS
+	update.Channel = "test"
+	_, err = upr.Update(ctx)
+	assert.EqualError(t, err, `Update Error (verify): Invalid update manifest: Update is for channel "test", not ""`)
+
+	update.Channel = ""
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+}
@@ -0,0 +1,122 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// PolicyAction is how an update is applied
+type PolicyAction string
+
+const (
+	// PolicyActionAuto applies the update without prompting if the user turned
+	// on auto update, otherwise prompts. This is the default.
+	PolicyActionAuto PolicyAction = "auto"
+	// PolicyActionPrompt always prompts for the update
+	PolicyActionPrompt PolicyAction = "prompt"
+	// PolicyActionApply applies the update without prompting
+	PolicyActionApply PolicyAction = "apply"
+)
+
+// PolicyRule is the policy for a type of update
+type PolicyRule struct {
+	// Action is how the update is applied. If empty, PolicyActionAuto.
+	Action PolicyAction `json:"action,omitempty"`
+	// ApplyAfterHours, if set, is how many hours after an update is published
+	// that it is applied without prompting, even if the user snoozed it
+	ApplyAfterHours int `json:"applyAfterHours,omitempty"`
+}
+
+// Policy is how each type of update is applied. The zero value applies all
+// updates automatically if the user turned on auto update, and prompts
+// otherwise.
+type Policy struct {
+	Normal   PolicyRule `json:"normal"`
+	BugFix   PolicyRule `json:"bugfix"`
+	Critical PolicyRule `json:"critical"`
+}
+
+// LoadPolicy loads a policy from a JSON file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("Invalid policy: %s", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Validate returns an error if the policy is invalid
+func (p Policy) Validate() error {
+	for _, rule := range []PolicyRule{p.Normal, p.BugFix, p.Critical} {
+		switch rule.Action {
+		case "", PolicyActionAuto, PolicyActionPrompt, PolicyActionApply:
+		default:
+			return fmt.Errorf("Invalid policy action: %q", rule.Action)
+		}
+		if rule.ApplyAfterHours < 0 {
+			return fmt.Errorf("Invalid policy applyAfterHours: %d", rule.ApplyAfterHours)
+		}
+	}
+	return nil
+}
+
+// Rule returns the rule for an update type. An unknown type uses the rule
+// for normal updates.
+func (p Policy) Rule(updateType UpdateType) PolicyRule {
+	switch updateType {
+	case UpdateTypeBugFix:
+		return p.BugFix
+	case UpdateTypeCritical:
+		return p.Critical
+	default:
+		return p.Normal
+	}
+}
+
+// IsOverdue returns true if the update was published long enough ago that it
+// should be applied without prompting (even if snoozed)
+func (p Policy) IsOverdue(update Update, now time.Time) bool {
+	rule := p.Rule(update.Type)
+	if rule.ApplyAfterHours == 0 || update.PublishedAt == 0 {
+		return false
+	}
+	publishedAt := time.Unix(0, update.PublishedAt*int64(time.Millisecond))
+	return now.Sub(publishedAt) >= time.Duration(rule.ApplyAfterHours)*time.Hour
+}
+
+// Decide returns whether to prompt for the update (PolicyActionPrompt) or
+// apply it without prompting (PolicyActionApply). Auto is whether the user
+// turned on auto update.
+func (p Policy) Decide(update Update, auto bool, now time.Time) PolicyAction {
+	if p.IsOverdue(update, now) {
+		return PolicyActionApply
+	}
+	switch p.Rule(update.Type).Action {
+	case PolicyActionPrompt:
+		return PolicyActionPrompt
+	case PolicyActionApply:
+		return PolicyActionApply
+	default:
+		if auto {
+			return PolicyActionApply
+		}
+		return PolicyActionPrompt
+	}
+}
+
+// SetPolicy sets how each type of update is applied
+func (u *Updater) SetPolicy(policy Policy) {
+	u.policy = policy
+}
@@ -0,0 +1,131 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPolicy is the example policy: critical updates are applied 48 hours
+// after they are published, bug fixes are applied if auto update is on, and
+// normal updates always prompt
+var testPolicy = Policy{
+	Normal:   PolicyRule{Action: PolicyActionPrompt},
+	BugFix:   PolicyRule{Action: PolicyActionAuto},
+	Critical: PolicyRule{Action: PolicyActionAuto, ApplyAfterHours: 48},
+}
+
+func TestPolicyDecide(t *testing.T) {
+	now := time.Now()
+	published := func(ago time.Duration) int64 {
+		return now.Add(-ago).UnixNano() / int64(time.Millisecond)
+	}
+	cases := []struct {
+		policy   Policy
+		update   Update
+		auto     bool
+		expected PolicyAction
+	}{
+		// The zero policy applies if auto update is on
+		{Policy{}, Update{Type: UpdateTypeNormal}, true, PolicyActionApply},
+		{Policy{}, Update{Type: UpdateTypeNormal}, false, PolicyActionPrompt},
+		{Policy{}, Update{Type: UpdateTypeCritical, PublishedAt: published(time.Hour * 1000)}, false, PolicyActionPrompt},
+		{testPolicy, Update{Type: UpdateTypeNormal}, true, PolicyActionPrompt},
+		{testPolicy, Update{Type: UpdateTypeBugFix}, true, PolicyActionApply},
+		{testPolicy, Update{Type: UpdateTypeBugFix}, false, PolicyActionPrompt},
+		{testPolicy, Update{Type: UpdateTypeCritical, PublishedAt: published(time.Hour)}, false, PolicyActionPrompt},
+		{testPolicy, Update{Type: UpdateTypeCritical, PublishedAt: published(49 * time.Hour)}, false, PolicyActionApply},
+		// Unknown publish time
+		{testPolicy, Update{Type: UpdateTypeCritical}, false, PolicyActionPrompt},
+		// Unknown type is treated as normal
+		{testPolicy, Update{Type: UpdateType(9)}, true, PolicyActionPrompt},
+		{Policy{Normal: PolicyRule{Action: PolicyActionApply}}, Update{Type: UpdateTypeNormal}, false, PolicyActionApply},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, c.policy.Decide(c.update, c.auto, now), "%#v %#v auto=%t", c.policy, c.update, c.auto)
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir, err := util.MakeTempDir("TestLoadPolicy.", 0700)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(dir)
+	path := filepath.Join(dir, "policy.json")
+
+	err = os.WriteFile(path, []byte(`{
+		"normal": {"action": "prompt"},
+		"bugfix": {"action": "auto"},
+		"critical": {"action": "auto", "applyAfterHours": 48}
+	}`), 0600)
+	require.NoError(t, err)
+	policy, err := LoadPolicy(path)
+	require.NoError(t, err)
+	assert.Equal(t, testPolicy, *policy)
+
+	err = os.WriteFile(path, []byte(`{"normal": {"action": "never"}}`), 0600)
+	require.NoError(t, err)
+	_, err = LoadPolicy(path)
+	assert.EqualError(t, err, `Invalid policy action: "never"`)
+
+	err = os.WriteFile(path, []byte(`{"critical": {"applyAfterHours": -1}}`), 0600)
+	require.NoError(t, err)
+	_, err = LoadPolicy(path)
+	assert.Error(t, err)
+
+	err = os.WriteFile(path, []byte(`invalid`), 0600)
+	require.NoError(t, err)
+	_, err = LoadPolicy(path)
+	assert.Error(t, err)
+
+	_, err = LoadPolicy(filepath.Join(dir, "missing.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUpdaterPolicyPrompt(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	// Auto update is on, but the policy prompts for normal updates
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{auto: true, autoSet: true})
+	require.NoError(t, err)
+	upr.SetPolicy(testPolicy)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true})
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, UpdateActionApply, ctx.actionReported)
+}
+
+func TestUpdaterPolicyOverdue(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	critical := testUpdate(testServer.URL)
+	critical.Type = UpdateTypeCritical
+	critical.PublishedAt = time.Now().Add(-72*time.Hour).UnixNano() / int64(time.Millisecond)
+	// Auto update is off, and the user snoozed the update
+	cfg := &testConfig{snoozeVersion: "1.0.1", snoozeUntil: time.Now().Add(time.Hour)}
+	upr, err := newTestUpdaterWithServer(t, testServer, critical, cfg)
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionSnooze})
+
+	// Without the policy, the update stays snoozed
+	update, err := upr.Update(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, update)
+
+	// The update is overdue, so it's applied without prompting
+	upr.SetPolicy(testPolicy)
+	update, err = upr.Update(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, update)
+	assert.Equal(t, UpdateActionAuto, ctx.actionReported)
+	assert.True(t, ctx.successReported)
+}
@@ -3,6 +3,8 @@
 
 package updater
 
+import "context"
+
 // Asset describes a downloadable file
 type Asset struct {
 	Name      string `json:"name"`
@@ -10,6 +12,45 @@ type Asset struct {
 	Digest    string `json:"digest"`
 	Signature string `json:"signature"`
 	LocalPath string `json:"localPath"`
+	// Signatures are more signatures of the asset, for when signatures from
+	// several signers are required
+	Signatures []string `json:"signatures,omitempty"`
+}
+
+// AllSignatures returns Signature (if set) and Signatures
+func (a Asset) AllSignatures() []string {
+	var signatures []string
+	if a.Signature != "" {
+		signatures = append(signatures, a.Signature)
+	}
+	for _, signature := range a.Signatures {
+		if signature != "" && signature != a.Signature {
+			signatures = append(signatures, signature)
+		}
+	}
+	return signatures
+}
+
+// Delta describes a patch which transforms an installed base (file or archive)
+// into the full Asset of an update
+type Delta struct {
+	// URL is where to download the patch
+	URL string `json:"url"`
+	// Digest is the digest of the patch
+	Digest string `json:"digest"`
+	// BaseVersion is the version the patch applies to
+	BaseVersion string `json:"baseVersion"`
+	// BaseDigest is the digest of the base the patch applies to
+	BaseDigest string `json:"baseDigest"`
+}
+
+// Rollout describes a staged rollout of an update, to a percentage of installs
+type Rollout struct {
+	// Percentage is the percentage (0-100) of installs that get the update
+	Percentage float64 `json:"percentage"`
+	// Salt is hashed with the install ID to pick the installs, so each
+	// rollout can pick different installs
+	Salt string `json:"salt"`
 }
 
 // UpdateType is the update type.
@@ -42,7 +83,22 @@ type Update struct {
 	PublishedAt int64      `json:"publishedAt"`
 	Props       []Property `codec:"props" json:"props,omitempty"`
 	Asset       *Asset     `json:"asset,omitempty"`
+	Delta       *Delta     `json:"delta,omitempty"`
+	Rollout     *Rollout   `json:"rollout,omitempty"`
 	NeedUpdate  bool       `json:"needUpdate"`
+	// ExpiresAt is when the update response expires (in milliseconds since
+	// epoch), if set, so an old response can't be replayed
+	ExpiresAt int64 `json:"expiresAt,omitempty"`
+	// Channel and Platform are what the update is for. A signed update must
+	// be for our channel and platform, see checkManifestTarget.
+	Channel  string `json:"channel,omitempty"`
+	Platform string `json:"platform,omitempty"`
+	// Signature is a (saltpack) signature of the manifest, see ManifestJSON
+	Signature string `json:"signature,omitempty"`
+
+	// raw is the JSON the update was decoded from, if it was, see
+	// ManifestJSON
+	raw []byte
 }
 
 func (u Update) missingAsset() bool {
@@ -120,3 +176,12 @@ type UpdateUI interface {
 	// UpdatePrompt prompts for an update
 	UpdatePrompt(Update, UpdateOptions, UpdatePromptOptions) (*UpdatePromptResponse, error)
 }
+
+// ContextUpdateUI is an UpdateUI whose prompt can be canceled. If the UI
+// implements it, UpdatePromptContext is used instead of UpdatePrompt.
+type ContextUpdateUI interface {
+	UpdateUI
+	// UpdatePromptContext prompts for an update, and stops if the context is
+	// done
+	UpdatePromptContext(goCtx context.Context, update Update, options UpdateOptions, promptOptions UpdatePromptOptions) (*UpdatePromptResponse, error)
+}
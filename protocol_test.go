@@ -0,0 +1,17 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetAllSignatures(t *testing.T) {
+	assert.Nil(t, Asset{}.AllSignatures())
+	assert.Equal(t, []string{"a"}, Asset{Signature: "a"}.AllSignatures())
+	assert.Equal(t, []string{"b"}, Asset{Signatures: []string{"b"}}.AllSignatures())
+	assert.Equal(t, []string{"a", "b"}, Asset{Signature: "a", Signatures: []string{"a", "b", ""}}.AllSignatures())
+}
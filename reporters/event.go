@@ -0,0 +1,116 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package reporters
+
+import (
+	"time"
+
+	"github.com/keybase/go-updater"
+)
+
+// EventType is the type of update event
+type EventType string
+
+const (
+	// EventError is an update error
+	EventError EventType = "error"
+	// EventAction is the action chosen at the update prompt
+	EventAction EventType = "action"
+	// EventSuccess is an update that was applied
+	EventSuccess EventType = "success"
+)
+
+// Event is an update event, as reported by the sinks in this package
+type Event struct {
+	Time           time.Time `json:"time"`
+	Type           EventType `json:"type"`
+	InstallID      string    `json:"installId,omitempty"`
+	RequestID      string    `json:"requestId,omitempty"`
+	Version        string    `json:"version"`
+	UpdateVersion  string    `json:"updateVersion,omitempty"`
+	UpdaterVersion string    `json:"updaterVersion,omitempty"`
+	// ErrorType, ErrorSubtype and Error are for error events
+	ErrorType    string `json:"errorType,omitempty"`
+	ErrorSubtype string `json:"errorSubtype,omitempty"`
+	Error        string `json:"error,omitempty"`
+	// Action, AutoUpdate and SnoozeDuration (in seconds) are for action events
+	Action         string `json:"action,omitempty"`
+	AutoUpdate     bool   `json:"autoUpdate,omitempty"`
+	SnoozeDuration int    `json:"snoozeDuration,omitempty"`
+}
+
+func newEvent(eventType EventType, update *updater.Update, options updater.UpdateOptions) Event {
+	event := Event{
+		Time:           time.Now(),
+		Type:           eventType,
+		Version:        options.Version,
+		UpdaterVersion: options.UpdaterVersion,
+	}
+	if update != nil {
+		event.InstallID = update.InstallID
+		event.RequestID = update.RequestID
+		event.UpdateVersion = update.Version
+	}
+	return event
+}
+
+// ErrorEvent returns the event for an update error
+func ErrorEvent(err error, update *updater.Update, options updater.UpdateOptions) Event {
+	event := newEvent(EventError, update, options)
+	event.ErrorType = string(updater.UnknownError)
+	if uerr, ok := err.(updater.Error); ok {
+		event.ErrorType = uerr.TypeString()
+		event.ErrorSubtype = uerr.SubtypeString()
+	}
+	event.Error = err.Error()
+	return event
+}
+
+// ActionEvent returns the event for the action chosen at the update prompt
+func ActionEvent(response updater.UpdatePromptResponse, update *updater.Update, options updater.UpdateOptions) Event {
+	event := newEvent(EventAction, update, options)
+	event.Action = response.Action.String()
+	event.AutoUpdate = response.AutoUpdate
+	event.SnoozeDuration = response.SnoozeDuration
+	return event
+}
+
+// SuccessEvent returns the event for an update that was applied
+func SuccessEvent(update *updater.Update, options updater.UpdateOptions) Event {
+	return newEvent(EventSuccess, update, options)
+}
+
+// eventSink reports events, for sinks that treat all events the same
+type eventSink interface {
+	report(event Event) error
+	name() string
+}
+
+// sinkReporter is an updater.Reporter for an event sink. Errors reporting are
+// logged.
+type sinkReporter struct {
+	sink eventSink
+	log  Log
+}
+
+func (r sinkReporter) reportEvent(event Event) {
+	if err := r.sink.report(event); err != nil {
+		r.log.Warningf("Error reporting %s to %s: %s", event.Type, r.sink.name(), err)
+	}
+}
+
+// ReportError reports an update error
+func (r sinkReporter) ReportError(err error, update *updater.Update, options updater.UpdateOptions) {
+	r.reportEvent(ErrorEvent(err, update, options))
+}
+
+// ReportAction reports the action chosen at the update prompt
+func (r sinkReporter) ReportAction(response updater.UpdatePromptResponse, update *updater.Update, options updater.UpdateOptions) {
+	r.reportEvent(ActionEvent(response, update, options))
+}
+
+// ReportSuccess reports that an update was applied
+func (r sinkReporter) ReportSuccess(update *updater.Update, options updater.UpdateOptions) {
+	r.reportEvent(SuccessEvent(update, options))
+}
@@ -0,0 +1,46 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package reporters
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/keybase/go-updater"
+)
+
+// fileSink appends events to a JSON Lines file
+type fileSink struct {
+	path string
+	mtx  *sync.Mutex
+}
+
+// NewFileReporter returns a reporter that appends events to a file, as JSON
+// Lines (one event per line)
+func NewFileReporter(path string, log Log) updater.Reporter {
+	return sinkReporter{sink: fileSink{path: path, mtx: &sync.Mutex{}}, log: log}
+}
+
+func (s fileSink) name() string {
+	return s.path
+}
+
+func (s fileSink) report(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(line, '\n'))
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package reporters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/util"
+)
+
+// DefaultHTTPTimeout is how long we wait to post an event
+const DefaultHTTPTimeout = 30 * time.Second
+
+// httpSink posts events (as JSON) to a URL
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPReporter returns a reporter that posts events (as JSON) to a URL,
+// such as a self-hosted collector. If client is nil, a client with
+// DefaultHTTPTimeout is used.
+func NewHTTPReporter(url string, client *http.Client, log Log) updater.Reporter {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	return sinkReporter{sink: httpSink{url: url, client: client}, log: log}
+}
+
+func (s httpSink) name() string {
+	return s.url
+}
+
+func (s httpSink) report(event Event) error {
+	return PostEvent(s.url, s.client, event)
+}
+
+// PostEvent posts an event (as JSON) to a URL. Unlike the reporter from
+// NewHTTPReporter, it returns the error, so the caller can retry later. If
+// client is nil, a client with DefaultHTTPTimeout is used.
+func PostEvent(url string, client *http.Client, event Event) error {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	defer util.DiscardAndCloseBodyIgnoreError(resp)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Responded with %s", resp.Status)
+	}
+	return nil
+}
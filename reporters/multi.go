@@ -0,0 +1,44 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package reporters
+
+import (
+	"github.com/keybase/go-updater"
+)
+
+// multiReporter reports to each of its reporters, in order
+type multiReporter []updater.Reporter
+
+// NewMultiReporter returns a reporter that reports each event to all of
+// reporters, in order. Nil reporters are skipped.
+func NewMultiReporter(reporters ...updater.Reporter) updater.Reporter {
+	var multi multiReporter
+	for _, r := range reporters {
+		if r != nil {
+			multi = append(multi, r)
+		}
+	}
+	return multi
+}
+
+// ReportError reports an update error
+func (m multiReporter) ReportError(err error, update *updater.Update, options updater.UpdateOptions) {
+	for _, r := range m {
+		r.ReportError(err, update, options)
+	}
+}
+
+// ReportAction reports the action chosen at the update prompt
+func (m multiReporter) ReportAction(response updater.UpdatePromptResponse, update *updater.Update, options updater.UpdateOptions) {
+	for _, r := range m {
+		r.ReportAction(response, update, options)
+	}
+}
+
+// ReportSuccess reports that an update was applied
+func (m multiReporter) ReportSuccess(update *updater.Update, options updater.UpdateOptions) {
+	for _, r := range m {
+		r.ReportSuccess(update, options)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package reporters
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/keybase/go-logging"
+	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var log = &logging.Logger{Module: "test"}
+
+var testUpdate = &updater.Update{
+	Version:   "1.0.1",
+	InstallID: "deadbeef",
+	RequestID: "cafedead",
+}
+
+var testOptions = updater.UpdateOptions{
+	Version:        "1.0.0",
+	UpdaterVersion: "0.3.0",
+}
+
+func reportAll(r updater.Reporter) {
+	r.ReportError(fmt.Errorf("Test error"), testUpdate, testOptions)
+	r.ReportAction(updater.UpdatePromptResponse{Action: updater.UpdateActionSnooze, SnoozeDuration: 3600}, testUpdate, testOptions)
+	r.ReportSuccess(testUpdate, testOptions)
+}
+
+func TestEvents(t *testing.T) {
+	event := ErrorEvent(fmt.Errorf("Test error"), testUpdate, testOptions)
+	assert.Equal(t, EventError, event.Type)
+	assert.Equal(t, "unknown", event.ErrorType)
+	assert.Equal(t, "Test error", event.Error)
+	assert.Equal(t, "cafedead", event.RequestID)
+	assert.Equal(t, "1.0.1", event.UpdateVersion)
+	assert.Equal(t, "1.0.0", event.Version)
+
+	event = ActionEvent(updater.UpdatePromptResponse{Action: updater.UpdateActionApply, AutoUpdate: true}, nil, testOptions)
+	assert.Equal(t, EventAction, event.Type)
+	assert.Equal(t, "apply", event.Action)
+	assert.True(t, event.AutoUpdate)
+	assert.Equal(t, "", event.RequestID)
+
+	event = SuccessEvent(testUpdate, testOptions)
+	assert.Equal(t, EventSuccess, event.Type)
+	assert.Equal(t, "0.3.0", event.UpdaterVersion)
+}
+
+func TestHTTPReporter(t *testing.T) {
+	var events []Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var event Event
+		err := json.NewDecoder(r.Body).Decode(&event)
+		require.NoError(t, err)
+		events = append(events, event)
+	}))
+	defer server.Close()
+
+	reportAll(NewHTTPReporter(server.URL, nil, log))
+	require.Equal(t, 3, len(events))
+	assert.Equal(t, EventError, events[0].Type)
+	assert.Equal(t, "snooze", events[1].Action)
+	assert.Equal(t, 3600, events[1].SnoozeDuration)
+	assert.Equal(t, EventSuccess, events[2].Type)
+}
+
+func TestHTTPSinkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sink := httpSink{url: server.URL, client: http.DefaultClient}
+	err := sink.report(SuccessEvent(testUpdate, testOptions))
+	assert.EqualError(t, err, "Responded with 500 Internal Server Error")
+}
+
+func readEvents(t *testing.T, path string) []Event {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer util.Close(file)
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func testDir(t *testing.T) string {
+	dir, err := util.MakeTempDir("TestReporters.", 0700)
+	require.NoError(t, err)
+	t.Cleanup(func() { util.RemoveFileAtPath(dir) })
+	return dir
+}
+
+func TestFileReporter(t *testing.T) {
+	path := filepath.Join(testDir(t), "events.jsonl")
+	reportAll(NewFileReporter(path, log))
+
+	events := readEvents(t, path)
+	require.Equal(t, 3, len(events))
+	assert.Equal(t, EventError, events[0].Type)
+	assert.Equal(t, EventAction, events[1].Type)
+	assert.Equal(t, EventSuccess, events[2].Type)
+}
+
+func TestFileReporterError(t *testing.T) {
+	sink := fileSink{path: filepath.Join(testDir(t), "missing", "events.jsonl"), mtx: &sync.Mutex{}}
+	err := sink.report(SuccessEvent(testUpdate, testOptions))
+	assert.Error(t, err)
+}
+
+func TestMultiReporter(t *testing.T) {
+	dir := testDir(t)
+	path1, path2 := filepath.Join(dir, "events1.jsonl"), filepath.Join(dir, "events2.jsonl")
+	reportAll(NewMultiReporter(NewFileReporter(path1, log), nil, NewFileReporter(path2, log)))
+
+	assert.Equal(t, 3, len(readEvents(t, path1)))
+	assert.Equal(t, 3, len(readEvents(t, path2)))
+}
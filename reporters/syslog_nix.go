@@ -0,0 +1,45 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+//go:build !windows
+// +build !windows
+
+package reporters
+
+import (
+	"encoding/json"
+	"log/syslog"
+
+	"github.com/keybase/go-updater"
+)
+
+// syslogSink writes events (as JSON) to the system log. Errors are logged at
+// error priority, and other events at info priority.
+type syslogSink struct {
+	writer *syslog.Writer
+}
+
+// NewSyslogReporter returns a reporter that writes events (as JSON) to the
+// local system log, with tag
+func NewSyslogReporter(tag string, log Log) (updater.Reporter, error) {
+	writer, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	return sinkReporter{sink: syslogSink{writer: writer}, log: log}, nil
+}
+
+func (s syslogSink) name() string {
+	return "syslog"
+}
+
+func (s syslogSink) report(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.Type == EventError {
+		return s.writer.Err(string(data))
+	}
+	return s.writer.Info(string(data))
+}
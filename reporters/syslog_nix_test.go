@@ -0,0 +1,19 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+//go:build !windows
+// +build !windows
+
+package reporters
+
+import (
+	"testing"
+)
+
+func TestSyslogReporter(t *testing.T) {
+	reporter, err := NewSyslogReporter("keybase.updater.test", log)
+	if err != nil {
+		t.Skipf("No syslog: %s", err)
+	}
+	reportAll(reporter)
+}
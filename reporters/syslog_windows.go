@@ -0,0 +1,18 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+//go:build windows
+// +build windows
+
+package reporters
+
+import (
+	"fmt"
+
+	"github.com/keybase/go-updater"
+)
+
+// NewSyslogReporter isn't supported on Windows
+func NewSyslogReporter(tag string, log Log) (updater.Reporter, error) {
+	return nil, fmt.Errorf("Syslog isn't supported on Windows")
+}
@@ -0,0 +1,47 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// rolloutBuckets is how many buckets installs are divided into for a rollout,
+// so a rollout percentage can have 2 decimal places
+const rolloutBuckets = 10000
+
+// rolloutBucket returns a bucket in [0, rolloutBuckets) for an install, which
+// is the same every time for the install ID and salt
+func rolloutBucket(installID string, salt string) uint64 {
+	digest := sha256.Sum256([]byte(salt + ":" + installID))
+	return binary.BigEndian.Uint64(digest[:8]) % rolloutBuckets
+}
+
+// inRollout returns true if this install should get the update. If the update
+// isn't a staged rollout, is critical, or is forced, it always should.
+// Otherwise the install ID decides if we are in the rollout.
+func (u *Updater) inRollout(update Update, options UpdateOptions) bool {
+	rollout := update.Rollout
+	if rollout == nil || rollout.Percentage >= 100 {
+		return true
+	}
+	if update.Type == UpdateTypeCritical {
+		u.log.Infof("Ignoring rollout (%g%%) for critical update %s", rollout.Percentage, update.Version)
+		return true
+	}
+	if options.Force {
+		u.log.Warningf("Forcing update, ignoring rollout (%g%%) for %s", rollout.Percentage, update.Version)
+		return true
+	}
+	installID := u.config.GetInstallID()
+	if installID == "" {
+		u.log.Infof("No install ID, so not in rollout (%g%%) for %s", rollout.Percentage, update.Version)
+		return false
+	}
+	bucket := rolloutBucket(installID, rollout.Salt)
+	inRollout := float64(bucket) < rollout.Percentage*rolloutBuckets/100
+	u.log.Infof("Rollout (%g%%) for %s, in rollout: %t", rollout.Percentage, update.Version, inRollout)
+	return inRollout
+}
@@ -0,0 +1,117 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRolloutUpdate(uri string, percentage float64) *Update {
+	update := testUpdate(uri)
+	update.Rollout = &Rollout{Percentage: percentage, Salt: "testsalt"}
+	return update
+}
+
+func TestUpdaterRollout(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	// Not in a 0% rollout
+	upr, err := newTestUpdaterWithServer(t, testServer, testRolloutUpdate(testServer.URL, 0), &testConfig{})
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+	update, err := upr.Update(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, update)
+	assert.False(t, ctx.successReported)
+	// The install ID is still saved
+	assert.Equal(t, "deadbeef", upr.config.GetInstallID())
+	needUpdate, err := upr.NeedUpdate(ctx)
+	require.NoError(t, err)
+	assert.False(t, needUpdate)
+
+	// In a 100% rollout
+	upr, err = newTestUpdaterWithServer(t, testServer, testRolloutUpdate(testServer.URL, 100), &testConfig{})
+	require.NoError(t, err)
+	ctx = newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+	update, err = upr.Update(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, update)
+	assert.True(t, ctx.successReported)
+}
+
+func TestUpdaterRolloutCritical(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	critical := testRolloutUpdate(testServer.URL, 0)
+	critical.Type = UpdateTypeCritical
+	upr, err := newTestUpdaterWithServer(t, testServer, critical, &testConfig{})
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+	update, err := upr.Update(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, update)
+	assert.True(t, ctx.successReported)
+}
+
+func TestUpdaterRolloutForce(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	upr, err := newTestUpdaterWithServer(t, testServer, testRolloutUpdate(testServer.URL, 0), &testConfig{})
+	require.NoError(t, err)
+	options := newDefaultTestUpdateOptions()
+	options.Force = true
+	ctx := newTestContext(options, upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+	update, err := upr.Update(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, update)
+	assert.True(t, ctx.successReported)
+}
+
+func TestInRollout(t *testing.T) {
+	upr, err := newTestUpdater(t)
+	require.NoError(t, err)
+	cfg := upr.config.(*testConfig)
+	options := newDefaultTestUpdateOptions()
+	update := testRolloutUpdate("", 10)
+
+	// No install ID, not in rollout
+	assert.False(t, upr.inRollout(*update, options))
+
+	// The same install is always in (or out of) the rollout
+	inRollout := 0
+	for i := 0; i < 1000; i++ {
+		cfg.installID = fmt.Sprintf("install%d", i)
+		in := upr.inRollout(*update, options)
+		assert.Equal(t, in, upr.inRollout(*update, options))
+		if in {
+			inRollout++
+			// Installs in the rollout stay in as it increases
+			update.Rollout.Percentage = 50
+			assert.True(t, upr.inRollout(*update, options))
+			update.Rollout.Percentage = 10
+		}
+	}
+	assert.InDelta(t, 100, inRollout, 40)
+}
+
+func TestRolloutBucket(t *testing.T) {
+	assert.Equal(t, rolloutBucket("deadbeef", "salt"), rolloutBucket("deadbeef", "salt"))
+	assert.True(t, rolloutBucket("deadbeef", "salt") < rolloutBuckets)
+	// A different salt picks different installs
+	same := 0
+	for i := 0; i < 100; i++ {
+		installID := fmt.Sprintf("install%d", i)
+		if rolloutBucket(installID, "salt1") == rolloutBucket(installID, "salt2") {
+			same++
+		}
+	}
+	assert.True(t, same < 5)
+}
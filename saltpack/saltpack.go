@@ -21,14 +21,37 @@ type Log interface {
 	Infof(s string, args ...interface{})
 }
 
+// Signers decides which signers (by KID) are valid
+type Signers interface {
+	// CheckSigner returns an error if kid isn't a valid signer
+	CheckSigner(kid string) error
+}
+
+// KIDs is a set of valid signer KIDs
+type KIDs map[string]bool
+
+// CheckSigner returns an error if kid isn't in the set
+func (k KIDs) CheckSigner(kid string) error {
+	if !k[kid] {
+		return fmt.Errorf("unknown signer KID: %s", kid)
+	}
+	return nil
+}
+
 // VerifyDetachedFileAtPath verifies a file
 func VerifyDetachedFileAtPath(path string, signature string, validKIDs map[string]bool, log Log) error {
+	return VerifyDetachedFileAtPathWithSigners(path, signature, KIDs(validKIDs), log)
+}
+
+// VerifyDetachedFileAtPathWithSigners verifies a file, signed by one of the
+// signers (like a TrustStore)
+func VerifyDetachedFileAtPathWithSigners(path string, signature string, signers Signers, log Log) error {
 	file, err := os.Open(path)
 	defer util.Close(file)
 	if err != nil {
 		return err
 	}
-	err = VerifyDetached(file, signature, validKIDs, log)
+	err = VerifyDetachedWithSigners(file, signature, signers, log)
 	if err != nil {
 		return fmt.Errorf("error verifying signature: %s", err)
 	}
@@ -44,6 +67,10 @@ func SigningPublicKeyToKeybaseKID(k sp.SigningPublicKey) (ret keybase1.KID) {
 }
 
 func checkSender(key sp.SigningPublicKey, validKIDs map[string]bool, log Log) error {
+	return checkSigner(key, KIDs(validKIDs), log)
+}
+
+func checkSigner(key sp.SigningPublicKey, signers Signers, log Log) error {
 	if key == nil {
 		return fmt.Errorf("no key")
 	}
@@ -52,20 +79,29 @@ func checkSender(key sp.SigningPublicKey, validKIDs map[string]bool, log Log) er
 		return fmt.Errorf("no KID for key")
 	}
 	log.Infof("Signed by %s", kid)
-	if !validKIDs[kid.String()] {
+	if signers == nil {
 		return fmt.Errorf("unknown signer KID: %s", kid)
 	}
+	if err := signers.CheckSigner(kid.String()); err != nil {
+		return err
+	}
 	log.Debugf("Valid KID: %s", kid)
 	return nil
 }
 
 // VerifyDetached verifies a message signature
 func VerifyDetached(reader io.Reader, signature string, validKIDs map[string]bool, log Log) error {
+	return VerifyDetachedWithSigners(reader, signature, KIDs(validKIDs), log)
+}
+
+// VerifyDetachedWithSigners verifies a message signature, by one of the
+// signers (like a TrustStore)
+func VerifyDetachedWithSigners(reader io.Reader, signature string, signers Signers, log Log) error {
 	if reader == nil {
 		return fmt.Errorf("no reader")
 	}
 	check := func(key sp.SigningPublicKey) error {
-		return checkSender(key, validKIDs, log)
+		return checkSigner(key, signers, log)
 	}
 	return VerifyDetachedCheckSender(reader, []byte(signature), check)
 }
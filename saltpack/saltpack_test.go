@@ -121,3 +121,10 @@ func TestVerifyNoFile(t *testing.T) {
 	assert.Error(t, err)
 	require.True(t, strings.HasPrefix(err.Error(), "open /invalid: "))
 }
+
+func TestVerifyDetachedFileAtPathThreshold(t *testing.T) {
+	err := VerifyDetachedFileAtPathThreshold(testZipPath, []string{testZipSignature}, 1, KIDs(validCodeSigningKIDs), testLog)
+	assert.NoError(t, err)
+	err = VerifyDetachedFileAtPathThreshold(testZipPath, []string{testZipSignature}, 2, KIDs(validCodeSigningKIDs), testLog)
+	assert.EqualError(t, err, "error verifying signature: signed by 1 valid signers, needs 2 (valid: [0120d7539e27e83a9c8caf8701199c6985c0a96801ff7cb69456e9b3a8a8446c66080a], missing: [])")
+}
@@ -0,0 +1,50 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+// Package saltpacktest signs with new keys, for tests which need signatures
+// (and trust stores) by keys they choose to trust. It isn't for use outside of
+// tests.
+package saltpacktest
+
+import (
+	"encoding/json"
+
+	"github.com/keybase/go-updater/saltpack"
+	sp "github.com/keybase/saltpack"
+	"github.com/keybase/saltpack/basic"
+)
+
+// Signer signs with a new (in memory) signing key
+type Signer struct {
+	// KID is the KID of the signing key
+	KID string
+	key *basic.SigningSecretKey
+}
+
+// NewSigner returns a signer with a new signing key
+func NewSigner() (*Signer, error) {
+	key, err := basic.NewKeyring().GenerateSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{KID: saltpack.SigningPublicKeyToKeybaseKID(key.GetPublicKey()).String(), key: key}, nil
+}
+
+// Sign returns a (armored) saltpack detached signature of message
+func (s Signer) Sign(message []byte) (string, error) {
+	return sp.SignDetachedArmor62(sp.CurrentVersion(), message, s.key, "KEYBASE")
+}
+
+// SignTrustStore returns a trust store signed by s, see
+// saltpack.ParseTrustStore
+func (s Signer) SignTrustStore(store saltpack.TrustStore) ([]byte, error) {
+	data, err := json.Marshal(store)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := s.Sign(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(saltpack.SignedTrustStore{Store: data, Signature: signature})
+}
@@ -0,0 +1,134 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package saltpack
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/keybase/go-updater/util"
+	sp "github.com/keybase/saltpack"
+)
+
+// SignerLister is an optional interface for Signers, to list the valid
+// signers, so a ThresholdError can say which are missing
+type SignerLister interface {
+	ListSigners() []string
+}
+
+// ListSigners returns the KIDs in the set
+func (k KIDs) ListSigners() []string {
+	var kids []string
+	for kid, valid := range k {
+		if valid {
+			kids = append(kids, kid)
+		}
+	}
+	sort.Strings(kids)
+	return kids
+}
+
+// ListSigners returns the KIDs in the trust store that are valid now
+func (s TrustStore) ListSigners() []string {
+	now := time.Now()
+	var kids []string
+	for _, key := range s.Keys {
+		if s.CheckSignerAt(key.KID, now) == nil {
+			kids = append(kids, key.KID)
+		}
+	}
+	sort.Strings(kids)
+	return kids
+}
+
+// ThresholdError is returned if there aren't valid signatures from enough
+// (distinct) signers
+type ThresholdError struct {
+	Threshold int
+	// Valid are the KIDs with a valid signature
+	Valid []string
+	// Missing are the valid signers (if known) without a signature
+	Missing []string
+	// Invalid are why the other signatures didn't verify
+	Invalid []string
+}
+
+func (e ThresholdError) Error() string {
+	msg := fmt.Sprintf("signed by %d valid signers, needs %d (valid: [%s], missing: [%s])",
+		len(e.Valid), e.Threshold, strings.Join(e.Valid, ", "), strings.Join(e.Missing, ", "))
+	if len(e.Invalid) > 0 {
+		msg += fmt.Sprintf(", invalid: [%s]", strings.Join(e.Invalid, "; "))
+	}
+	return msg
+}
+
+// VerifyDetachedFileAtPathThreshold verifies signatures of a file, which must
+// be from at least threshold distinct valid signers
+func VerifyDetachedFileAtPathThreshold(path string, signatures []string, threshold int, signers Signers, log Log) error {
+	file, err := os.Open(path)
+	defer util.Close(file)
+	if err != nil {
+		return err
+	}
+	err = VerifyDetachedThreshold(file, signatures, threshold, signers, log)
+	if err != nil {
+		return fmt.Errorf("error verifying signature: %s", err)
+	}
+	return nil
+}
+
+// VerifyDetachedThreshold verifies signatures of a message, which must be from
+// at least threshold distinct valid signers. The message is read once for
+// each signature.
+func VerifyDetachedThreshold(message io.ReadSeeker, signatures []string, threshold int, signers Signers, log Log) error {
+	if message == nil {
+		return fmt.Errorf("no reader")
+	}
+	if threshold < 1 {
+		threshold = 1
+	}
+	valid := map[string]bool{}
+	var validKIDs, invalid []string
+	for i, signature := range signatures {
+		if _, err := message.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		var kid string
+		check := func(key sp.SigningPublicKey) error {
+			if key != nil {
+				kid = SigningPublicKeyToKeybaseKID(key).String()
+			}
+			return checkSigner(key, signers, log)
+		}
+		if err := VerifyDetachedCheckSender(message, []byte(signature), check); err != nil {
+			// If we have the KID, the signer isn't valid (and the error says who)
+			if kid == "" {
+				err = fmt.Errorf("signature %d: %s", i+1, err)
+			}
+			invalid = append(invalid, err.Error())
+			continue
+		}
+		if !valid[kid] {
+			valid[kid] = true
+			validKIDs = append(validKIDs, kid)
+		}
+	}
+	if len(validKIDs) >= threshold {
+		return nil
+	}
+
+	var missing []string
+	if lister, ok := signers.(SignerLister); ok {
+		for _, kid := range lister.ListSigners() {
+			if !valid[kid] {
+				missing = append(missing, kid)
+			}
+		}
+	}
+	return ThresholdError{Threshold: threshold, Valid: validKIDs, Missing: missing, Invalid: invalid}
+}
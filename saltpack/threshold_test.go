@@ -0,0 +1,60 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package saltpack_test
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/keybase/go-updater/saltpack"
+	"github.com/keybase/go-updater/saltpack/saltpacktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyDetachedThreshold(t *testing.T) {
+	var keys []*saltpacktest.Signer
+	for i := 0; i < 4; i++ {
+		key, err := saltpacktest.NewSigner()
+		require.NoError(t, err)
+		keys = append(keys, key)
+	}
+	a, b, c, other := keys[0], keys[1], keys[2], keys[3]
+	signers := saltpack.KIDs{a.KID: true, b.KID: true, c.KID: true}
+	message := []byte("This is a test message\n")
+	reader := bytes.NewReader(message)
+	sign := func(s *saltpacktest.Signer, message []byte) string {
+		signature, err := s.Sign(message)
+		require.NoError(t, err)
+		return signature
+	}
+
+	err := saltpack.VerifyDetachedThreshold(reader, []string{sign(a, message), sign(b, message)}, 2, signers, testLog)
+	assert.NoError(t, err)
+	// The single signature (threshold of 1) still works
+	err = saltpack.VerifyDetachedThreshold(reader, []string{sign(a, message)}, 0, signers, testLog)
+	assert.NoError(t, err)
+
+	// Two signatures from the same signer count once, and an unknown signer
+	// doesn't count
+	err = saltpack.VerifyDetachedThreshold(reader, []string{sign(a, message), sign(a, message), sign(other, message)}, 2, signers, testLog)
+	require.Error(t, err)
+	thresholdErr, ok := err.(saltpack.ThresholdError)
+	require.True(t, ok)
+	assert.Equal(t, []string{a.KID}, thresholdErr.Valid)
+	missing := []string{b.KID, c.KID}
+	sort.Strings(missing)
+	assert.Equal(t, missing, thresholdErr.Missing)
+	assert.Equal(t, []string{"unknown signer KID: " + other.KID}, thresholdErr.Invalid)
+	assert.Contains(t, err.Error(), "signed by 1 valid signers, needs 2 (valid: ["+a.KID+"], missing: [")
+
+	// A signature of another message
+	err = saltpack.VerifyDetachedThreshold(reader, []string{sign(a, message), sign(b, []byte("other"))}, 2, signers, testLog)
+	require.Error(t, err)
+	assert.Equal(t, []string{"signature 2: invalid signature"}, err.(saltpack.ThresholdError).Invalid)
+
+	err = saltpack.VerifyDetachedThreshold(nil, nil, 1, signers, testLog)
+	assert.EqualError(t, err, "no reader")
+}
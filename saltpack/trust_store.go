@@ -0,0 +1,90 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package saltpack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TrustedKey is a code signing key in a trust store
+type TrustedKey struct {
+	KID string `json:"kid"`
+	// Name describes the key (like who has it, and on what device)
+	Name string `json:"name,omitempty"`
+	// NotBefore and NotAfter are when the key is valid, if set
+	NotBefore *time.Time `json:"notBefore,omitempty"`
+	NotAfter  *time.Time `json:"notAfter,omitempty"`
+	// Revoked is whether the key was revoked
+	Revoked bool `json:"revoked,omitempty"`
+}
+
+// TrustStore is a list of code signing keys. It is signed by a root key, so
+// keys can be rotated (or revoked) without a new build.
+type TrustStore struct {
+	// Version only goes up, so an older trust store can't replace a newer one
+	Version int64        `json:"version"`
+	Keys    []TrustedKey `json:"keys"`
+}
+
+// SignedTrustStore is a trust store, and a saltpack detached signature of the
+// store (as compact JSON)
+type SignedTrustStore struct {
+	Store     json.RawMessage `json:"store"`
+	Signature string          `json:"signature"`
+}
+
+// ParseTrustStore parses a signed trust store, which must be signed by one of
+// the roots
+func ParseTrustStore(data []byte, roots Signers, log Log) (*TrustStore, error) {
+	var signed SignedTrustStore
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, fmt.Errorf("invalid trust store: %s", err)
+	}
+	var store bytes.Buffer
+	if err := json.Compact(&store, signed.Store); err != nil {
+		return nil, fmt.Errorf("invalid trust store: %s", err)
+	}
+	if err := VerifyDetachedWithSigners(bytes.NewReader(store.Bytes()), signed.Signature, roots, log); err != nil {
+		return nil, fmt.Errorf("error verifying trust store: %s", err)
+	}
+	var trustStore TrustStore
+	if err := json.Unmarshal(store.Bytes(), &trustStore); err != nil {
+		return nil, fmt.Errorf("invalid trust store: %s", err)
+	}
+	return &trustStore, nil
+}
+
+// CheckSigner returns an error if kid isn't in the trust store, was revoked,
+// or isn't valid now
+func (s TrustStore) CheckSigner(kid string) error {
+	return s.CheckSignerAt(kid, time.Now())
+}
+
+// CheckSignerAt returns an error if kid isn't in the trust store, was revoked,
+// or isn't valid at a time
+func (s TrustStore) CheckSignerAt(kid string, at time.Time) error {
+	var found *TrustedKey
+	for i, key := range s.Keys {
+		if key.KID != kid {
+			continue
+		}
+		if key.Revoked {
+			return fmt.Errorf("revoked signer KID: %s", kid)
+		}
+		found = &s.Keys[i]
+	}
+	if found == nil {
+		return fmt.Errorf("unknown signer KID: %s", kid)
+	}
+	if found.NotBefore != nil && at.Before(*found.NotBefore) {
+		return fmt.Errorf("signer KID %s isn't valid until %s", kid, found.NotBefore.Format(time.RFC3339))
+	}
+	if found.NotAfter != nil && !at.Before(*found.NotAfter) {
+		return fmt.Errorf("signer KID %s expired at %s", kid, found.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
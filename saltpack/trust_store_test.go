@@ -0,0 +1,84 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package saltpack_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/keybase/go-logging"
+	"github.com/keybase/go-updater/saltpack"
+	"github.com/keybase/go-updater/saltpack/saltpacktest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testLog = &logging.Logger{Module: "test"}
+
+func TestTrustStore(t *testing.T) {
+	root, err := saltpacktest.NewSigner()
+	require.NoError(t, err)
+	signer, err := saltpacktest.NewSigner()
+	require.NoError(t, err)
+	revoked, err := saltpacktest.NewSigner()
+	require.NoError(t, err)
+	now := time.Now().UTC().Truncate(time.Second)
+	yesterday, tomorrow := now.Add(-24*time.Hour), now.Add(24*time.Hour)
+	data, err := root.SignTrustStore(saltpack.TrustStore{
+		Version: 2,
+		Keys: []saltpack.TrustedKey{
+			{KID: signer.KID, Name: "signer", NotBefore: &yesterday, NotAfter: &tomorrow},
+			{KID: revoked.KID, Name: "revoked", Revoked: true},
+		},
+	})
+	require.NoError(t, err)
+
+	store, err := saltpack.ParseTrustStore(data, saltpack.KIDs{root.KID: true}, testLog)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), store.Version)
+
+	message := []byte("This is a test message\n")
+	signerSignature, err := signer.Sign(message)
+	require.NoError(t, err)
+	revokedSignature, err := revoked.Sign(message)
+	require.NoError(t, err)
+	rootSignature, err := root.Sign(message)
+	require.NoError(t, err)
+	err = saltpack.VerifyDetachedWithSigners(bytes.NewReader(message), signerSignature, store, testLog)
+	assert.NoError(t, err)
+	err = saltpack.VerifyDetachedWithSigners(bytes.NewReader(message), revokedSignature, store, testLog)
+	assert.EqualError(t, err, "revoked signer KID: "+revoked.KID)
+	err = saltpack.VerifyDetachedWithSigners(bytes.NewReader(message), rootSignature, store, testLog)
+	assert.EqualError(t, err, "unknown signer KID: "+root.KID)
+
+	err = store.CheckSignerAt(signer.KID, tomorrow)
+	assert.EqualError(t, err, "signer KID "+signer.KID+" expired at "+tomorrow.Format(time.RFC3339))
+	err = store.CheckSignerAt(signer.KID, yesterday.Add(-time.Second))
+	assert.EqualError(t, err, "signer KID "+signer.KID+" isn't valid until "+yesterday.Format(time.RFC3339))
+}
+
+func TestTrustStoreInvalid(t *testing.T) {
+	root, err := saltpacktest.NewSigner()
+	require.NoError(t, err)
+	data, err := root.SignTrustStore(saltpack.TrustStore{Version: 1})
+	require.NoError(t, err)
+
+	// Not signed by a root key
+	_, err = saltpack.ParseTrustStore(data, saltpack.KIDs{}, testLog)
+	assert.EqualError(t, err, "error verifying trust store: unknown signer KID: "+root.KID)
+
+	// Changed after it was signed
+	var signed saltpack.SignedTrustStore
+	require.NoError(t, json.Unmarshal(data, &signed))
+	signed.Store = json.RawMessage(`{"version":1,"keys":[{"kid":"0120deadbeef"}]}`)
+	tampered, err := json.Marshal(signed)
+	require.NoError(t, err)
+	_, err = saltpack.ParseTrustStore(tampered, saltpack.KIDs{root.KID: true}, testLog)
+	assert.EqualError(t, err, "error verifying trust store: invalid signature")
+
+	_, err = saltpack.ParseTrustStore([]byte("invalid"), saltpack.KIDs{root.KID: true}, testLog)
+	require.Error(t, err)
+}
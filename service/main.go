@@ -4,14 +4,21 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/kardianos/osext"
 	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/control"
 	"github.com/keybase/go-updater/keybase"
 	"github.com/keybase/go-updater/util"
 )
@@ -22,12 +29,18 @@ type flags struct {
 	appName       string
 	pathToKeybase string
 	command       string
+	args          []string
+	lockTimeout   time.Duration
+	endpoints     keybase.Endpoints
+	caCerts       string
+	force         bool
 }
 
 func main() {
 	f, args := loadFlags()
 	if len(args) > 0 {
 		f.command = args[0]
+		f.args = args[1:]
 	}
 	if err := run(f); err != nil {
 		os.Exit(1)
@@ -40,11 +53,33 @@ func loadFlags() (flags, []string) {
 	flag.BoolVar(&f.logToFile, "log-to-file", false, "Log to file")
 	flag.StringVar(&f.pathToKeybase, "path-to-keybase", "", "Path to keybase executable")
 	flag.StringVar(&f.appName, "app-name", defaultAppName(), "App name")
+	flag.DurationVar(&f.lockTimeout, "lock-timeout", 0, "How long to wait for another updater to finish (0 to fail immediately)")
+	flag.StringVar(&f.endpoints.Update, "update-url", "", "URL to check for updates")
+	flag.StringVar(&f.endpoints.Action, "action-url", "", "URL to report prompt actions")
+	flag.StringVar(&f.endpoints.Success, "success-url", "", "URL to report successful updates")
+	flag.StringVar(&f.endpoints.Error, "error-url", "", "URL to report update errors")
+	flag.StringVar(&f.caCerts, "ca-certs", "", "PEM files with CA certificates to trust (separated like PATH)")
+	flag.BoolVar(&f.force, "force", false, "Apply an update even if it isn't newer, or we aren't in its rollout")
 	flag.Parse()
 	args := flag.Args()
 	return f, args
 }
 
+// contextOptions returns the options for the updater context, from the flags
+func (f flags) contextOptions() keybase.ContextOptions {
+	var caCertPaths []string
+	for _, path := range filepath.SplitList(f.caCerts) {
+		if path != "" {
+			caCertPaths = append(caCertPaths, path)
+		}
+	}
+	return keybase.ContextOptions{Endpoints: f.endpoints, CACertPaths: caCertPaths, Force: f.force}
+}
+
+func newUpdaterContext(f flags, ulog logger, mode keybase.UpdaterMode) (updater.Context, *updater.Updater) {
+	return keybase.NewUpdaterContextWithOptions(f.appName, f.pathToKeybase, ulog, mode, f.contextOptions())
+}
+
 func defaultAppName() string {
 	if runtime.GOOS == "linux" {
 		return "keybase"
@@ -84,9 +119,18 @@ func run(f flags) error {
 		ulog.Warning("Missing -path-to-keybase")
 	}
 
+	if err := f.contextOptions().Validate(); err != nil {
+		ulog.Error(err)
+		return err
+	}
+
+	// Cancel a check, download or apply in progress if we're asked to stop
+	goCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	switch f.command {
 	case "need-update":
-		ctx, updater := keybase.NewUpdaterContext(f.appName, f.pathToKeybase, ulog, keybase.Check)
+		ctx, updater := newUpdaterContext(f, ulog, keybase.Check)
 		needUpdate, err := updater.NeedUpdate(ctx)
 		if err != nil {
 			ulog.Error(err)
@@ -97,13 +141,14 @@ func run(f flags) error {
 		// https: //github.com/keybase/client/blob/master/go/client/cmd_update.go
 		fmt.Println(needUpdate)
 	case "check":
-		if err := updateCheckFromFlags(f, ulog); err != nil {
+		if err := updateCheckFromFlags(goCtx, f, ulog); err != nil {
 			ulog.Error(err)
 			return err
 		}
 	case "download-latest":
-		ctx, updater := keybase.NewUpdaterContext(f.appName, f.pathToKeybase, ulog, keybase.CheckPassive)
-		updateAvailable, _, err := updater.CheckAndDownload(ctx)
+		ctx, updater := newUpdaterContext(f, ulog, keybase.CheckPassive)
+		updater.SetLockTimeout(f.lockTimeout)
+		updateAvailable, _, err := updater.CheckAndDownloadContext(goCtx, ctx)
 		if err != nil {
 			ulog.Error(err)
 			return err
@@ -113,19 +158,39 @@ func run(f flags) error {
 		// https: //github.com/keybase/client/blob/master/go/client/cmd_update.go
 		fmt.Println(updateAvailable)
 	case "apply-downloaded":
-		ctx, updater := keybase.NewUpdaterContext(f.appName, f.pathToKeybase, ulog, keybase.Check)
-		applied, err := updater.ApplyDownloaded(ctx)
+		ctx, updater := newUpdaterContext(f, ulog, keybase.Check)
+		updater.SetLockTimeout(f.lockTimeout)
+		applied, err := updater.ApplyDownloadedContext(goCtx, ctx)
 		if err != nil {
 			ulog.Error(err)
 			return err
 		}
 		fmt.Println(applied)
+	case "status":
+		if err := statusFromFlags(f, ulog); err != nil {
+			ulog.Error(err)
+			return err
+		}
+	case "history":
+		if err := historyFromFlags(f, ulog); err != nil {
+			ulog.Error(err)
+			return err
+		}
+	case "control":
+		if err := controlFromFlags(f); err != nil {
+			ulog.Error(err)
+			return err
+		}
 	case "service", "":
 		svc := serviceFromFlags(f, ulog)
+		go func() {
+			<-goCtx.Done()
+			svc.Quit()
+		}()
 		svc.Run()
 	case "clean":
 		if runtime.GOOS == "windows" {
-			ctx, _ := keybase.NewUpdaterContext(f.appName, f.pathToKeybase, ulog, keybase.CheckPassive)
+			ctx, _ := newUpdaterContext(f, ulog, keybase.CheckPassive)
 			fmt.Printf("Doing DeepClean\n")
 			ctx.DeepClean()
 		} else {
@@ -139,12 +204,125 @@ func run(f flags) error {
 
 func serviceFromFlags(f flags, ulog logger) *service {
 	ulog.Infof("Updater %s", updater.Version)
-	ctx, upd := keybase.NewUpdaterContext(f.appName, f.pathToKeybase, ulog, keybase.Service)
+	ctx, upd := newUpdaterContext(f, ulog, keybase.Service)
 	return newService(upd, ctx, ulog, f.appName)
 }
 
-func updateCheckFromFlags(f flags, ulog logger) error {
-	ctx, updater := keybase.NewUpdaterContext(f.appName, f.pathToKeybase, ulog, keybase.Check)
-	_, err := updater.Update(ctx)
+func updateCheckFromFlags(goCtx context.Context, f flags, ulog logger) error {
+	ctx, updater := newUpdaterContext(f, ulog, keybase.Check)
+	updater.SetLockTimeout(f.lockTimeout)
+	_, err := updater.UpdateContext(goCtx, ctx)
 	return err
 }
+
+// serviceStatus is the state of the updater, and of the service
+type serviceStatus struct {
+	updater.Status
+	// ServiceLocked is whether a service holds the PID lock
+	ServiceLocked bool `json:"serviceLocked"`
+	// ServicePID is the pid of the service holding the PID lock, if known
+	ServicePID int `json:"servicePid,omitempty"`
+}
+
+// statusFromFlags prints the state of the updater, as JSON
+func statusFromFlags(f flags, ulog logger) error {
+	ctx, upd := newUpdaterContext(f, ulog, keybase.CheckPassive)
+	status := serviceStatus{Status: upd.Status(ctx)}
+	locked, pid, err := pidLockHolder(f.appName, ulog)
+	if err != nil {
+		ulog.Warningf("Error checking PID lock: %s", err)
+	}
+	status.ServiceLocked, status.ServicePID = locked, pid
+	return printJSON(status)
+}
+
+// historyFromFlags prints the update history (oldest first), as JSON. The
+// entries can be selected with -failed, -since <duration>, -version <version>
+// and -limit <n>.
+func historyFromFlags(f flags, ulog logger) error {
+	var query updater.HistoryQuery
+	var since time.Duration
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	fs.BoolVar(&query.Failed, "failed", false, "Only failed attempts")
+	fs.DurationVar(&since, "since", 0, "Only attempts in the last duration")
+	fs.StringVar(&query.Version, "version", "", "Only attempts to update to a version")
+	fs.IntVar(&query.Limit, "limit", 0, "Only the most recent attempts")
+	if err := fs.Parse(f.args); err != nil {
+		return err
+	}
+	if since > 0 {
+		query.Since = time.Now().Add(-since)
+	}
+	_, upd := newUpdaterContext(f, ulog, keybase.CheckPassive)
+	entries, err := upd.History(query)
+	if err != nil {
+		return err
+	}
+	if entries == nil {
+		entries = []updater.HistoryEntry{}
+	}
+	return printJSON(entries)
+}
+
+// controlFromFlags sends a request to the running service, with the control
+// API: status, check, snooze [duration] [version], auto <true|false> or
+// apply-downloaded
+func controlFromFlags(f flags) error {
+	if len(f.args) == 0 {
+		return fmt.Errorf("Missing control command")
+	}
+	path, err := socketPath(f.appName)
+	if err != nil {
+		return err
+	}
+	client := control.NewClient(path)
+	switch cmd, args := f.args[0], f.args[1:]; cmd {
+	case "status":
+		status, err := client.Status()
+		if err != nil {
+			return err
+		}
+		return printJSON(status)
+	case "check":
+		return client.Check()
+	case "snooze":
+		var duration time.Duration
+		var version string
+		if len(args) > 0 {
+			if duration, err = time.ParseDuration(args[0]); err != nil {
+				return fmt.Errorf("Invalid snooze duration: %s", err)
+			}
+		}
+		if len(args) > 1 {
+			version = args[1]
+		}
+		return client.Snooze(version, duration)
+	case "auto":
+		if len(args) == 0 {
+			return fmt.Errorf("Missing auto value (true or false)")
+		}
+		auto, err := strconv.ParseBool(args[0])
+		if err != nil {
+			return fmt.Errorf("Invalid auto value: %s", err)
+		}
+		return client.SetAuto(auto)
+	case "apply-downloaded":
+		applied, err := client.ApplyDownloaded()
+		if err != nil {
+			return err
+		}
+		fmt.Println(applied)
+		return nil
+	default:
+		return fmt.Errorf("Unknown control command: %s", cmd)
+	}
+}
+
+func printJSON(v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
@@ -4,9 +4,11 @@
 package main
 
 import (
+	"os"
 	"runtime"
 	"testing"
 
+	"github.com/keybase/go-updater/keybase"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -32,3 +34,29 @@ func TestLoadFlags(t *testing.T) {
 		assert.Equal(t, "Keybase", f.appName)
 	}
 }
+
+func TestStatusFromFlags(t *testing.T) {
+	err := statusFromFlags(flags{appName: "KeybaseTest", pathToKeybase: "keybase"}, logger{})
+	require.NoError(t, err)
+}
+
+func TestHistoryFromFlags(t *testing.T) {
+	err := historyFromFlags(flags{appName: "KeybaseTest", pathToKeybase: "keybase", args: []string{"-failed", "-since", "24h", "-limit", "10"}}, logger{})
+	require.NoError(t, err)
+
+	err = historyFromFlags(flags{appName: "KeybaseTest", pathToKeybase: "keybase", args: []string{"-since", "invalid"}}, logger{})
+	require.Error(t, err)
+}
+
+func TestContextOptionsFromFlags(t *testing.T) {
+	f := flags{
+		endpoints: keybase.Endpoints{Update: "https://updates.example.com/update.json"},
+		caCerts:   "a.pem" + string(os.PathListSeparator) + "b.pem",
+	}
+	options := f.contextOptions()
+	assert.Equal(t, "https://updates.example.com/update.json", options.Endpoints.Update)
+	assert.Equal(t, []string{"a.pem", "b.pem"}, options.CACertPaths)
+
+	err := run(flags{command: "status", endpoints: keybase.Endpoints{Update: "http://updates.example.com"}})
+	assert.Error(t, err)
+}
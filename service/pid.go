@@ -7,7 +7,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
+
+	"github.com/keybase/go-updater/util"
 )
 
 // LockPIDFile manages a lock file containing the PID for the current process.
@@ -59,6 +63,26 @@ func (f *LockPIDFile) Lock() (err error) {
 	return nil
 }
 
+// Holder returns whether another process holds the lock, and its pid (or 0 if
+// unknown). It doesn't lock the file, so it can't get in the way of a service
+// starting. The file has the PID of the service holding the lock, and is
+// removed when it stops, so the lock is held if the process with the PID
+// exists.
+func (f *LockPIDFile) Holder() (bool, int, error) {
+	data, err := os.ReadFile(f.name)
+	if os.IsNotExist(err) {
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || !util.ProcessExists(pid) {
+		return false, 0, nil
+	}
+	return true, pid, nil
+}
+
 // Close releases the lock by closing and removing the file.
 func (f *LockPIDFile) Close() (err error) {
 	if f.file != nil {
@@ -0,0 +1,55 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockPIDFileHolder(t *testing.T) {
+	dir, err := util.MakeTempDir("TestLockPIDFileHolder.", 0700)
+	require.NoError(t, err)
+	defer util.RemoveFileAtPath(dir)
+	path := filepath.Join(dir, "updater.pid")
+
+	held, pid, err := NewLockPIDFile(path, testLog).Holder()
+	require.NoError(t, err)
+	assert.False(t, held)
+	assert.Equal(t, 0, pid)
+
+	lockPID := NewLockPIDFile(path, testLog)
+	err = lockPID.Lock()
+	require.NoError(t, err)
+	held, pid, err = NewLockPIDFile(path, testLog).Holder()
+	require.NoError(t, err)
+	assert.True(t, held)
+	assert.Equal(t, os.Getpid(), pid)
+
+	err = lockPID.Close()
+	require.NoError(t, err)
+	held, _, err = NewLockPIDFile(path, testLog).Holder()
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	// A pid file left by a service that didn't stop
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	err = cmd.Run()
+	require.NoError(t, err)
+	err = os.WriteFile(path, []byte(strconv.Itoa(cmd.Process.Pid)), 0600)
+	require.NoError(t, err)
+	held, _, err = NewLockPIDFile(path, testLog).Holder()
+	require.NoError(t, err)
+	assert.False(t, held)
+}
@@ -4,7 +4,14 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
 	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/control"
+	"github.com/keybase/go-updater/keybase"
 	"github.com/keybase/go-updater/util"
 )
 
@@ -24,6 +31,7 @@ type service struct {
 	context       updater.Context
 	log           Log
 	appName       string
+	controlServer *control.Server
 	ch            chan int
 }
 
@@ -56,10 +64,83 @@ func (s *service) Run() {
 	}
 	defer closer.Close()
 
+	// Finish or undo an update that was interrupted, before anything else
+	if err := s.updater.Recover(); err != nil {
+		s.log.Errorf("Error recovering interrupted update: %s", err)
+	}
+
 	s.Start()
+	s.startControl()
 	<-s.ch
+	s.stopControl()
+	s.updateChecker.Stop()
 }
 
 func (s *service) Quit() {
 	s.ch <- 0
 }
+
+// socketPath is where the control API listens. It's in its own dir, which is
+// private to the current user (unlike the cache dir, which may not be).
+func socketPath(appName string) (string, error) {
+	cacheDir, err := keybase.CacheDir(appName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "updater-control", "updater.sock"), nil
+}
+
+// startControl starts the control API. If it doesn't start, the error is
+// logged, and the service runs without it.
+func (s *service) startControl() {
+	path, err := socketPath(s.appName)
+	if err != nil {
+		s.log.Errorf("Error getting control socket path: %s", err)
+		return
+	}
+	server := control.NewServer(path, s, s.log)
+	if err := server.Start(); err != nil {
+		s.log.Errorf("Error starting control API: %s", err)
+		return
+	}
+	s.controlServer = server
+}
+
+func (s *service) stopControl() {
+	if s.controlServer == nil {
+		return
+	}
+	if err := s.controlServer.Stop(); err != nil {
+		s.log.Warningf("Error stopping control API: %s", err)
+	}
+	s.controlServer = nil
+}
+
+// Status returns the state of the updater (for the control API)
+func (s *service) Status() (*updater.Status, error) {
+	status := s.updater.Status(s.context)
+	return &status, nil
+}
+
+// Check triggers an update check (for the control API)
+func (s *service) Check() error {
+	if !s.updateChecker.CheckNow() {
+		return fmt.Errorf("Check already requested")
+	}
+	return nil
+}
+
+// Snooze snoozes an update (for the control API)
+func (s *service) Snooze(version string, duration time.Duration) error {
+	return s.updater.Snooze(s.context, version, duration)
+}
+
+// SetAuto sets whether to update automatically (for the control API)
+func (s *service) SetAuto(auto bool) error {
+	return s.updater.SetUpdateAuto(auto)
+}
+
+// ApplyDownloaded applies a previously downloaded update (for the control API)
+func (s *service) ApplyDownloaded(goCtx context.Context) (bool, error) {
+	return s.updater.ApplyDownloadedContext(goCtx, s.context)
+}
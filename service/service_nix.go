@@ -10,15 +10,33 @@ import (
 	"github.com/keybase/go-updater/keybase"
 )
 
+func pidPath(appName string) (string, error) {
+	cacheDir, err := keybase.CacheDir(appName)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "updater.pid"), nil
+}
+
 func (s *service) lockPID() (io.Closer, error) {
-	cacheDir, err := keybase.CacheDir(s.appName)
+	path, err := pidPath(s.appName)
 	if err != nil {
 		return nil, err
 	}
-	lockPID := NewLockPIDFile(filepath.Join(cacheDir, "updater.pid"), s.log)
+	lockPID := NewLockPIDFile(path, s.log)
 	if err := lockPID.Lock(); err != nil {
 		return nil, err
 	}
 	s.log.Debug("update pid file %s created, updater service starting", lockPID.name)
 	return lockPID, nil
 }
+
+// pidLockHolder returns whether a service holds the PID lock, and its pid (or
+// 0 if unknown)
+func pidLockHolder(appName string, log Log) (bool, int, error) {
+	path, err := pidPath(appName)
+	if err != nil {
+		return false, 0, err
+	}
+	return NewLockPIDFile(path, log).Holder()
+}
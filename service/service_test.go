@@ -8,8 +8,11 @@ import (
 	"time"
 
 	"github.com/keybase/go-logging"
+	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/control"
 	"github.com/keybase/go-updater/keybase"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var testLog = &logging.Logger{Module: "test"}
@@ -26,3 +29,41 @@ func TestService(t *testing.T) {
 	}()
 	svc.Run()
 }
+
+func TestServiceControl(t *testing.T) {
+	ctx, upd := keybase.NewUpdaterContext("KeybaseTest", "keybase", testLog, keybase.Service)
+	svc := newService(upd, ctx, testLog, "KeybaseTest")
+	done := make(chan struct{})
+	go func() {
+		svc.Run()
+		close(done)
+	}()
+	defer func() {
+		svc.Quit()
+		<-done
+	}()
+
+	path, err := socketPath("KeybaseTest")
+	require.NoError(t, err)
+	client := control.NewClient(path)
+	var status *updater.Status
+	for i := 0; i < 100; i++ {
+		if status, err = client.Status(); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err)
+	assert.Equal(t, updater.Version, status.Version)
+
+	err = controlFromFlags(flags{appName: "KeybaseTest", args: []string{"status"}})
+	require.NoError(t, err)
+	err = controlFromFlags(flags{appName: "KeybaseTest", args: []string{"auto", "maybe"}})
+	require.Error(t, err)
+	err = controlFromFlags(flags{appName: "KeybaseTest", args: []string{"snooze", "soon"}})
+	require.Error(t, err)
+	err = controlFromFlags(flags{appName: "KeybaseTest", args: []string{"unknown"}})
+	require.EqualError(t, err, "Unknown control command: unknown")
+	err = controlFromFlags(flags{appName: "KeybaseTest"})
+	require.EqualError(t, err, "Missing control command")
+}
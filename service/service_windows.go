@@ -14,3 +14,8 @@ func (n *nopCloser) Close() error {
 func (s *service) lockPID() (io.Closer, error) {
 	return &nopCloser{}, nil
 }
+
+// pidLockHolder returns false, since there is no PID lock on Windows
+func pidLockHolder(appName string, log Log) (bool, int, error) {
+	return false, 0, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/keybase/go-updater/util"
+)
+
+// DefaultSnoozeDuration is how long an update is snoozed, if the prompt
+// response doesn't specify a duration
+const DefaultSnoozeDuration = 24 * time.Hour
+
+// SnoozeConfig is an optional interface for a Config, to remember that the
+// user snoozed an update. If the Config implements it, we don't prompt for
+// the snoozed version again until the snooze is over.
+type SnoozeConfig interface {
+	// GetSnooze returns the snoozed version (or "" if none) and when the
+	// snooze is over
+	GetSnooze() (version string, until time.Time)
+	// SetSnooze snoozes version until a time. An empty version clears it.
+	SetSnooze(version string, until time.Time) error
+}
+
+// snooze saves the snooze for an update, from the prompt response
+func (u *Updater) snooze(update Update, response UpdatePromptResponse) {
+	snoozeConfig, ok := u.config.(SnoozeConfig)
+	if !ok {
+		return
+	}
+	if err := u.snoozeVersion(snoozeConfig, update.Version, time.Duration(response.SnoozeDuration)*time.Second); err != nil {
+		u.log.Warningf("Error saving snooze: %s", err)
+	}
+}
+
+func (u *Updater) snoozeVersion(snoozeConfig SnoozeConfig, version string, duration time.Duration) error {
+	if duration <= 0 {
+		duration = DefaultSnoozeDuration
+	}
+	until := time.Now().Add(duration)
+	u.log.Infof("Snoozing update %s until %s", version, until)
+	return snoozeConfig.SetSnooze(version, until)
+}
+
+// Snooze snoozes an update for a duration (or DefaultSnoozeDuration if 0). If
+// version is empty, the available update is snoozed, which checks for it
+// (with the update lock held).
+func (u *Updater) Snooze(ctx Context, version string, duration time.Duration) error {
+	snoozeConfig, ok := u.config.(SnoozeConfig)
+	if !ok {
+		return configErr(fmt.Errorf("Snooze isn't supported"))
+	}
+	if version == "" {
+		lock, err := u.lock(context.Background())
+		if err != nil {
+			return err
+		}
+		defer lock.unlock()
+		update, err := u.checkForUpdate(context.Background(), ctx, ctx.UpdateOptions())
+		if err != nil {
+			return err
+		}
+		if update == nil || !update.NeedUpdate {
+			return fmt.Errorf("No update to snooze")
+		}
+		version = update.Version
+	}
+	if err := u.snoozeVersion(snoozeConfig, version, duration); err != nil {
+		return configErr(err)
+	}
+	return nil
+}
+
+// isSnoozed returns true if the user snoozed the update and the snooze isn't
+// over. A snooze for an older version (or one that's over) is cleared.
+func (u *Updater) isSnoozed(update Update, options UpdateOptions) bool {
+	snoozeConfig, ok := u.config.(SnoozeConfig)
+	if !ok {
+		return false
+	}
+	version, until := snoozeConfig.GetSnooze()
+	if version == "" {
+		return false
+	}
+	if version != update.Version {
+		// An invalid version is treated as newer
+		if compare, err := util.SemverCompare(update.Version, version); err != nil || compare > 0 {
+			u.log.Infof("Clearing snooze for %s, since there is a newer version %s", version, update.Version)
+			u.clearSnooze(snoozeConfig)
+		}
+		return false
+	}
+	if !time.Now().Before(until) {
+		u.log.Infof("Snooze for %s is over", version)
+		u.clearSnooze(snoozeConfig)
+		return false
+	}
+	if u.policy.IsOverdue(update, time.Now()) {
+		u.log.Infof("Ignoring snooze for %s (until %s), the update is overdue", version, until)
+		return false
+	}
+	if options.IgnoreSnooze {
+		u.log.Infof("Ignoring snooze for %s (until %s)", version, until)
+		return false
+	}
+	u.log.Infof("Update %s is snoozed until %s", version, until)
+	return true
+}
+
+func (u *Updater) clearSnooze(snoozeConfig SnoozeConfig) {
+	if err := snoozeConfig.SetSnooze("", time.Time{}); err != nil {
+		u.log.Warningf("Error clearing snooze: %s", err)
+	}
+}
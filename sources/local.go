@@ -47,8 +47,18 @@ func (k LocalUpdateSource) FindUpdate(options updater.UpdateOptions) (*updater.U
 	}
 
 	update.Asset.URL = fmt.Sprintf("file://%s", k.path)
-	// TODO: Only do if version is newer or forced (this source is used for testing, so ok to hardcode NeedUpdate)
-	update.NeedUpdate = true
+	update.NeedUpdate = options.Force || k.isNewer(update.Version, options.Version)
 	k.log.Debugf("Returning update: %#v", update)
 	return &update, nil
 }
+
+// isNewer returns true if version is newer than current. If current is
+// unknown (or invalid) we assume we need the update.
+func (k LocalUpdateSource) isNewer(version string, current string) bool {
+	cmp, err := util.SemverCompare(version, current)
+	if err != nil {
+		k.log.Warningf("Unable to compare versions %q and %q: %s", version, current, err)
+		return true
+	}
+	return cmp > 0
+}
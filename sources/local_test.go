@@ -27,3 +27,26 @@ func TestLocalUpdateSource(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, update)
 }
+
+func TestLocalUpdateSourceNeedUpdate(t *testing.T) {
+	_, filename, _, _ := runtime.Caller(0)
+	path := filepath.Join(filepath.Dir(filename), "../test/test.zip")
+	jsonPath := filepath.Join(filepath.Dir(filename), "../test/update.json")
+	local := NewLocalUpdateSource(path, jsonPath, log)
+
+	update, err := local.FindUpdate(updater.UpdateOptions{Version: "1.2.2"})
+	require.NoError(t, err)
+	assert.True(t, update.NeedUpdate)
+
+	update, err = local.FindUpdate(updater.UpdateOptions{Version: "1.2.3-400+abcdef"})
+	require.NoError(t, err)
+	assert.False(t, update.NeedUpdate)
+
+	update, err = local.FindUpdate(updater.UpdateOptions{Version: "1.2.4"})
+	require.NoError(t, err)
+	assert.False(t, update.NeedUpdate)
+
+	update, err = local.FindUpdate(updater.UpdateOptions{Version: "1.2.4", Force: true})
+	require.NoError(t, err)
+	assert.True(t, update.NeedUpdate)
+}
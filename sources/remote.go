@@ -4,6 +4,7 @@
 package sources
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -48,8 +49,13 @@ func (r RemoteUpdateSource) sourceURL(options updater.UpdateOptions) string {
 
 // FindUpdate returns update for options
 func (r RemoteUpdateSource) FindUpdate(options updater.UpdateOptions) (*updater.Update, error) {
+	return r.FindUpdateContext(context.Background(), options)
+}
+
+// FindUpdateContext returns update for options
+func (r RemoteUpdateSource) FindUpdateContext(ctx context.Context, options updater.UpdateOptions) (*updater.Update, error) {
 	sourceURL := r.sourceURL(options)
-	req, err := http.NewRequest("GET", sourceURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", sourceURL, nil)
 	if err != nil {
 		return nil, err
 	}
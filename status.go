@@ -0,0 +1,138 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"os"
+	"time"
+)
+
+// CheckResult is the result of an update check
+type CheckResult struct {
+	// Time is when the check finished
+	Time time.Time `json:"time"`
+	// Version is the version of the update found, if any
+	Version string `json:"version,omitempty"`
+	// Error is the error from the check, if any
+	Error string `json:"error,omitempty"`
+}
+
+// CheckStatusConfig is an optional interface for a Config, to remember when
+// we last checked for an update and the result, for Status
+type CheckStatusConfig interface {
+	// GetLastUpdateCheckTime returns when we last checked for an update
+	GetLastUpdateCheckTime() (time.Time, error)
+	// GetLastCheckResult returns the result of the last update check, or nil
+	GetLastCheckResult() *CheckResult
+	// SetLastCheckResult saves the result of the last update check
+	SetLastCheckResult(result CheckResult) error
+}
+
+// Status is the state of the updater
+type Status struct {
+	// Version is the updater version
+	Version string `json:"version"`
+	// CurrentVersion is the version of the app
+	CurrentVersion string `json:"currentVersion"`
+	// PendingAsset is the path to a downloaded asset that hasn't been applied
+	PendingAsset string `json:"pendingAsset,omitempty"`
+	// Journal is the state of the last update, if any
+	Journal *Journal `json:"journal,omitempty"`
+	// LastCheckTime is when we last checked for an update
+	LastCheckTime *time.Time `json:"lastCheckTime,omitempty"`
+	// LastCheck is the result of the last update check
+	LastCheck *CheckResult `json:"lastCheck,omitempty"`
+	// Auto is whether to update automatically
+	Auto bool `json:"auto"`
+	// AutoSet is whether the user set Auto
+	AutoSet bool `json:"autoSet"`
+	// AutoOverride is whether Auto is temporarily overridden
+	AutoOverride bool `json:"autoOverride"`
+	// InstallID is the install ID from the API
+	InstallID string `json:"installId"`
+	// LastAppliedVersion is the version of the last update applied
+	LastAppliedVersion string `json:"lastAppliedVersion"`
+	// SnoozeVersion is the snoozed version, if any
+	SnoozeVersion string `json:"snoozeVersion,omitempty"`
+	// SnoozeUntil is when the snooze is over
+	SnoozeUntil *time.Time `json:"snoozeUntil,omitempty"`
+	// UpdateLocked is whether an updater holds the update lock
+	UpdateLocked bool `json:"updateLocked"`
+	// UpdateLockPID is the PID of the updater holding the update lock, if known
+	UpdateLockPID int `json:"updateLockPid,omitempty"`
+}
+
+// Status returns the state of the updater. Errors getting parts of the state
+// are logged, and those parts are left out.
+func (u *Updater) Status(ctx Context) Status {
+	auto, autoSet := u.config.GetUpdateAuto()
+	status := Status{
+		Version:            Version,
+		CurrentVersion:     ctx.UpdateOptions().Version,
+		Auto:               auto,
+		AutoSet:            autoSet,
+		AutoOverride:       u.config.GetUpdateAutoOverride(),
+		InstallID:          u.config.GetInstallID(),
+		LastAppliedVersion: u.config.GetLastAppliedVersion(),
+	}
+
+	pendingAsset, err := u.pendingAsset()
+	if err != nil {
+		u.log.Warningf("Error finding pending asset: %s", err)
+	}
+	status.PendingAsset = pendingAsset
+
+	journal, err := u.ReadJournal()
+	if err != nil {
+		u.log.Warningf("Error reading journal: %s", err)
+	}
+	status.Journal = journal
+
+	if checkConfig, ok := u.config.(CheckStatusConfig); ok {
+		lastCheckTime, err := checkConfig.GetLastUpdateCheckTime()
+		if err != nil && !os.IsNotExist(err) {
+			u.log.Warningf("Error getting last update check time: %s", err)
+		} else if err == nil {
+			status.LastCheckTime = &lastCheckTime
+		}
+		status.LastCheck = checkConfig.GetLastCheckResult()
+	}
+
+	if snoozeConfig, ok := u.config.(SnoozeConfig); ok {
+		snoozeVersion, snoozeUntil := snoozeConfig.GetSnooze()
+		if snoozeVersion != "" {
+			status.SnoozeVersion = snoozeVersion
+			status.SnoozeUntil = &snoozeUntil
+		}
+	}
+
+	status.UpdateLocked, status.UpdateLockPID = u.updateLockHolder()
+	return status
+}
+
+// recordCheck saves the result of an update check, if the config supports it
+func (u *Updater) recordCheck(update *Update, err error) {
+	checkConfig, ok := u.config.(CheckStatusConfig)
+	if !ok {
+		return
+	}
+	result := CheckResult{Time: time.Now()}
+	if update != nil {
+		result.Version = update.Version
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if err := checkConfig.SetLastCheckResult(result); err != nil {
+		u.log.Warningf("Error saving check result: %s", err)
+	}
+}
+
+// SetUpdateAuto sets whether to update automatically
+func (u *Updater) SetUpdateAuto(auto bool) error {
+	if err := u.config.SetUpdateAuto(auto); err != nil {
+		return configErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package updater
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdaterStatus(t *testing.T) {
+	upr, err := newTestUpdater(t)
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+	err = upr.SetUpdateAuto(true)
+	require.NoError(t, err)
+
+	status := upr.Status(ctx)
+	assert.Equal(t, Version, status.Version)
+	assert.Equal(t, "1.0.0", status.CurrentVersion)
+	assert.True(t, status.Auto)
+	assert.True(t, status.AutoSet)
+	assert.False(t, status.AutoOverride)
+	assert.Equal(t, "", status.PendingAsset)
+	assert.Nil(t, status.Journal)
+	assert.Nil(t, status.LastCheckTime)
+	assert.Nil(t, status.LastCheck)
+	assert.Nil(t, status.SnoozeUntil)
+	assert.False(t, status.UpdateLocked)
+
+	upr.config.(*testConfig).err = fmt.Errorf("Test config error")
+	err = upr.SetUpdateAuto(false)
+	require.Error(t, err)
+	assert.Equal(t, "config", err.(Error).TypeString())
+}
+
+func TestUpdaterStatusAfterUpdate(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionSnooze})
+
+	upr.config.SetLastUpdateCheckTime()
+	_, err = upr.Update(ctx)
+	require.Error(t, err)
+	path := makeCachedAsset(t, upr, *testUpdate(testServer.URL).Asset)
+
+	status := upr.Status(ctx)
+	require.NotNil(t, status.LastCheckTime)
+	assert.WithinDuration(t, time.Now(), *status.LastCheckTime, time.Minute)
+	require.NotNil(t, status.LastCheck)
+	assert.Equal(t, "1.0.1", status.LastCheck.Version)
+	assert.Equal(t, "Update Error (cancel): Snoozed update", status.LastCheck.Error)
+	assert.Equal(t, "deadbeef", status.InstallID)
+	assert.Equal(t, "1.0.1", status.SnoozeVersion)
+	require.NotNil(t, status.SnoozeUntil)
+	assert.Equal(t, path, status.PendingAsset)
+
+	// Holding the update lock
+	lock, err := upr.lock(context.Background())
+	require.NoError(t, err)
+	defer lock.unlock()
+	status = upr.Status(ctx)
+	assert.True(t, status.UpdateLocked)
+	assert.Equal(t, os.Getpid(), status.UpdateLockPID)
+}
@@ -0,0 +1,30 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package tufrepo
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FixtureSeed is the seed for the keys of the fixture repository
+const FixtureSeed = "go-updater-test"
+
+// FixtureExpires is when the fixture repository metadata expires
+var FixtureExpires = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Fixture builds the fixture repository (in test/tuf), which signs the asset in
+// test/update.json (its digest is of test/test-with-sym.zip). testDir is the
+// path to test.
+func Fixture(testDir string) (*Repo, error) {
+	data, err := os.ReadFile(filepath.Join(testDir, "test-with-sym.zip"))
+	if err != nil {
+		return nil, err
+	}
+	r := New(FixtureSeed, FixtureExpires)
+	r.AddTarget("Test-1.2.3-400+abcdef.zip", data, "1.2.3-400+abcdef")
+	r.Commit()
+	return r, nil
+}
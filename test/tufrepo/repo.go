@@ -0,0 +1,268 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+// Package tufrepo builds TUF repositories (signed metadata for update assets)
+// for tests. Keys are derived from a seed, so a repository is reproducible.
+package tufrepo
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Roles, in the order they are signed
+var roles = []string{"root", "targets", "snapshot", "timestamp"}
+
+type roleKeys struct {
+	keys       []ed25519.PrivateKey
+	threshold  int
+	generation int
+	// signers is how many of the keys sign (all if 0)
+	signers int
+}
+
+func (k roleKeys) signing() []ed25519.PrivateKey {
+	if k.signers > 0 && k.signers < len(k.keys) {
+		return k.keys[:k.signers]
+	}
+	return k.keys
+}
+
+type key struct {
+	Type   string `json:"keytype"`
+	Public string `json:"public"`
+}
+
+type roleMeta struct {
+	KeyIDs    []string `json:"keyids"`
+	Threshold int      `json:"threshold"`
+}
+
+type fileMeta struct {
+	Version int64             `json:"version"`
+	Length  int64             `json:"length,omitempty"`
+	Hashes  map[string]string `json:"hashes,omitempty"`
+}
+
+// Target is a signed update asset
+type Target struct {
+	Length int64             `json:"length"`
+	Hashes map[string]string `json:"hashes"`
+	Custom map[string]string `json:"custom,omitempty"`
+}
+
+type signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+type signed struct {
+	Signed     json.RawMessage `json:"signed"`
+	Signatures []signature     `json:"signatures"`
+}
+
+// Repo is a TUF repository
+type Repo struct {
+	seed     string
+	expires  time.Time
+	keys     map[string]*roleKeys
+	versions map[string]int64
+	targets  map[string]Target
+	files    map[string][]byte
+}
+
+// New returns a repository with one key for each role, and an initial (empty)
+// commit. Metadata expires at expires.
+func New(seed string, expires time.Time) *Repo {
+	r := &Repo{
+		seed:     seed,
+		expires:  expires.UTC(),
+		keys:     map[string]*roleKeys{},
+		versions: map[string]int64{},
+		targets:  map[string]Target{},
+		files:    map[string][]byte{},
+	}
+	for _, role := range roles {
+		r.keys[role] = r.newKeys(role, 0, 1, 1)
+	}
+	r.writeRoot(nil)
+	r.Commit()
+	return r
+}
+
+func (r *Repo) newKeys(role string, generation int, n int, threshold int) *roleKeys {
+	keys := &roleKeys{threshold: threshold, generation: generation}
+	for i := 0; i < n; i++ {
+		seed := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%d:%d", r.seed, role, generation, i)))
+		keys.keys = append(keys.keys, ed25519.NewKeyFromSeed(seed[:]))
+	}
+	return keys
+}
+
+func keyID(public ed25519.PublicKey) string {
+	sum := sha256.Sum256(public)
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *Repo) sign(data interface{}, keys []ed25519.PrivateKey) []byte {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+	s := signed{Signed: raw}
+	for _, k := range keys {
+		public := k.Public().(ed25519.PublicKey)
+		s.Signatures = append(s.Signatures, signature{
+			KeyID: keyID(public),
+			Sig:   hex.EncodeToString(ed25519.Sign(k, raw)),
+		})
+	}
+	out, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+func header(role string, version int64, expires time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"_type":   role,
+		"version": version,
+		"expires": expires,
+	}
+}
+
+func fileMetaFor(data []byte, version int64) fileMeta {
+	sum := sha256.Sum256(data)
+	return fileMeta{
+		Version: version,
+		Length:  int64(len(data)),
+		Hashes:  map[string]string{"sha256": hex.EncodeToString(sum[:])},
+	}
+}
+
+// writeRoot writes a new version of the root metadata, signed by the root
+// keys, and by oldRootKeys (if the root keys were rotated)
+func (r *Repo) writeRoot(oldRootKeys []ed25519.PrivateKey) {
+	r.versions["root"]++
+	version := r.versions["root"]
+	root := header("root", version, r.expires)
+	keys := map[string]key{}
+	roleMetas := map[string]roleMeta{}
+	for _, role := range roles {
+		meta := roleMeta{Threshold: r.keys[role].threshold}
+		for _, k := range r.keys[role].keys {
+			public := k.Public().(ed25519.PublicKey)
+			id := keyID(public)
+			keys[id] = key{Type: "ed25519", Public: hex.EncodeToString(public)}
+			meta.KeyIDs = append(meta.KeyIDs, id)
+		}
+		sort.Strings(meta.KeyIDs)
+		roleMetas[role] = meta
+	}
+	root["keys"] = keys
+	root["roles"] = roleMetas
+	data := r.sign(root, append(append([]ed25519.PrivateKey{}, oldRootKeys...), r.keys["root"].keys...))
+	r.files["root.json"] = data
+	r.files[fmt.Sprintf("%d.root.json", version)] = data
+}
+
+// SetExpires sets when metadata expires, for the next commit
+func (r *Repo) SetExpires(expires time.Time) {
+	r.expires = expires.UTC()
+}
+
+// AddTarget adds an update asset (for an update version), for the next commit
+func (r *Repo) AddTarget(name string, data []byte, version string) {
+	sum := sha256.Sum256(data)
+	r.targets[name] = Target{
+		Length: int64(len(data)),
+		Hashes: map[string]string{"sha256": hex.EncodeToString(sum[:])},
+		Custom: map[string]string{"version": version},
+	}
+}
+
+// AddTargetFile adds the update asset at path, for the next commit
+func (r *Repo) AddTargetFile(path string, version string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	r.AddTarget(filepath.Base(path), data, version)
+	return nil
+}
+
+// RemoveTarget removes an update asset, for the next commit
+func (r *Repo) RemoveTarget(name string) {
+	delete(r.targets, name)
+}
+
+// SetKeys replaces the keys for a role with n new keys, and a threshold, and
+// writes a new version of the root metadata. The new root is signed by the old
+// root keys as well, so clients can rotate to it.
+func (r *Repo) SetKeys(role string, n int, threshold int) {
+	old := r.keys["root"].keys
+	r.keys[role] = r.newKeys(role, r.keys[role].generation+1, n, threshold)
+	r.writeRoot(old)
+}
+
+// SetSigners sets how many of the keys for a role sign, from the next commit,
+// for example to sign with fewer than the threshold
+func (r *Repo) SetSigners(role string, n int) {
+	r.keys[role].signers = n
+}
+
+// Commit writes new versions of the targets, snapshot and timestamp metadata
+func (r *Repo) Commit() {
+	r.versions["targets"]++
+	targets := header("targets", r.versions["targets"], r.expires)
+	targets["targets"] = r.targets
+	targetsData := r.sign(targets, r.keys["targets"].signing())
+	r.files["targets.json"] = targetsData
+
+	r.versions["snapshot"]++
+	snapshot := header("snapshot", r.versions["snapshot"], r.expires)
+	snapshot["meta"] = map[string]fileMeta{"targets.json": fileMetaFor(targetsData, r.versions["targets"])}
+	snapshotData := r.sign(snapshot, r.keys["snapshot"].signing())
+	r.files["snapshot.json"] = snapshotData
+
+	r.versions["timestamp"]++
+	timestamp := header("timestamp", r.versions["timestamp"], r.expires)
+	timestamp["meta"] = map[string]fileMeta{"snapshot.json": fileMetaFor(snapshotData, r.versions["snapshot"])}
+	r.files["timestamp.json"] = r.sign(timestamp, r.keys["timestamp"].signing())
+}
+
+// InitialRoot returns the first root metadata, to distribute with the app as
+// the root of trust
+func (r *Repo) InitialRoot() []byte {
+	return r.files["1.root.json"]
+}
+
+// Files returns the metadata files (by name), as of now
+func (r *Repo) Files() map[string][]byte {
+	files := map[string][]byte{}
+	for name, data := range r.files {
+		files[name] = data
+	}
+	return files
+}
+
+// Write writes the metadata files to dir
+func (r *Repo) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, data := range r.files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
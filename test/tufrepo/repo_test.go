@@ -0,0 +1,59 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package tufrepo
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFixture checks the fixture repository in test/tuf is up to date. To
+// update it, run with UPDATE_FIXTURES=1.
+func TestFixture(t *testing.T) {
+	r, err := Fixture("..")
+	require.NoError(t, err)
+	dir := filepath.Join("..", "tuf")
+	if os.Getenv("UPDATE_FIXTURES") != "" {
+		require.NoError(t, os.RemoveAll(dir))
+		require.NoError(t, r.Write(dir))
+	}
+	for name, data := range r.Files() {
+		fixture, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.Equal(t, string(data), string(fixture), "%s is out of date", name)
+	}
+}
+
+func TestRepo(t *testing.T) {
+	r := New("test", time.Now().Add(time.Hour))
+	files := r.Files()
+	for _, name := range []string{"root.json", "1.root.json", "targets.json", "snapshot.json", "timestamp.json"} {
+		assert.Contains(t, files, name)
+	}
+
+	r.AddTarget("test.zip", []byte("test"), "1.0.1")
+	r.Commit()
+	var s signed
+	require.NoError(t, json.Unmarshal(r.Files()["targets.json"], &s))
+	assert.Equal(t, 1, len(s.Signatures))
+	var targets struct {
+		Version int64             `json:"version"`
+		Targets map[string]Target `json:"targets"`
+	}
+	require.NoError(t, json.Unmarshal(s.Signed, &targets))
+	assert.Equal(t, int64(2), targets.Version)
+	assert.Equal(t, "1.0.1", targets.Targets["test.zip"].Custom["version"])
+
+	// Rotating keys signs the new root with the old and new root keys
+	r.SetKeys("root", 2, 2)
+	require.NoError(t, json.Unmarshal(r.Files()["2.root.json"], &s))
+	assert.Equal(t, 3, len(s.Signatures))
+	assert.Equal(t, r.Files()["root.json"], r.Files()["2.root.json"])
+}
@@ -0,0 +1,281 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package tuf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/util"
+)
+
+// maxRootRotations is how many new root versions we accept in one refresh
+const maxRootRotations = 32
+
+// Client verifies updates against the signed metadata of a repository. The
+// metadata we trust is saved in a local dir, so versions only go forward.
+type Client struct {
+	remote Fetcher
+	dir    string
+	log    Log
+	now    func() time.Time
+
+	root      *Root
+	timestamp *Timestamp
+	snapshot  *Snapshot
+	targets   *Targets
+}
+
+// NewClient returns a client for the repository at remote, which saves the
+// metadata it trusts in dir. The first time, trustedRoot (root metadata that
+// was distributed with the app) is the root of trust.
+func NewClient(remote Fetcher, dir string, trustedRoot []byte, log Log) (*Client, error) {
+	c := &Client{remote: remote, dir: dir, log: log, now: time.Now}
+	data, err := os.ReadFile(c.path(RoleRoot.filename()))
+	if os.IsNotExist(err) {
+		data = trustedRoot
+	} else if err != nil {
+		return nil, err
+	}
+	var root Root
+	s, err := parseSigned(data, RoleRoot, &root)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignatures(s, &root, RoleRoot); err != nil {
+		return nil, err
+	}
+	c.root = &root
+	if err := c.save(RoleRoot.filename(), data); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetNow sets the time source (for testing)
+func (c *Client) SetNow(now func() time.Time) {
+	c.now = now
+}
+
+func (c *Client) path(name string) string {
+	return filepath.Join(c.dir, name)
+}
+
+func (c *Client) save(name string, data []byte) error {
+	if err := util.MakeDirs(c.dir, 0700, c.log); err != nil {
+		return err
+	}
+	return util.NewFile(c.path(name), data, 0600).Save(c.log)
+}
+
+// loadTrusted loads the metadata we trusted before, if it is still signed by
+// the root. Metadata that isn't is ignored, and replaced on refresh.
+func (c *Client) loadTrusted(role Role, v interface{}) bool {
+	data, err := os.ReadFile(c.path(role.filename()))
+	if err != nil {
+		return false
+	}
+	s, err := parseSigned(data, role, v)
+	if err == nil {
+		err = verifySignatures(s, c.root, role)
+	}
+	if err != nil {
+		c.log.Warningf("Ignoring trusted %s metadata: %s", role, err)
+		return false
+	}
+	return true
+}
+
+func (c *Client) checkExpires(header Header) error {
+	if !c.now().Before(header.Expires) {
+		return ExpiredError{Role: header.Type, Expires: header.Expires}
+	}
+	return nil
+}
+
+// Refresh fetches the latest metadata from the repository, and verifies it:
+// the signatures (by the threshold of keys for each role), that it hasn't
+// expired, and that versions haven't gone back.
+func (c *Client) Refresh(goCtx context.Context) error {
+	if err := c.updateRoot(goCtx); err != nil {
+		return err
+	}
+	if err := c.checkExpires(c.root.Header); err != nil {
+		return err
+	}
+
+	var trustedTimestamp Timestamp
+	hasTimestamp := c.loadTrusted(RoleTimestamp, &trustedTimestamp)
+	var trustedSnapshot Snapshot
+	hasSnapshot := c.loadTrusted(RoleSnapshot, &trustedSnapshot)
+
+	// Timestamp
+	timestampData, timestamp, err := c.fetchRole(goCtx, RoleTimestamp, &Timestamp{})
+	if err != nil {
+		return err
+	}
+	ts := timestamp.(*Timestamp)
+	if hasTimestamp && ts.Version < trustedTimestamp.Version {
+		return RollbackError{Role: RoleTimestamp, Version: ts.Version, Trusted: trustedTimestamp.Version}
+	}
+	if err := c.checkExpires(ts.Header); err != nil {
+		return err
+	}
+	snapshotMeta, ok := ts.Meta[RoleSnapshot.filename()]
+	if !ok {
+		return fmt.Errorf("Timestamp metadata has no snapshot")
+	}
+
+	// Snapshot
+	snapshotData, snapshot, err := c.fetchRole(goCtx, RoleSnapshot, &Snapshot{})
+	if err != nil {
+		return err
+	}
+	if err := checkHashes(snapshotData, snapshotMeta, RoleSnapshot.filename()); err != nil {
+		return err
+	}
+	sn := snapshot.(*Snapshot)
+	if sn.Version != snapshotMeta.Version {
+		return fmt.Errorf("Snapshot metadata version %d doesn't match timestamp (%d)", sn.Version, snapshotMeta.Version)
+	}
+	if hasSnapshot {
+		if sn.Version < trustedSnapshot.Version {
+			return RollbackError{Role: RoleSnapshot, Version: sn.Version, Trusted: trustedSnapshot.Version}
+		}
+		for name, trusted := range trustedSnapshot.Meta {
+			if meta, ok := sn.Meta[name]; ok && meta.Version < trusted.Version {
+				return RollbackError{Role: RoleTargets, Version: meta.Version, Trusted: trusted.Version}
+			}
+		}
+	}
+	if err := c.checkExpires(sn.Header); err != nil {
+		return err
+	}
+	targetsMeta, ok := sn.Meta[RoleTargets.filename()]
+	if !ok {
+		return fmt.Errorf("Snapshot metadata has no targets")
+	}
+
+	// Targets
+	targetsData, targets, err := c.fetchRole(goCtx, RoleTargets, &Targets{})
+	if err != nil {
+		return err
+	}
+	if err := checkHashes(targetsData, targetsMeta, RoleTargets.filename()); err != nil {
+		return err
+	}
+	tg := targets.(*Targets)
+	if tg.Version != targetsMeta.Version {
+		return fmt.Errorf("Targets metadata version %d doesn't match snapshot (%d)", tg.Version, targetsMeta.Version)
+	}
+	if err := c.checkExpires(tg.Header); err != nil {
+		return err
+	}
+
+	// It all checks out, so save it
+	for _, f := range []struct {
+		role Role
+		data []byte
+	}{{RoleTimestamp, timestampData}, {RoleSnapshot, snapshotData}, {RoleTargets, targetsData}} {
+		if err := c.save(f.role.filename(), f.data); err != nil {
+			return err
+		}
+	}
+	c.timestamp, c.snapshot, c.targets = ts, sn, tg
+	return nil
+}
+
+// fetchRole fetches the metadata for role, and verifies its signatures
+func (c *Client) fetchRole(goCtx context.Context, role Role, v interface{}) ([]byte, interface{}, error) {
+	data, err := c.remote.Fetch(goCtx, role.filename())
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error fetching %s metadata: %s", role, err)
+	}
+	s, err := parseSigned(data, role, v)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := verifySignatures(s, c.root, role); err != nil {
+		return nil, nil, err
+	}
+	return data, v, nil
+}
+
+// updateRoot fetches new versions of the root metadata (N.root.json), which
+// must be signed by the threshold of keys in both the trusted root and the new
+// root, so keys can be rotated
+func (c *Client) updateRoot(goCtx context.Context) error {
+	for i := 0; i < maxRootRotations; i++ {
+		next := c.root.Version + 1
+		name := fmt.Sprintf("%d.%s", next, RoleRoot.filename())
+		data, err := c.remote.Fetch(goCtx, name)
+		if err == ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Error fetching %s: %s", name, err)
+		}
+		var root Root
+		s, err := parseSigned(data, RoleRoot, &root)
+		if err != nil {
+			return err
+		}
+		if err := verifySignatures(s, c.root, RoleRoot); err != nil {
+			return err
+		}
+		if err := verifySignatures(s, &root, RoleRoot); err != nil {
+			return err
+		}
+		if root.Version != next {
+			return fmt.Errorf("%s has version %d", name, root.Version)
+		}
+		c.log.Infof("Rotating to root metadata version %d", root.Version)
+		if err := c.save(RoleRoot.filename(), data); err != nil {
+			return err
+		}
+		c.root = &root
+	}
+	return fmt.Errorf("Too many root rotations")
+}
+
+// Target returns the target with name, from the targets metadata. Refresh must
+// be called first.
+func (c *Client) Target(name string) (*Target, error) {
+	if c.targets == nil {
+		return nil, fmt.Errorf("No targets metadata")
+	}
+	target, ok := c.targets.Targets[name]
+	if !ok {
+		return nil, TargetError{Name: name, Reason: "not in targets metadata"}
+	}
+	return &target, nil
+}
+
+// VerifyUpdate returns an error unless the update asset is a target, with the
+// same digest and version. Refresh must be called first.
+func (c *Client) VerifyUpdate(update updater.Update) error {
+	if update.Asset == nil {
+		return fmt.Errorf("No asset to verify")
+	}
+	name := update.Asset.Name
+	target, err := c.Target(name)
+	if err != nil {
+		return err
+	}
+	digest, ok := target.Hashes["sha256"]
+	if !ok {
+		return TargetError{Name: name, Reason: "no sha256 hash"}
+	}
+	if digest != update.Asset.Digest {
+		return TargetError{Name: name, Reason: "digest doesn't match"}
+	}
+	if target.Custom == nil || target.Custom.Version != update.Version {
+		return TargetError{Name: name, Reason: fmt.Sprintf("not for version %s", update.Version)}
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package tuf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/keybase/go-logging"
+	"github.com/keybase/go-updater"
+	"github.com/keybase/go-updater/test/tufrepo"
+	"github.com/keybase/go-updater/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var log = &logging.Logger{Module: "test"}
+
+// testFetcher fetches metadata files from a map
+type testFetcher map[string][]byte
+
+func (f testFetcher) Fetch(goCtx context.Context, name string) ([]byte, error) {
+	data, ok := f[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
+
+func testDir(t *testing.T) string {
+	dir, err := util.MakeTempDir("TestTUF.", 0700)
+	require.NoError(t, err)
+	t.Cleanup(func() { util.RemoveFileAtPath(dir) })
+	return dir
+}
+
+func testRepo() *tufrepo.Repo {
+	r := tufrepo.New("test", time.Now().Add(24*time.Hour))
+	r.AddTarget("test.zip", []byte("test"), "1.0.1")
+	r.Commit()
+	return r
+}
+
+func testRepoUpdate() updater.Update {
+	return updater.Update{
+		Version: "1.0.1",
+		Asset: &updater.Asset{
+			Name: "test.zip",
+			// sha256 of "test"
+			Digest: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+		},
+	}
+}
+
+func newTestClient(t *testing.T, r *tufrepo.Repo, fetcher Fetcher) (*Client, string) {
+	dir := testDir(t)
+	client, err := NewClient(fetcher, dir, r.InitialRoot(), log)
+	require.NoError(t, err)
+	return client, dir
+}
+
+func TestClientFixture(t *testing.T) {
+	fixtureDir := filepath.Join("..", "test", "tuf")
+	root, err := os.ReadFile(filepath.Join(fixtureDir, "1.root.json"))
+	require.NoError(t, err)
+	client, err := NewClient(NewDirFetcher(fixtureDir), testDir(t), root, log)
+	require.NoError(t, err)
+	err = client.Refresh(context.Background())
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join("..", "test", "update.json"))
+	require.NoError(t, err)
+	var update updater.Update
+	require.NoError(t, json.Unmarshal(data, &update))
+	err = client.VerifyUpdate(update)
+	require.NoError(t, err)
+
+	update.Version = "1.2.4"
+	err = client.VerifyUpdate(update)
+	assert.EqualError(t, err, "Target Test-1.2.3-400+abcdef.zip isn't trusted: not for version 1.2.4")
+
+	update.Asset.Digest = "deadbeef"
+	err = client.VerifyUpdate(update)
+	assert.EqualError(t, err, "Target Test-1.2.3-400+abcdef.zip isn't trusted: digest doesn't match")
+
+	update.Asset.Name = "Other.zip"
+	err = client.VerifyUpdate(update)
+	assert.IsType(t, TargetError{}, err)
+}
+
+func TestClientRefresh(t *testing.T) {
+	r := testRepo()
+	fetcher := testFetcher(r.Files())
+	client, dir := newTestClient(t, r, fetcher)
+
+	err := client.VerifyUpdate(testRepoUpdate())
+	assert.EqualError(t, err, "No targets metadata")
+	err = client.Refresh(context.Background())
+	require.NoError(t, err)
+	err = client.VerifyUpdate(testRepoUpdate())
+	require.NoError(t, err)
+
+	// The trusted metadata is saved
+	for _, name := range []string{"root.json", "timestamp.json", "snapshot.json", "targets.json"} {
+		exists, err := util.FileExists(filepath.Join(dir, name))
+		require.NoError(t, err)
+		assert.True(t, exists, name)
+	}
+}
+
+func TestClientRollback(t *testing.T) {
+	r := testRepo()
+	old := r.Files()
+	r.Commit()
+	client, _ := newTestClient(t, r, testFetcher(r.Files()))
+	err := client.Refresh(context.Background())
+	require.NoError(t, err)
+
+	client.remote = testFetcher(old)
+	err = client.Refresh(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, RollbackError{Role: RoleTimestamp, Version: 2, Trusted: 3}, err)
+}
+
+func TestClientExpired(t *testing.T) {
+	r := testRepo()
+	client, _ := newTestClient(t, r, testFetcher(r.Files()))
+	client.SetNow(func() time.Time { return time.Now().Add(48 * time.Hour) })
+	err := client.Refresh(context.Background())
+	require.Error(t, err)
+	assert.IsType(t, ExpiredError{}, err)
+	assert.Equal(t, RoleRoot, err.(ExpiredError).Role)
+
+	// Only the timestamp expired
+	r.SetExpires(time.Now().Add(-time.Minute))
+	r.Commit()
+	client.SetNow(time.Now)
+	client.remote = testFetcher(r.Files())
+	err = client.Refresh(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, RoleTimestamp, err.(ExpiredError).Role)
+}
+
+func TestClientThreshold(t *testing.T) {
+	r := testRepo()
+	r.SetKeys("targets", 3, 2)
+	r.Commit()
+	client, _ := newTestClient(t, r, testFetcher(r.Files()))
+	err := client.Refresh(context.Background())
+	require.NoError(t, err)
+
+	r.SetSigners("targets", 1)
+	r.Commit()
+	client.remote = testFetcher(r.Files())
+	err = client.Refresh(context.Background())
+	assert.Equal(t, ThresholdError{Role: RoleTargets, Valid: 1, Threshold: 2}, err)
+}
+
+func TestClientTampered(t *testing.T) {
+	r := testRepo()
+	files := testFetcher(r.Files())
+	var s Signed
+	require.NoError(t, json.Unmarshal(files["timestamp.json"], &s))
+	s.Signed = json.RawMessage(fmt.Sprintf(`{"_type":"timestamp","version":100,"expires":%q,"meta":{}}`, time.Now().Add(time.Hour).Format(time.RFC3339)))
+	data, err := json.Marshal(s)
+	require.NoError(t, err)
+	files["timestamp.json"] = data
+
+	client, _ := newTestClient(t, r, files)
+	err = client.Refresh(context.Background())
+	assert.Equal(t, ThresholdError{Role: RoleTimestamp, Valid: 0, Threshold: 1}, err)
+}
+
+func TestClientMixAndMatch(t *testing.T) {
+	r := testRepo()
+	old := r.Files()
+	r.AddTarget("test2.zip", []byte("test2"), "1.0.2")
+	r.Commit()
+	files := testFetcher(r.Files())
+	// New timestamp, with an old snapshot
+	files["snapshot.json"] = old["snapshot.json"]
+	client, _ := newTestClient(t, r, files)
+	err := client.Refresh(context.Background())
+	assert.EqualError(t, err, "snapshot.json doesn't match its sha256 hash")
+}
+
+func TestClientRootRotation(t *testing.T) {
+	r := testRepo()
+	initialRoot := r.InitialRoot()
+	r.SetKeys("root", 2, 2)
+	r.SetKeys("timestamp", 1, 1)
+	r.Commit()
+	client, dir := newTestClient(t, r, testFetcher(r.Files()))
+	err := client.Refresh(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), client.root.Version)
+
+	// The rotated root is trusted from now on
+	client, err = NewClient(testFetcher(r.Files()), dir, initialRoot, log)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), client.root.Version)
+
+	// A new root that isn't signed by the trusted root keys
+	other := tufrepo.New("other", time.Now().Add(time.Hour))
+	other.SetKeys("root", 1, 1)
+	other.SetKeys("root", 1, 1)
+	files := testFetcher(r.Files())
+	files["4.root.json"] = other.Files()["3.root.json"]
+	client.remote = files
+	err = client.Refresh(context.Background())
+	assert.Equal(t, ThresholdError{Role: RoleRoot, Valid: 0, Threshold: 2}, err)
+}
+
+func TestNewClientInvalidRoot(t *testing.T) {
+	_, err := NewClient(testFetcher{}, testDir(t), []byte("invalid"), log)
+	require.Error(t, err)
+
+	r := testRepo()
+	_, err = NewClient(testFetcher{}, testDir(t), r.Files()["targets.json"], log)
+	assert.EqualError(t, err, `Invalid root metadata: type is "targets"`)
+}
+
+// testSource is an update source that returns an update
+type testSource struct {
+	update *updater.Update
+}
+
+func (s testSource) Description() string {
+	return "Test"
+}
+
+func (s testSource) FindUpdate(options updater.UpdateOptions) (*updater.Update, error) {
+	return s.update, nil
+}
+
+func TestUpdateSource(t *testing.T) {
+	r := testRepo()
+	client, _ := newTestClient(t, r, testFetcher(r.Files()))
+	update := testRepoUpdate()
+	source := NewUpdateSource(testSource{update: &update}, client)
+	assert.Equal(t, "Test (TUF)", source.Description())
+	found, err := source.FindUpdate(updater.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, &update, found)
+
+	// An update that isn't signed isn't accepted
+	other := testRepoUpdate()
+	other.Version = "1.0.2"
+	source = NewUpdateSource(testSource{update: &other}, client)
+	found, err = source.FindUpdate(updater.UpdateOptions{})
+	require.Error(t, err)
+	assert.Nil(t, found)
+
+	// An update without an asset is passed through
+	source = NewUpdateSource(testSource{update: &updater.Update{Version: "1.0.2"}}, client)
+	found, err = source.FindUpdate(updater.UpdateOptions{})
+	require.NoError(t, err)
+	assert.NotNil(t, found)
+}
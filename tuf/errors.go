@@ -0,0 +1,53 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package tuf
+
+import (
+	"fmt"
+	"time"
+)
+
+// ThresholdError is returned when metadata doesn't have enough valid
+// signatures
+type ThresholdError struct {
+	Role      Role
+	Valid     int
+	Threshold int
+}
+
+func (e ThresholdError) Error() string {
+	return fmt.Sprintf("%s metadata has %d valid signatures, needs %d", e.Role, e.Valid, e.Threshold)
+}
+
+// ExpiredError is returned when metadata has expired (a freeze attack, or a
+// repository that isn't being maintained)
+type ExpiredError struct {
+	Role    Role
+	Expires time.Time
+}
+
+func (e ExpiredError) Error() string {
+	return fmt.Sprintf("%s metadata expired at %s", e.Role, e.Expires)
+}
+
+// RollbackError is returned when metadata is older than what we trust
+type RollbackError struct {
+	Role    Role
+	Version int64
+	Trusted int64
+}
+
+func (e RollbackError) Error() string {
+	return fmt.Sprintf("%s metadata version %d is older than trusted version %d", e.Role, e.Version, e.Trusted)
+}
+
+// TargetError is returned when an update doesn't match the signed targets
+type TargetError struct {
+	Name   string
+	Reason string
+}
+
+func (e TargetError) Error() string {
+	return fmt.Sprintf("Target %s isn't trusted: %s", e.Name, e.Reason)
+}
@@ -0,0 +1,100 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package tuf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/keybase/go-updater/util"
+)
+
+// MaxMetadataSize is the largest metadata file we fetch, so a repository
+// can't send us endless data
+const MaxMetadataSize = 1024 * 1024
+
+// ErrNotFound is returned by a Fetcher if a metadata file doesn't exist
+var ErrNotFound = errors.New("Not found")
+
+// Fetcher fetches metadata files from a repository
+type Fetcher interface {
+	// Fetch returns the metadata file with name, or ErrNotFound
+	Fetch(goCtx context.Context, name string) ([]byte, error)
+}
+
+// HTTPFetcher fetches metadata files from a URL
+type HTTPFetcher struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPFetcher returns a fetcher for metadata files at baseURL. If client is
+// nil, the default client is used.
+func NewHTTPFetcher(baseURL string, client *http.Client) HTTPFetcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return HTTPFetcher{baseURL: strings.TrimSuffix(baseURL, "/"), client: client}
+}
+
+// Fetch returns the metadata file with name
+func (f HTTPFetcher) Fetch(goCtx context.Context, name string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(goCtx, "GET", f.baseURL+"/"+url.PathEscape(name), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.client.Do(req)
+	defer util.DiscardAndCloseBodyIgnoreError(resp)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Fetching %s returned bad HTTP status %v", name, resp.Status)
+	}
+	return readLimited(resp.Body, name)
+}
+
+// DirFetcher fetches metadata files from a local directory
+type DirFetcher struct {
+	dir string
+}
+
+// NewDirFetcher returns a fetcher for metadata files in dir
+func NewDirFetcher(dir string) DirFetcher {
+	return DirFetcher{dir: dir}
+}
+
+// Fetch returns the metadata file with name
+func (f DirFetcher) Fetch(goCtx context.Context, name string) ([]byte, error) {
+	file, err := os.Open(filepath.Join(f.dir, filepath.Base(name)))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer util.Close(file)
+	return readLimited(file, name)
+}
+
+func readLimited(r io.Reader, name string) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxMetadataSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxMetadataSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, MaxMetadataSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package tuf
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Role is a metadata role
+type Role string
+
+const (
+	// RoleRoot signs the keys and thresholds for all roles
+	RoleRoot Role = "root"
+	// RoleTargets signs the targets (update assets)
+	RoleTargets Role = "targets"
+	// RoleSnapshot signs the version of the targets metadata
+	RoleSnapshot Role = "snapshot"
+	// RoleTimestamp signs the version of the snapshot metadata, and expires
+	// quickly, so clients know they have the latest metadata
+	RoleTimestamp Role = "timestamp"
+)
+
+// KeyTypeEd25519 is the only supported key type
+const KeyTypeEd25519 = "ed25519"
+
+// filename is the name of the metadata file for a role
+func (r Role) filename() string {
+	return string(r) + ".json"
+}
+
+// Signed is a metadata file: the signed metadata, and its signatures. The
+// signatures are of the signed metadata in compact JSON (as encoding/json
+// writes it), so the file can be indented.
+type Signed struct {
+	Signed     json.RawMessage `json:"signed"`
+	Signatures []Signature     `json:"signatures"`
+}
+
+// Signature is an ed25519 signature (hex) of the signed metadata
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// Key is a public key
+type Key struct {
+	Type string `json:"keytype"`
+	// Public is the public key (hex)
+	Public string `json:"public"`
+}
+
+// ID returns the key ID, which is the SHA256 (hex) of the public key, or ""
+// if the public key isn't valid
+func (k Key) ID() string {
+	public, err := hex.DecodeString(k.Public)
+	if err != nil || len(public) != ed25519.PublicKeySize {
+		return ""
+	}
+	return KeyID(ed25519.PublicKey(public))
+}
+
+// KeyID returns the key ID for a public key
+func KeyID(public ed25519.PublicKey) string {
+	sum := sha256.Sum256(public)
+	return hex.EncodeToString(sum[:])
+}
+
+// RoleKeys are the keys for a role, and how many must sign
+type RoleKeys struct {
+	KeyIDs    []string `json:"keyids"`
+	Threshold int      `json:"threshold"`
+}
+
+// Header is common to all metadata
+type Header struct {
+	Type    Role      `json:"_type"`
+	Version int64     `json:"version"`
+	Expires time.Time `json:"expires"`
+}
+
+// Root is the root metadata
+type Root struct {
+	Header
+	Keys  map[string]Key    `json:"keys"`
+	Roles map[Role]RoleKeys `json:"roles"`
+}
+
+// Targets is the targets metadata
+type Targets struct {
+	Header
+	Targets map[string]Target `json:"targets"`
+}
+
+// Target is an update asset
+type Target struct {
+	Length int64             `json:"length"`
+	Hashes map[string]string `json:"hashes"`
+	Custom *TargetCustom     `json:"custom,omitempty"`
+}
+
+// TargetCustom is custom metadata for a target
+type TargetCustom struct {
+	// Version is the update version of the asset
+	Version string `json:"version,omitempty"`
+}
+
+// Snapshot is the snapshot metadata
+type Snapshot struct {
+	Header
+	Meta map[string]FileMeta `json:"meta"`
+}
+
+// Timestamp is the timestamp metadata
+type Timestamp struct {
+	Header
+	Meta map[string]FileMeta `json:"meta"`
+}
+
+// FileMeta describes a metadata file
+type FileMeta struct {
+	Version int64             `json:"version"`
+	Length  int64             `json:"length,omitempty"`
+	Hashes  map[string]string `json:"hashes,omitempty"`
+}
+
+// parseSigned parses a metadata file, and the signed metadata (as role) into v
+func parseSigned(data []byte, role Role, v interface{}) (*Signed, error) {
+	var s Signed
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("Invalid %s metadata: %s", role, err)
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, s.Signed); err != nil {
+		return nil, fmt.Errorf("Invalid %s metadata: %s", role, err)
+	}
+	s.Signed = compact.Bytes()
+	var header Header
+	if err := json.Unmarshal(s.Signed, &header); err != nil {
+		return nil, fmt.Errorf("Invalid %s metadata: %s", role, err)
+	}
+	if header.Type != role {
+		return nil, fmt.Errorf("Invalid %s metadata: type is %q", role, header.Type)
+	}
+	if err := json.Unmarshal(s.Signed, v); err != nil {
+		return nil, fmt.Errorf("Invalid %s metadata: %s", role, err)
+	}
+	return &s, nil
+}
+
+// verifySignatures returns an error unless the signed metadata has valid
+// signatures from at least the threshold of keys for role in root
+func verifySignatures(s *Signed, root *Root, role Role) error {
+	roleKeys, ok := root.Roles[role]
+	if !ok || roleKeys.Threshold < 1 {
+		return fmt.Errorf("No keys for %s", role)
+	}
+	allowed := map[string]bool{}
+	for _, keyID := range roleKeys.KeyIDs {
+		allowed[keyID] = true
+	}
+	valid := map[string]bool{}
+	for _, sig := range s.Signatures {
+		if !allowed[sig.KeyID] || valid[sig.KeyID] {
+			continue
+		}
+		key, ok := root.Keys[sig.KeyID]
+		if !ok || key.Type != KeyTypeEd25519 || key.ID() != sig.KeyID {
+			continue
+		}
+		public, _ := hex.DecodeString(key.Public)
+		signature, err := hex.DecodeString(sig.Sig)
+		if err != nil {
+			continue
+		}
+		if ed25519.Verify(ed25519.PublicKey(public), s.Signed, signature) {
+			valid[sig.KeyID] = true
+		}
+	}
+	if len(valid) < roleKeys.Threshold {
+		return ThresholdError{Role: role, Valid: len(valid), Threshold: roleKeys.Threshold}
+	}
+	return nil
+}
+
+// checkHashes returns an error if data doesn't match the length and hashes
+// (if any) in meta
+func checkHashes(data []byte, meta FileMeta, name string) error {
+	if meta.Length > 0 && int64(len(data)) != meta.Length {
+		return fmt.Errorf("%s has length %d, expected %d", name, len(data), meta.Length)
+	}
+	if expected, ok := meta.Hashes["sha256"]; ok {
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != expected {
+			return fmt.Errorf("%s doesn't match its sha256 hash", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package tuf
+
+import (
+	"context"
+	"sync"
+
+	"github.com/keybase/go-updater"
+)
+
+// UpdateSource is an update source whose updates are only accepted if they
+// match the signed targets metadata
+type UpdateSource struct {
+	source updater.UpdateSource
+	client *Client
+	// mtx serializes refreshes of the client
+	mtx *sync.Mutex
+}
+
+// NewUpdateSource returns an update source which verifies the updates from
+// source with client
+func NewUpdateSource(source updater.UpdateSource, client *Client) UpdateSource {
+	return UpdateSource{source: source, client: client, mtx: &sync.Mutex{}}
+}
+
+// Description returns the description of the source
+func (s UpdateSource) Description() string {
+	return s.source.Description() + " (TUF)"
+}
+
+// FindUpdate returns a verified update for options
+func (s UpdateSource) FindUpdate(options updater.UpdateOptions) (*updater.Update, error) {
+	return s.FindUpdateContext(context.Background(), options)
+}
+
+// FindUpdateContext returns a verified update for options. An update with an
+// asset is an error unless it matches the signed targets metadata.
+func (s UpdateSource) FindUpdateContext(goCtx context.Context, options updater.UpdateOptions) (*updater.Update, error) {
+	var update *updater.Update
+	var err error
+	if source, ok := s.source.(updater.ContextUpdateSource); ok {
+		update, err = source.FindUpdateContext(goCtx, options)
+	} else {
+		update, err = s.source.FindUpdate(options)
+	}
+	if err != nil || update == nil || update.Asset == nil {
+		return update, err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if err := s.client.Refresh(goCtx); err != nil {
+		return nil, err
+	}
+	if err := s.client.VerifyUpdate(*update); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
@@ -3,18 +3,52 @@
 
 package updater
 
-import "time"
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
 
 const DefaultTickDuration = time.Hour
 
+// DefaultMaxBackoff is the longest we wait between checks after errors
+const DefaultMaxBackoff = 24 * time.Hour
+
+// tickJitter is how much (as a fraction of the tick duration) we randomly vary
+// the time between checks, so that clients don't all check at the same time
+const tickJitter = 0.1
+
+// Clock is the time source for the update checker
+type Clock interface {
+	// After waits for the duration to elapse and then sends the current time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
 // UpdateChecker runs updates checks every check duration
 type UpdateChecker struct {
 	updater      *Updater
 	ctx          Context
-	ticker       *time.Ticker
 	log          Log
-	tickDuration time.Duration // tickDuration is the ticker delay
-	count        int           // count is number of times we've checked
+	tickDuration time.Duration // tickDuration is the delay between checks
+	maxBackoff   time.Duration // maxBackoff is the longest delay after errors
+	clock        Clock
+	random       func() float64 // random returns a number in [0, 1), for jitter
+	checkNow     chan struct{}  // checkNow triggers a check, without waiting for the timer
+
+	// mtx protects the fields below, which are used by the checker goroutine
+	mtx      sync.Mutex
+	count    int // count is number of times we've checked
+	failures int // failures is the number of consecutive find or download errors
+	goCtx    context.Context
+	cancel   context.CancelFunc // cancel stops the checker and a check in progress
+	done     chan struct{}      // done is closed when the checker goroutine exits
 }
 
 // NewUpdateChecker creates an update checker
@@ -24,12 +58,30 @@ func NewUpdateChecker(updater *Updater, ctx Context, tickDuration time.Duration,
 		ctx:          ctx,
 		log:          log,
 		tickDuration: tickDuration,
+		maxBackoff:   DefaultMaxBackoff,
+		clock:        realClock{},
+		random:       rand.Float64,
+		goCtx:        context.Background(),
+		checkNow:     make(chan struct{}, 1),
 	}
 }
 
+// SetClock sets the time source (for testing)
+func (u *UpdateChecker) SetClock(clock Clock) {
+	u.clock = clock
+}
+
+// SetMaxBackoff sets the longest delay between checks after errors
+func (u *UpdateChecker) SetMaxBackoff(maxBackoff time.Duration) {
+	u.maxBackoff = maxBackoff
+}
+
 func (u *UpdateChecker) check() error {
+	u.mtx.Lock()
 	u.count++
-	update, err := u.updater.Update(u.ctx)
+	goCtx := u.goCtx
+	u.mtx.Unlock()
+	update, err := u.updater.UpdateContext(goCtx, u.ctx)
 	u.ctx.AfterUpdateCheck(update)
 	return err
 }
@@ -37,43 +89,126 @@ func (u *UpdateChecker) check() error {
 // Check checks for an update.
 func (u *UpdateChecker) Check() {
 	u.updater.config.SetLastUpdateCheckTime()
-	if err := u.check(); err != nil {
+	err := u.check()
+	if err != nil {
 		u.log.Errorf("Error in update: %s", err)
 	}
+	u.recordResult(err)
+}
+
+// recordResult counts consecutive find and download errors, for backoff. A
+// successful check resets the count.
+func (u *UpdateChecker) recordResult(err error) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	if err == nil {
+		u.failures = 0
+		return
+	}
+	if e, ok := err.(Error); ok && (e.errorType == FindError || e.errorType == DownloadError) {
+		u.failures++
+	}
+}
+
+// nextDelay returns how long to wait until the next check. This is the tick
+// duration (with jitter), or after errors, an exponential backoff (with
+// jitter) up to the max backoff.
+func (u *UpdateChecker) nextDelay() time.Duration {
+	u.mtx.Lock()
+	failures := u.failures
+	u.mtx.Unlock()
+	if failures == 0 {
+		jitter := (u.random()*2 - 1) * tickJitter * float64(u.tickDuration)
+		return u.tickDuration + time.Duration(jitter)
+	}
+	backoff := u.tickDuration
+	for i := 0; i < failures && backoff < u.maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > u.maxBackoff {
+		backoff = u.maxBackoff
+	}
+	// Wait between half and all of the backoff
+	return backoff/2 + time.Duration(u.random()*float64(backoff/2))
 }
 
 // Start starts the update checker. Returns false if we are already running.
 func (u *UpdateChecker) Start() bool {
-	if u.ticker != nil {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	if u.cancel != nil {
 		return false
 	}
-	u.ticker = time.NewTicker(u.tickDuration)
+	goCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	u.goCtx, u.cancel, u.done = goCtx, cancel, done
 	go func() {
+		defer close(done)
 		// If we haven't done an update recently, check now.
 		// If there is an error getting the last update time, we don't trigger a
-		// check and let the ticker below trigger it.
+		// check and let the timer below trigger it.
 		if !u.updater.config.IsLastUpdateCheckTimeRecent(u.tickDuration) {
 			u.Check()
 		}
 
-		u.log.Debugf("Starting (ticker %s)", u.tickDuration)
-		for range u.ticker.C {
-			u.log.Debugf("%s", "Checking for update (ticker)")
+		u.log.Debugf("Starting (tick %s)", u.tickDuration)
+		for {
+			delay := u.nextDelay()
+			u.log.Debugf("Next check in %s", delay)
+			select {
+			case <-goCtx.Done():
+				return
+			case <-u.clock.After(delay):
+				u.log.Debugf("%s", "Checking for update (timer)")
+			case <-u.checkNow:
+				u.log.Debugf("%s", "Checking for update (requested)")
+			}
 			u.Check()
 		}
 	}()
 	return true
 }
 
-// Stop stops the update checker
+// CheckNow triggers a check by the running update checker, without waiting for
+// the timer. Returns false if the checker isn't running, or a check was already
+// triggered.
+func (u *UpdateChecker) CheckNow() bool {
+	u.mtx.Lock()
+	running := u.cancel != nil
+	u.mtx.Unlock()
+	if !running {
+		return false
+	}
+	select {
+	case u.checkNow <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop stops the update checker, cancels a check in progress, and waits for
+// the checker to exit. It's still running (so can't be started again) until
+// then.
 func (u *UpdateChecker) Stop() {
-	if u.ticker != nil {
-		u.ticker.Stop()
-		u.ticker = nil
+	u.mtx.Lock()
+	cancel, done := u.cancel, u.done
+	u.mtx.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+	u.mtx.Lock()
+	if u.done == done {
+		u.cancel, u.done = nil, nil
 	}
+	u.mtx.Unlock()
 }
 
 // Count is number of times the check has been called
-func (u UpdateChecker) Count() int {
+func (u *UpdateChecker) Count() int {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
 	return u.count
 }
@@ -5,6 +5,7 @@ package updater
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -35,6 +36,12 @@ func TestUpdateCheckerStart(t *testing.T) {
 	assert.True(t, checker.Count() >= 1)
 
 	checker.Stop()
+	// Stop waits for the checker to exit, so it can be started again
+	count := checker.Count()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, count, checker.Count())
+	require.True(t, checker.Start())
+	checker.Stop()
 }
 
 type testUpdateCheckUI struct {
@@ -101,3 +108,117 @@ func TestUpdateCheckerError(t *testing.T) {
 	err = checker.check()
 	require.Error(t, err)
 }
+
+// testClock sends the delays it's asked to wait for on delays, and fires when
+// the test sends on fire
+type testClock struct {
+	delays chan time.Duration
+	fire   chan time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{delays: make(chan time.Duration), fire: make(chan time.Time)}
+}
+
+func (c *testClock) After(d time.Duration) <-chan time.Time {
+	c.delays <- d
+	return c.fire
+}
+
+// testFailingSource fails to find an update the first failures times
+type testFailingSource struct {
+	sync.Mutex
+	failures int
+}
+
+func (s *testFailingSource) Description() string {
+	return "Test (failing)"
+}
+
+func (s *testFailingSource) FindUpdate(options UpdateOptions) (*Update, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return nil, fmt.Errorf("Test find error")
+	}
+	return nil, nil
+}
+
+func TestUpdateCheckerBackoff(t *testing.T) {
+	updater, err := newTestUpdater(t)
+	require.NoError(t, err)
+	updater.source = &testFailingSource{failures: 3}
+
+	clock := newTestClock()
+	checker := NewUpdateChecker(updater, testUpdateCheckUI{}, time.Hour, testLog)
+	checker.SetClock(clock)
+	checker.SetMaxBackoff(5 * time.Hour)
+	// No jitter (the middle of the range)
+	checker.random = func() float64 { return 0.5 }
+	defer checker.Stop()
+	require.True(t, checker.Start())
+
+	expected := []time.Duration{
+		time.Hour,         // No errors
+		90 * time.Minute,  // 1 error, backoff 2h
+		3 * time.Hour,     // 2 errors, backoff 4h
+		225 * time.Minute, // 3 errors, backoff 8h capped to 5h
+		time.Hour,         // Success resets backoff
+	}
+	for i, delay := range expected {
+		assert.Equal(t, delay, <-clock.delays, "delay %d", i)
+		if i < len(expected)-1 {
+			clock.fire <- time.Now()
+		}
+	}
+	checker.Stop()
+	assert.Equal(t, 4, checker.Count())
+}
+
+func TestUpdateCheckerJitter(t *testing.T) {
+	updater, err := newTestUpdater(t)
+	require.NoError(t, err)
+	checker := NewUpdateChecker(updater, testUpdateCheckUI{}, time.Hour, testLog)
+
+	checker.random = func() float64 { return 0 }
+	assert.Equal(t, 54*time.Minute, checker.nextDelay())
+	checker.random = func() float64 { return 0.75 }
+	assert.Equal(t, 63*time.Minute, checker.nextDelay())
+
+	checker.failures = 1
+	checker.random = func() float64 { return 0 }
+	assert.Equal(t, time.Hour, checker.nextDelay())
+	checker.random = func() float64 { return 0.75 }
+	assert.Equal(t, 105*time.Minute, checker.nextDelay())
+
+	// Errors other than find or download don't back off
+	checker.failures = 0
+	checker.recordResult(verifyErr(fmt.Errorf("Test verify error")))
+	assert.Equal(t, 0, checker.failures)
+	checker.recordResult(downloadErr(fmt.Errorf("Test download error")))
+	assert.Equal(t, 1, checker.failures)
+	checker.recordResult(nil)
+	assert.Equal(t, 0, checker.failures)
+}
+
+func TestUpdateCheckerCheckNow(t *testing.T) {
+	updater, err := newTestUpdater(t)
+	require.NoError(t, err)
+	updater.source = &testFailingSource{}
+
+	clock := newTestClock()
+	checker := NewUpdateChecker(updater, testUpdateCheckUI{}, time.Hour, testLog)
+	checker.SetClock(clock)
+	assert.False(t, checker.CheckNow())
+	defer checker.Stop()
+	require.True(t, checker.Start())
+
+	<-clock.delays
+	count := checker.Count()
+	assert.True(t, checker.CheckNow())
+	// Waiting for the next check means the requested check is done
+	<-clock.delays
+	checker.Stop()
+	assert.Equal(t, count+1, checker.Count())
+}
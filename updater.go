@@ -4,15 +4,16 @@
 package updater
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
-	"strings"
 	"time"
 
+	"github.com/keybase/go-updater/delta"
 	"github.com/keybase/go-updater/util"
 )
 
@@ -26,6 +27,14 @@ type Updater struct {
 	log          Log
 	guiBusyCount int
 	tickDuration time.Duration
+	journalPath  string
+	cacheDir     string
+	cacheLimit   int64
+	lockTimeout  time.Duration
+	lockFilePath string
+	policy       Policy
+	historyPath  string
+	historyLimit int64
 }
 
 // UpdateSource defines where the updater can find updates
@@ -36,6 +45,15 @@ type UpdateSource interface {
 	FindUpdate(options UpdateOptions) (*Update, error)
 }
 
+// ContextUpdateSource is an UpdateSource that can be canceled. If a source
+// implements it, FindUpdateContext is used instead of FindUpdate.
+type ContextUpdateSource interface {
+	UpdateSource
+	// FindUpdateContext finds an update given options, and stops if the
+	// context is done
+	FindUpdateContext(goCtx context.Context, options UpdateOptions) (*Update, error)
+}
+
 // Context defines options, UI and hooks for the updater.
 // This is where you can define custom behavior specific to your apps.
 type Context interface {
@@ -46,15 +64,59 @@ type Context interface {
 	BeforeApply(update Update) error
 	Apply(update Update, options UpdateOptions, tmpDir string) error
 	AfterApply(update Update) error
-	ReportError(err error, update *Update, options UpdateOptions)
-	ReportAction(updatePromptResponse UpdatePromptResponse, update *Update, options UpdateOptions)
-	ReportSuccess(update *Update, options UpdateOptions)
+	Reporter
 	AfterUpdateCheck(update *Update)
 	GetAppStatePath() string
 	IsCheckCommand() bool
 	DeepClean()
 }
 
+// Reporter reports update events, for example to an API server. Reporting
+// shouldn't fail an update, so errors reporting are logged (not returned).
+type Reporter interface {
+	// ReportError reports an update error
+	ReportError(err error, update *Update, options UpdateOptions)
+	// ReportAction reports the action chosen at the update prompt
+	ReportAction(updatePromptResponse UpdatePromptResponse, update *Update, options UpdateOptions)
+	// ReportSuccess reports that an update was applied
+	ReportSuccess(update *Update, options UpdateOptions)
+}
+
+// HealthChecker is an optional interface for a Context. If the Context
+// implements it, the updater checks the install after an update is applied and
+// restores the previous install (if it was backed up) when the check fails.
+type HealthChecker interface {
+	// HealthCheck returns an error if the applied update isn't working
+	HealthCheck(update Update, options UpdateOptions) error
+	// AfterRollback is called after the previous install was restored
+	AfterRollback(update Update, options UpdateOptions) error
+}
+
+// DeltaBaser is an optional interface for a Context, for delta updates. It
+// returns the path to the installed file or archive that a delta applies to,
+// like the asset that was last installed (see FindInstalledAsset). If not
+// implemented, the options DestinationPath is used.
+type DeltaBaser interface {
+	DeltaBasePath(update Update, options UpdateOptions) string
+}
+
+// ContextBeforeApplier is an optional interface for a Context. If the Context
+// implements it, BeforeApplyContext is used instead of BeforeApply, so that a
+// prompt (or command) it runs stops if the context is done.
+type ContextBeforeApplier interface {
+	BeforeApplyContext(goCtx context.Context, update Update) error
+}
+
+// ManifestVerifier is an optional interface for a Context. If the Context
+// implements it, the update found (the whole manifest, not just the asset) is
+// verified before the updater uses it, so fields like the version can't be
+// changed. See Update.Signature.
+type ManifestVerifier interface {
+	// VerifyManifest returns an error if the update isn't signed (or is
+	// required to be, and isn't)
+	VerifyManifest(update Update) error
+}
+
 // Config defines configuration for the Updater
 type Config interface {
 	GetUpdateAuto() (bool, bool)
@@ -95,19 +157,35 @@ func (u *Updater) SetTickDuration(dur time.Duration) {
 
 // Update checks, downloads and performs an update
 func (u *Updater) Update(ctx Context) (*Update, error) {
+	return u.UpdateContext(context.Background(), ctx)
+}
+
+// UpdateContext checks, downloads and performs an update. If goCtx is done
+// before the update is applied, the update stops with a cancel error. Once the
+// update is being applied, it isn't canceled.
+func (u *Updater) UpdateContext(goCtx context.Context, ctx Context) (*Update, error) {
 	options := ctx.UpdateOptions()
-	update, err := u.update(ctx, options)
+	lock, err := u.lock(goCtx)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.unlock()
+	u.recoverOnStart(ctx, options)
+	a := newAttempt(options)
+	update, err := u.update(goCtx, ctx, options, a)
 	report(ctx, err, update, options)
+	u.recordCheck(update, err)
+	u.recordAttempt(a, update, err)
 	return update, err
 }
 
 // update returns the update received, and an error if the update was not
 // performed. The error with be of type Error. The error may be due to the user
 // (or system) canceling an update, in which case error.IsCancel() will be true.
-func (u *Updater) update(ctx Context, options UpdateOptions) (*Update, error) {
-	update, err := u.checkForUpdate(ctx, options)
+func (u *Updater) update(goCtx context.Context, ctx Context, options UpdateOptions, a *attempt) (*Update, error) {
+	update, err := u.checkForUpdate(goCtx, ctx, options)
 	if err != nil {
-		return nil, findErr(err)
+		return nil, canceledOr(goCtx, err)
 	}
 	if update == nil || !update.NeedUpdate {
 		// No update available
@@ -115,29 +193,57 @@ func (u *Updater) update(ctx Context, options UpdateOptions) (*Update, error) {
 	}
 	u.log.Infof("Got update with version: %s", update.Version)
 
+	if err := u.checkVersion(*update, options); err != nil {
+		return update, err
+	}
+
 	if update.missingAsset() {
 		return update, nil
 	}
 
+	if u.isSnoozed(*update, options) {
+		// Nothing to do until the snooze is over
+		return nil, nil
+	}
+
 	if err := u.CleanupPreviousUpdates(); err != nil {
 		u.log.Infof("Error cleaning up previous downloads: %v", err)
 	}
 
 	tmpDir := u.tempDir()
 	defer u.Cleanup(tmpDir)
-	if err := u.downloadAsset(update.Asset, tmpDir, options); err != nil {
-		return update, downloadErr(err)
+	// We may have downloaded it before, outside the maintenance window
+	cached := u.findCachedUpdate(update)
+	if !cached {
+		a.stage(HistoryStageDownload)
+		n, err := u.downloadUpdate(goCtx, ctx, update, tmpDir, options)
+		a.downloaded(n)
+		if err != nil {
+			return update, canceledOr(goCtx, downloadErr(err))
+		}
 	}
 
+	if !u.inMaintenanceWindow(ctx, *update) {
+		// Keep the download, to apply when the window opens
+		if !cached {
+			if err := u.cacheAsset(update.Asset); err != nil {
+				u.log.Warningf("Error caching update: %s", err)
+			}
+		}
+		return nil, nil
+	}
+	u.journal(JournalDownloaded, *update, options, tmpDir)
+
 	err = ctx.BeforeUpdatePrompt(*update, options)
 	if err != nil {
 		return update, err
 	}
 
 	// Prompt for update
-	updatePromptResponse, err := u.promptForUpdateAction(ctx, *update, options)
+	a.stage(HistoryStagePrompt)
+	updatePromptResponse, err := u.promptForUpdateAction(goCtx, ctx, *update, options)
 	if err != nil {
-		return update, promptErr(err)
+		return update, canceledOr(goCtx, promptErr(err))
 	}
 	switch updatePromptResponse.Action {
 	case UpdateActionApply:
@@ -146,6 +252,7 @@ func (u *Updater) update(ctx Context, options UpdateOptions) (*Update, error) {
 		ctx.ReportAction(updatePromptResponse, update, options)
 	case UpdateActionSnooze:
 		ctx.ReportAction(updatePromptResponse, update, options)
+		u.snooze(*update, updatePromptResponse)
 		return update, CancelErr(fmt.Errorf("Snoozed update"))
 	case UpdateActionCancel:
 		ctx.ReportAction(updatePromptResponse, update, options)
@@ -169,32 +276,60 @@ func (u *Updater) update(ctx Context, options UpdateOptions) (*Update, error) {
 		}
 	}
 
+	a.stage(HistoryStageVerify)
 	u.log.Infof("Verify asset: %s", update.Asset.LocalPath)
 	if err := ctx.Verify(*update); err != nil {
+		u.removeCachedAsset(update.Asset)
 		return update, verifyErr(err)
 	}
+	u.journal(JournalVerified, *update, options, tmpDir)
 
-	if err := u.apply(ctx, *update, options, tmpDir); err != nil {
+	if err := goCtx.Err(); err != nil {
+		return update, CancelErr(err)
+	}
+	a.stage(HistoryStageApply)
+	if err := u.apply(goCtx, ctx, *update, options, tmpDir); err != nil {
 		return update, err
 	}
+	a.stage(HistoryStageDone)
+	u.keepInstalledAsset(update.Asset)
+	u.removeCachedAsset(update.Asset)
 
 	return update, nil
 }
 
+// ApplyDownloaded applies a previously downloaded update (see CheckAndDownload)
 func (u *Updater) ApplyDownloaded(ctx Context) (bool, error) {
+	return u.ApplyDownloadedContext(context.Background(), ctx)
+}
+
+// ApplyDownloadedContext applies a previously downloaded update. If goCtx is
+// done before the update is applied, it stops with a cancel error.
+func (u *Updater) ApplyDownloadedContext(goCtx context.Context, ctx Context) (bool, error) {
 	options := ctx.UpdateOptions()
+	lock, err := u.lock(goCtx)
+	if err != nil {
+		return false, err
+	}
+	defer lock.unlock()
+	u.recoverOnStart(ctx, options)
 
 	// 1. check with the api server again for the latest update to be sure that a
 	// new update has not come out since our last call to CheckAndDownload
 	u.log.Infof("Attempting to apply previously downloaded update")
-	update, err := u.checkForUpdate(ctx, options)
+	a := newAttempt(options)
+	update, err := u.checkForUpdate(goCtx, ctx, options)
 	if err != nil {
-		return false, findErr(err)
+		err = canceledOr(goCtx, err)
+		reportUnverified(ctx, err, options)
+		u.recordAttempt(a, nil, err)
+		return false, err
 	}
 
 	// Only report apply success/failure
-	applied, err := u.applyDownloaded(ctx, update, options)
+	applied, err := u.applyDownloaded(goCtx, ctx, update, options, a)
 	defer report(ctx, err, update, options)
+	u.recordAttempt(a, update, err)
 	if err != nil {
 		return false, err
 	}
@@ -204,23 +339,28 @@ func (u *Updater) ApplyDownloaded(ctx Context) (bool, error) {
 
 // ApplyDownloaded will look for an previously downloaded update and attempt to apply it without prompting.
 // CheckAndDownload must be called first so that we have a download asset available to apply.
-func (u *Updater) applyDownloaded(ctx Context, update *Update, options UpdateOptions) (applied bool, err error) {
+func (u *Updater) applyDownloaded(goCtx context.Context, ctx Context, update *Update, options UpdateOptions, a *attempt) (applied bool, err error) {
 	if update == nil || !update.NeedUpdate {
 		return false, fmt.Errorf("No previously downloaded update to apply since client is update to date")
 	}
 	u.log.Infof("Got update with version: %s", update.Version)
 
+	if err := u.checkVersion(*update, options); err != nil {
+		return false, err
+	}
+
 	if update.missingAsset() {
 		return false, fmt.Errorf("Update contained no asset to apply. Update version: %s", update.Version)
 	}
 
 	// 2. check the disk via FindDownloadedAsset. Compare our API result to this
 	// result. If the downloaded update is stale, clear it and start over.
-	downloadedAssetPath, err := u.FindDownloadedAsset(update.Asset.Name)
+	downloadedAssetPath, err := u.FindDownloadedAsset(*update.Asset)
 	if err != nil {
 		return false, err
 	}
 	defer func() {
+		u.removeCachedAsset(update.Asset)
 		if err := u.CleanupPreviousUpdates(); err != nil {
 			u.log.Infof("Error cleaning up previous downloads: %v", err)
 		}
@@ -231,6 +371,7 @@ func (u *Updater) applyDownloaded(ctx Context, update *Update, options UpdateOpt
 	update.Asset.LocalPath = downloadedAssetPath
 
 	// 3. otherwise use the update on disk and apply it.
+	a.stage(HistoryStageVerify)
 	if err = util.CheckDigest(update.Asset.Digest, downloadedAssetPath, u.log); err != nil {
 		return false, verifyErr(err)
 	}
@@ -239,37 +380,111 @@ func (u *Updater) applyDownloaded(ctx Context, update *Update, options UpdateOpt
 		return false, verifyErr(err)
 	}
 
-	tmpDir := os.TempDir()
-	if err := u.apply(ctx, *update, options, tmpDir); err != nil {
+	if err := goCtx.Err(); err != nil {
+		return false, CancelErr(err)
+	}
+	tmpDir := u.tempDir()
+	defer u.Cleanup(tmpDir)
+	u.journal(JournalVerified, *update, options, tmpDir)
+	a.stage(HistoryStageApply)
+	if err := u.apply(goCtx, ctx, *update, options, tmpDir); err != nil {
 		return false, err
 	}
+	a.stage(HistoryStageDone)
+	u.keepInstalledAsset(update.Asset)
 
 	return true, nil
 }
 
-func (u *Updater) apply(ctx Context, update Update, options UpdateOptions, tmpDir string) error {
+func (u *Updater) apply(goCtx context.Context, ctx Context, update Update, options UpdateOptions, tmpDir string) error {
 	u.log.Info("Before apply")
-	if err := ctx.BeforeApply(update); err != nil {
-		return applyErr(err)
+	if err := u.beforeApply(goCtx, ctx, update); err != nil {
+		return canceledOr(goCtx, applyErr(err))
 	}
 
 	u.log.Info("Applying update")
+	u.journal(JournalApplying, update, options, tmpDir)
 	if err := ctx.Apply(update, options, tmpDir); err != nil {
-		u.log.Info("Apply error: %v", err)
-		return applyErr(err)
+		u.log.Warningf("Apply error: %s", err)
+		// The previous install may have been moved to tmpDir already. The
+		// journal stays at applying until it's restored.
+		return u.rollback(ctx, update, options, tmpDir, err)
 	}
+	u.journal(JournalApplied, update, options, tmpDir)
 
 	u.log.Info("After apply")
 	if err := ctx.AfterApply(update); err != nil {
-		return applyErr(err)
+		return u.rollback(ctx, update, options, tmpDir, err)
 	}
 
+	if healthChecker, ok := ctx.(HealthChecker); ok {
+		u.log.Info("Health check")
+		if err := healthChecker.HealthCheck(update, options); err != nil {
+			u.log.Warningf("Health check failed: %s", err)
+			return u.rollback(ctx, update, options, tmpDir, fmt.Errorf("Health check failed: %s", err))
+		}
+	}
+
+	u.journal(JournalCommitted, update, options, tmpDir)
 	return nil
 }
 
-// downloadAsset will download the update to a temporary path (if not cached),
-// check the digest, and set the LocalPath property on the asset.
-func (u *Updater) downloadAsset(asset *Asset, tmpDir string, options UpdateOptions) error {
+// beforeApply calls the Context BeforeApply, or BeforeApplyContext if it
+// implements ContextBeforeApplier
+func (u *Updater) beforeApply(goCtx context.Context, ctx Context, update Update) error {
+	if beforeApplier, ok := ctx.(ContextBeforeApplier); ok {
+		return beforeApplier.BeforeApplyContext(goCtx, update)
+	}
+	return ctx.BeforeApply(update)
+}
+
+// backupPath is where the existing install is moved to when an update is
+// applied (see util.MoveFile), or "" if there is no destination.
+func backupPath(options UpdateOptions, tmpDir string) string {
+	if options.DestinationPath == "" || tmpDir == "" {
+		return ""
+	}
+	return filepath.Join(tmpDir, filepath.Base(options.DestinationPath))
+}
+
+// rollback restores the install that was replaced by the update, if a backup
+// of it is in tmpDir. If there is nothing to restore, the apply error is
+// returned, otherwise a rollback error.
+//
+// If the restore fails, the journal is left at applying, which keeps tmpDir
+// (see Cleanup), so Recover can try again.
+func (u *Updater) rollback(ctx Context, update Update, options UpdateOptions, tmpDir string, applyError error) error {
+	backup := backupPath(options, tmpDir)
+	if backup == "" {
+		u.clearJournal()
+		return applyErr(applyError)
+	}
+	if exists, err := util.FileExists(backup); err != nil || !exists {
+		u.log.Warningf("No previous install to restore at %s", backup)
+		u.clearJournal()
+		return applyErr(applyError)
+	}
+
+	u.log.Warningf("Restoring previous install from %s", backup)
+	if err := util.MoveFile(backup, options.DestinationPath, "", u.log); err != nil {
+		u.journal(JournalApplying, update, options, tmpDir)
+		return rollbackErr(fmt.Errorf("%s; Error restoring previous install (will retry): %s", applyError, err))
+	}
+	u.clearJournal()
+
+	if healthChecker, ok := ctx.(HealthChecker); ok {
+		if err := healthChecker.AfterRollback(update, options); err != nil {
+			u.log.Warningf("Error after rollback: %s", err)
+		}
+	}
+	return rollbackErr(fmt.Errorf("%s; Restored previous install", applyError))
+}
+
+// downloadAsset will download the update to the cache (or to tmpDir, if we
+// can't use the cache), check the digest, and set the LocalPath property on
+// the asset. A partial download is kept in the cache, so it can be resumed by
+// the next attempt.
+func (u *Updater) downloadAsset(goCtx context.Context, asset *Asset, tmpDir string, options UpdateOptions) error {
 	if asset == nil {
 		return fmt.Errorf("No asset to download")
 	}
@@ -280,9 +495,12 @@ func (u *Updater) downloadAsset(asset *Asset, tmpDir string, options UpdateOptio
 		Log:           u.log,
 	}
 
-	downloadPath := filepath.Join(tmpDir, asset.Name)
-	// If asset had a file extension, lets add it back on
-	if err := util.DownloadURL(asset.URL, downloadPath, downloadOptions); err != nil {
+	downloadPath, err := u.cacheDownloadPath(*asset)
+	if err != nil {
+		u.log.Warningf("Unable to download to cache: %s", err)
+		downloadPath = filepath.Join(tmpDir, asset.Name)
+	}
+	if err := util.DownloadURLContext(goCtx, asset.URL, downloadPath, downloadOptions); err != nil {
 		return err
 	}
 
@@ -290,21 +508,123 @@ func (u *Updater) downloadAsset(asset *Asset, tmpDir string, options UpdateOptio
 	return nil
 }
 
+// downloadUpdate downloads the update asset to tmpDir, using the update delta
+// if there is one for what is installed, otherwise the full asset. It returns
+// the number of bytes downloaded.
+func (u *Updater) downloadUpdate(goCtx context.Context, ctx Context, update *Update, tmpDir string, options UpdateOptions) (int64, error) {
+	var downloaded int64
+	if update.Delta != nil && update.Asset != nil {
+		n, err := u.downloadDelta(goCtx, ctx, update, tmpDir, options)
+		downloaded += n
+		if err == nil {
+			return downloaded, nil
+		}
+		if goCtx.Err() != nil {
+			return downloaded, err
+		}
+		u.log.Warningf("Unable to update from delta, downloading full asset: %s", err)
+	}
+	if err := u.downloadAsset(goCtx, update.Asset, tmpDir, options); err != nil {
+		return downloaded, err
+	}
+	return downloaded + fileSize(update.Asset.LocalPath), nil
+}
+
+// fileSize returns the size of the file at path, or 0 if we can't stat it
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// downloadDelta downloads the update delta (patch) and applies it to the
+// installed base, which reconstructs the full asset in tmpDir. The
+// reconstructed asset must match the full asset digest. It returns the size of
+// the delta downloaded.
+func (u *Updater) downloadDelta(goCtx context.Context, ctx Context, update *Update, tmpDir string, options UpdateOptions) (int64, error) {
+	d := update.Delta
+	if d.BaseVersion != options.Version {
+		return 0, fmt.Errorf("Delta is for version %s, not %s", d.BaseVersion, options.Version)
+	}
+	basePath := options.DestinationPath
+	if deltaBaser, ok := ctx.(DeltaBaser); ok {
+		basePath = deltaBaser.DeltaBasePath(*update, options)
+	}
+	if basePath == "" {
+		return 0, fmt.Errorf("No delta base")
+	}
+	if err := util.CheckDigest(d.BaseDigest, basePath, u.log); err != nil {
+		return 0, fmt.Errorf("Delta base mismatch: %s", err)
+	}
+
+	patchPath := filepath.Join(tmpDir, update.Asset.Name+".delta")
+	defer util.RemoveFileAtPath(patchPath)
+	downloadOptions := util.DownloadURLOptions{
+		Digest:        d.Digest,
+		RequireDigest: true,
+		Log:           u.log,
+	}
+	if err := util.DownloadURLContext(goCtx, d.URL, patchPath, downloadOptions); err != nil {
+		return 0, err
+	}
+	downloaded := fileSize(patchPath)
+
+	assetPath := filepath.Join(tmpDir, update.Asset.Name)
+	u.log.Infof("Applying delta %s to %s", patchPath, basePath)
+	if err := delta.PatchFile(basePath, patchPath, assetPath); err != nil {
+		return downloaded, err
+	}
+	if err := util.CheckDigest(update.Asset.Digest, assetPath, u.log); err != nil {
+		util.RemoveFileAtPath(assetPath)
+		return downloaded, err
+	}
+
+	update.Asset.LocalPath = assetPath
+	return downloaded, nil
+}
+
 // checkForUpdate checks a update source (like a remote API) for an update.
-// It may set an InstallID, if the server tells us to.
-func (u *Updater) checkForUpdate(ctx Context, options UpdateOptions) (*Update, error) {
+// It may set an InstallID, if the server tells us to. If the Context is a
+// ManifestVerifier, the update is verified before we use any of it, and it
+// must be fresh (see checkFresh). The error will be of type Error.
+func (u *Updater) checkForUpdate(goCtx context.Context, ctx Context, options UpdateOptions) (*Update, error) {
 	u.log.Infof("Checking for update, current version is %s", options.Version)
 	u.log.Infof("Using updater source: %s", u.source.Description())
 	u.log.Debugf("Using options: %#v", options)
 
-	update, findErr := u.source.FindUpdate(options)
+	var update *Update
+	var findErr error
+	if source, ok := u.source.(ContextUpdateSource); ok {
+		update, findErr = source.FindUpdateContext(goCtx, options)
+	} else {
+		update, findErr = u.source.FindUpdate(options)
+	}
 	if findErr != nil {
-		return nil, findErr
+		return nil, NewError(FindError, findErr)
 	}
 	if update == nil {
 		return nil, nil
 	}
 
+	// The manifest is verified if it has a signature, and the signature is valid
+	verified := false
+	if verifier, ok := ctx.(ManifestVerifier); ok {
+		if err := verifier.VerifyManifest(*update); err != nil {
+			return nil, verifyErr(fmt.Errorf("Invalid update manifest: %s", err))
+		}
+		verified = update.Signature != ""
+	}
+	if verified {
+		if err := checkManifestTarget(*update, options); err != nil {
+			return nil, verifyErr(fmt.Errorf("Invalid update manifest: %s", err))
+		}
+	}
+	if err := u.checkFresh(*update, options, verified); err != nil {
+		return nil, err
+	}
+
 	// Save InstallID if we received one
 	if update.InstallID != "" && u.config.GetInstallID() != update.InstallID {
 		u.log.Debugf("Saving install ID: %s", update.InstallID)
@@ -314,33 +634,96 @@ func (u *Updater) checkForUpdate(ctx Context, options UpdateOptions) (*Update, e
 		}
 	}
 
+	// Out of a staged rollout, we don't need the update (yet)
+	if update.NeedUpdate && !u.inRollout(*update, options) {
+		update.NeedUpdate = false
+	}
+
 	return update, nil
 }
 
 // NeedUpdate returns true if we are out-of-date.
 func (u *Updater) NeedUpdate(ctx Context) (upToDate bool, err error) {
-	update, err := u.checkForUpdate(ctx, ctx.UpdateOptions())
+	options := ctx.UpdateOptions()
+	update, err := u.checkForUpdate(context.Background(), ctx, options)
 	if err != nil {
 		return false, err
 	}
-	return update.NeedUpdate, nil
+	if update == nil || !update.NeedUpdate {
+		return false, nil
+	}
+	if err := u.checkVersion(*update, options); err != nil {
+		u.log.Warningf("%s", err)
+		return false, nil
+	}
+	return true, nil
 }
 
+// checkVersion returns a downgrade error if the update isn't newer than the
+// current version, or if either version is invalid, unless we are forcing the
+// update. A broken install (with a current version we can't parse) needs to
+// be forced, otherwise downgrade protection would be off for it.
+func (u *Updater) checkVersion(update Update, options UpdateOptions) error {
+	var err error
+	if !util.SemverValid(options.Version) {
+		u.log.Warningf("Unable to parse current version %q", options.Version)
+		err = downgradeErr(fmt.Errorf("Invalid current version %q", options.Version))
+	} else if compare, compareErr := util.SemverCompare(update.Version, options.Version); compareErr != nil {
+		err = downgradeErr(fmt.Errorf("Invalid update version %q: %s", update.Version, compareErr))
+	} else if compare <= 0 {
+		err = downgradeErr(fmt.Errorf("Update version %s is not newer than current version %s", update.Version, options.Version))
+	}
+	if err != nil && options.Force {
+		u.log.Warningf("Forcing update, ignoring: %s", err)
+		return nil
+	}
+	return err
+}
+
+// CheckAndDownload checks for an update and downloads it (if not already
+// downloaded), so it can be applied later with ApplyDownloaded
 func (u *Updater) CheckAndDownload(ctx Context) (updateAvailable, updateWasDownloaded bool, err error) {
+	return u.CheckAndDownloadContext(context.Background(), ctx)
+}
+
+// CheckAndDownloadContext checks for an update and downloads it. If goCtx is
+// done, it stops with a cancel error.
+func (u *Updater) CheckAndDownloadContext(goCtx context.Context, ctx Context) (updateAvailable, updateWasDownloaded bool, err error) {
 	options := ctx.UpdateOptions()
-	update, err := u.checkForUpdate(ctx, options)
+	lock, err := u.lock(goCtx)
 	if err != nil {
 		return false, false, err
 	}
+	defer lock.unlock()
+	u.recoverOnStart(ctx, options)
+	a := newAttempt(options)
+	update, err := u.checkForUpdate(goCtx, ctx, options)
+	if err != nil {
+		err = canceledOr(goCtx, err)
+		reportUnverified(ctx, err, options)
+		return false, false, err
+	}
+	defer func() {
+		u.recordAttempt(a, update, err)
+	}()
 
-	if !update.NeedUpdate || update.missingAsset() {
+	if update == nil || !update.NeedUpdate {
+		return false, false, nil
+	}
+	if err := u.checkVersion(*update, options); err != nil {
+		return false, false, err
+	}
+	if update.missingAsset() {
 		return false, false, nil
 	}
 
 	var tmpDir string
+	// A partial download is kept, to resume it next time
+	keepDownload := false
 	defer func() {
 		// If anything in this process errors cleanup the downloaded asset
-		if err != nil {
+		if err != nil && !keepDownload {
+			u.removeCachedAsset(update.Asset)
 			if err := u.CleanupPreviousUpdates(); err != nil {
 				u.log.Infof("Error cleaning up previous downloads: %v", err)
 			}
@@ -350,12 +733,22 @@ func (u *Updater) CheckAndDownload(ctx Context) (updateAvailable, updateWasDownl
 		}
 	}()
 	var digestChecked bool
-	downloadedAssetPath, err := u.FindDownloadedAsset(update.Asset.Name)
+	downloadedAssetPath, err := u.FindDownloadedAsset(*update.Asset)
 	if downloadedAssetPath == "" || err != nil {
+		if err != nil {
+			u.log.Warningf("Error finding existing download asset: %s", err)
+		}
 		u.log.Infof("Could not find existing download asset for version: %s. Downloading new asset.", update.Version)
 		tmpDir = u.tempDir()
+		a.stage(HistoryStageDownload)
 		// This will set update.Asset.LocalPath
-		if err := u.downloadAsset(update.Asset, tmpDir, options); err != nil {
+		n, err := u.downloadUpdate(goCtx, ctx, update, tmpDir, options)
+		a.downloaded(n)
+		if err != nil {
+			keepDownload = true
+			return false, false, canceledOr(goCtx, downloadErr(err))
+		}
+		if err := u.cacheAsset(update.Asset); err != nil {
 			return false, false, downloadErr(err)
 		}
 		updateWasDownloaded = true
@@ -365,6 +758,7 @@ func (u *Updater) CheckAndDownload(ctx Context) (updateAvailable, updateWasDownl
 	// Verify depends on LocalPath being set to the downloaded asset
 	update.Asset.LocalPath = downloadedAssetPath
 
+	a.stage(HistoryStageVerify)
 	u.log.Infof("Verify asset: %s", downloadedAssetPath)
 	if err := ctx.Verify(*update); err != nil {
 		return false, false, verifyErr(err)
@@ -380,13 +774,15 @@ func (u *Updater) CheckAndDownload(ctx Context) (updateAvailable, updateWasDownl
 }
 
 // promptForUpdateAction prompts the user for permission to apply an update
-func (u *Updater) promptForUpdateAction(ctx Context, update Update, options UpdateOptions) (UpdatePromptResponse, error) {
+func (u *Updater) promptForUpdateAction(goCtx context.Context, ctx Context, update Update, options UpdateOptions) (UpdatePromptResponse, error) {
 	u.log.Debug("Prompt for update")
 
 	auto, autoSet := u.config.GetUpdateAuto()
 	autoOverride := u.config.GetUpdateAutoOverride()
 	u.log.Debugf("Auto update: %s (set=%s autoOverride=%s)", strconv.FormatBool(auto), strconv.FormatBool(autoSet), strconv.FormatBool(autoOverride))
-	if auto && !autoOverride {
+	action := u.policy.Decide(update, auto && !autoOverride, time.Now())
+	u.log.Debugf("Policy action for update type %d: %s", update.Type, action)
+	if action == PolicyActionApply {
 		if !ctx.IsCheckCommand() {
 			// If there's an error getting active status, we'll just update
 			isActive, err := u.checkUserActive(ctx)
@@ -403,7 +799,13 @@ func (u *Updater) promptForUpdateAction(ctx Context, update Update, options Upda
 	// If auto update never set, default to true
 	autoUpdate := auto || !autoSet
 	promptOptions := UpdatePromptOptions{AutoUpdate: autoUpdate}
-	updatePromptResponse, err := updateUI.UpdatePrompt(update, options, promptOptions)
+	var updatePromptResponse *UpdatePromptResponse
+	var err error
+	if contextUI, ok := updateUI.(ContextUpdateUI); ok {
+		updatePromptResponse, err = contextUI.UpdatePromptContext(goCtx, update, options, promptOptions)
+	} else {
+		updatePromptResponse, err = updateUI.UpdatePrompt(update, options, promptOptions)
+	}
 	if err != nil {
 		return UpdatePromptResponse{UpdateActionError, false, 0}, err
 	}
@@ -456,11 +858,20 @@ func (u *Updater) checkUserActive(ctx Context) (bool, error) {
 	return isActive, nil
 }
 
+// canceledOr returns a cancel error if goCtx is done (canceled or past its
+// deadline), otherwise err
+func canceledOr(goCtx context.Context, err error) error {
+	if ctxErr := goCtx.Err(); ctxErr != nil {
+		return CancelErr(ctxErr)
+	}
+	return err
+}
+
 func report(ctx Context, err error, update *Update, options UpdateOptions) {
 	if err != nil {
-		// Don't report cancels or GUI busy
+		// Don't report cancels, GUI busy or another updater running
 		if e, ok := err.(Error); ok {
-			if e.IsCancel() || e.IsGUIBusy() {
+			if e.IsCancel() || e.IsGUIBusy() || e.IsLock() {
 				return
 			}
 		}
@@ -470,10 +881,23 @@ func report(ctx Context, err error, update *Update, options UpdateOptions) {
 	}
 }
 
+// tempParentDir is where temp dirs for updates are made. It's in the cache
+// dir, rather than the system temp dir, which may be cleared on reboot, since
+// the temp dir of an update has the backup of the previous install (see
+// backupPath), which Recover needs after a crash.
+func (u *Updater) tempParentDir() string {
+	dir, err := u.openCache()
+	if err != nil {
+		u.log.Warningf("Unable to use cache for temp dir: %s", err)
+		return ""
+	}
+	return filepath.Join(dir, "tmp")
+}
+
 // tempDir, if specified, will contain files that were replaced during an update
 // and will be removed after an update. The temp dir should already exist.
 func (u *Updater) tempDir() string {
-	tmpDir := util.TempPath("", "KeybaseUpdater.")
+	tmpDir := util.TempPath(u.tempParentDir(), "KeybaseUpdater.")
 	if err := util.MakeDirs(tmpDir, 0700, u.log); err != nil {
 		u.log.Warningf("Error trying to create temp dir: %s", err)
 		return ""
@@ -483,13 +907,34 @@ func (u *Updater) tempDir() string {
 
 var tempDirRE = regexp.MustCompile(`^KeybaseUpdater.([ABCDEFGHIJKLMNOPQRSTUVWXYZ234567]{52}|\d{18,})$`)
 
-// CleanupPreviousUpdates removes temporary files from previous updates.
+// CleanupPreviousUpdates removes temporary files from previous updates, and
+// evicts downloaded assets if the cache is over its size limit.
 func (u *Updater) CleanupPreviousUpdates() (err error) {
-	parent := os.TempDir()
+	pendingTmpDir := u.pendingTmpDir()
+	// Updates used to make temp dirs in the system temp dir
+	parents := []string{os.TempDir()}
+	if parent := u.tempParentDir(); parent != "" {
+		parents = append([]string{parent}, parents...)
+	}
+	for _, parent := range parents {
+		if err := u.cleanupTempDirs(parent, pendingTmpDir); err != nil {
+			return err
+		}
+	}
+	u.evictCache("")
+	return nil
+}
+
+// cleanupTempDirs removes our temp dirs in parent, except for the temp dir of
+// an interrupted update
+func (u *Updater) cleanupTempDirs(parent string, pendingTmpDir string) error {
 	if parent == "" || parent == "." {
 		return fmt.Errorf("temp directory is '%v'", parent)
 	}
 	files, err := os.ReadDir(parent)
+	if os.IsNotExist(err) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("listing parent directory: %v", err)
 	}
@@ -499,6 +944,15 @@ func (u *Updater) CleanupPreviousUpdates() (err error) {
 		}
 		if tempDirRE.MatchString(fi.Name()) {
 			targetPath := filepath.Join(parent, fi.Name())
+			if isPendingTmpDir(targetPath, pendingTmpDir) {
+				u.log.Infof("Keeping temp dir of interrupted update: %v", targetPath)
+				continue
+			}
+			// The temp dir is shared, so only remove our own
+			if err := util.CheckPermissions(targetPath, 0); err != nil {
+				u.log.Debugf("Skipping temp dir: %v", err)
+				continue
+			}
 			u.log.Debugf("Cleaning old download: %v", targetPath)
 			err = os.RemoveAll(targetPath)
 			if err != nil {
@@ -509,8 +963,19 @@ func (u *Updater) CleanupPreviousUpdates() (err error) {
 	return nil
 }
 
-// Cleanup removes temporary files from this update
+// Cleanup removes temporary files from this update. The temp dir of an update
+// that was interrupted, or couldn't be rolled back, is kept, since it has the
+// backup of the previous install, for Recover.
 func (u *Updater) Cleanup(tmpDir string) {
+	if isPendingTmpDir(tmpDir, u.pendingTmpDir()) {
+		u.log.Warningf("Keeping temp dir of interrupted update: %q", tmpDir)
+		return
+	}
+	u.removeTempDir(tmpDir)
+}
+
+// removeTempDir removes a temp dir
+func (u *Updater) removeTempDir(tmpDir string) {
 	if tmpDir != "" {
 		u.log.Debugf("Remove temporary directory: %q", tmpDir)
 		if err := os.RemoveAll(tmpDir); err != nil {
@@ -518,52 +983,3 @@ func (u *Updater) Cleanup(tmpDir string) {
 		}
 	}
 }
-
-// Inspect previously downloaded updates to avoid redownloading
-func (u *Updater) FindDownloadedAsset(assetName string) (matchingAssetPath string, err error) {
-	if assetName == "" {
-		return "", fmt.Errorf("No asset name provided")
-	}
-	parent := os.TempDir()
-	if parent == "" || parent == "." {
-		return matchingAssetPath, fmt.Errorf("temp directory is %v", parent)
-	}
-
-	files, err := os.ReadDir(parent)
-	if err != nil {
-		return matchingAssetPath, fmt.Errorf("listing parent directory: %v", err)
-	}
-
-	for _, fi := range files {
-		if !fi.IsDir() || !tempDirRE.MatchString(fi.Name()) {
-			continue
-		}
-
-		keybaseTempDirAbs := filepath.Join(parent, fi.Name())
-		walkErr := filepath.Walk(keybaseTempDirAbs, func(fullPath string, info os.FileInfo, inErr error) (err error) {
-			if inErr != nil {
-				return inErr
-			}
-
-			if info.IsDir() {
-				if fullPath == keybaseTempDirAbs {
-					return nil
-				}
-				return filepath.SkipDir
-			}
-
-			path := strings.TrimPrefix(fullPath, keybaseTempDirAbs+string(filepath.Separator))
-			if path == assetName {
-				matchingAssetPath = fullPath
-				return filepath.SkipDir
-			}
-
-			return nil
-		})
-
-		if walkErr != nil {
-			return "", walkErr
-		}
-	}
-	return matchingAssetPath, nil
-}
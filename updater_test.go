@@ -4,6 +4,8 @@
 package updater
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -16,6 +18,7 @@ import (
 	"testing"
 
 	"github.com/keybase/go-logging"
+	"github.com/keybase/go-updater/delta"
 	"github.com/keybase/go-updater/saltpack"
 	"github.com/keybase/go-updater/util"
 	"github.com/stretchr/testify/assert"
@@ -44,14 +47,14 @@ otWUI0nTu2vG2Fx Mgeyqm20Ug8j7Bi N. END KEYBASE SALTPACK DETACHED SIGNATURE.`
 	KEYBASE SALTPACK DETACHED SIGNATURE.`
 )
 
-func makeKeybaseUpdateTempDir(t *testing.T, updater *Updater, testAsset *Asset) (tmpDir string) {
-	// This creates a real KebyaseUpdater.[ID] directory in os.TempDir
-	// Then we download the test zip to this directory from testServer
-	tmpDir, err := util.MakeTempDir("KeybaseUpdater.", 0700)
+// makeCachedAsset puts the test zip in the updater cache for the asset
+// (whether or not the digest matches), and returns its path
+func makeCachedAsset(t *testing.T, updater *Updater, testAsset Asset) string {
+	path, err := updater.cachePath(testAsset)
 	require.NoError(t, err)
-	err = updater.downloadAsset(testAsset, tmpDir, UpdateOptions{})
+	err = util.CopyFile(testZipPath, path, testLog)
 	require.NoError(t, err)
-	return tmpDir
+	return path
 }
 
 func init() {
@@ -64,7 +67,11 @@ func newTestUpdater(t *testing.T) (*Updater, error) {
 }
 
 func newTestUpdaterWithServer(t *testing.T, testServer *httptest.Server, update *Update, config Config) (*Updater, error) {
-	return NewUpdater(testUpdateSource{testServer: testServer, config: config, update: update}, config, testLog), nil
+	upr := NewUpdater(testUpdateSource{testServer: testServer, config: config, update: update}, config, testLog)
+	cacheDir := util.TempPath("", "KeybaseUpdaterTestCache.")
+	t.Cleanup(func() { util.RemoveFileAtPath(cacheDir) })
+	upr.SetCacheDir(cacheDir)
+	return upr, nil
 }
 
 func newTestContext(options UpdateOptions, cfg Config, response *UpdatePromptResponse) *testUpdateUI {
@@ -196,11 +203,16 @@ func (u testUpdateSource) FindUpdate(options UpdateOptions) (*Update, error) {
 }
 
 type testConfig struct {
-	auto         bool
-	autoSet      bool
-	autoOverride bool
-	installID    string
-	err          error
+	auto          bool
+	autoSet       bool
+	autoOverride  bool
+	installID     string
+	err           error
+	snoozeVersion string
+	snoozeUntil   time.Time
+	window        *MaintenanceWindow
+	lastCheckTime time.Time
+	lastCheck     *CheckResult
 }
 
 func (c testConfig) GetUpdateAuto() (bool, bool) {
@@ -218,7 +230,23 @@ func (c *testConfig) IsLastUpdateCheckTimeRecent(d time.Duration) bool {
 }
 
 func (c *testConfig) SetLastUpdateCheckTime() {
+	c.lastCheckTime = time.Now()
+}
 
+func (c testConfig) GetLastUpdateCheckTime() (time.Time, error) {
+	if c.lastCheckTime.IsZero() {
+		return time.Time{}, os.ErrNotExist
+	}
+	return c.lastCheckTime, nil
+}
+
+func (c testConfig) GetLastCheckResult() *CheckResult {
+	return c.lastCheck
+}
+
+func (c *testConfig) SetLastCheckResult(result CheckResult) error {
+	c.lastCheck = &result
+	return c.err
 }
 
 // For overriding the current Auto setting
@@ -240,6 +268,20 @@ func (c *testConfig) SetInstallID(s string) error {
 	return c.err
 }
 
+func (c testConfig) GetSnooze() (string, time.Time) {
+	return c.snoozeVersion, c.snoozeUntil
+}
+
+func (c *testConfig) SetSnooze(version string, until time.Time) error {
+	c.snoozeVersion = version
+	c.snoozeUntil = until
+	return c.err
+}
+
+func (c testConfig) GetMaintenanceWindow() *MaintenanceWindow {
+	return c.window
+}
+
 func (c testConfig) GetLastAppliedVersion() string {
 	return ""
 }
@@ -345,11 +387,68 @@ func TestUpdaterSnooze(t *testing.T) {
 	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
 	assert.NoError(t, err)
 	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionSnooze, AutoUpdate: true})
+	ctx.response.SnoozeDuration = 3600
+	ctx.response.AutoUpdate = false
 	_, err = upr.Update(ctx)
 	assert.EqualError(t, err, "Update Error (cancel): Snoozed update")
 
 	// Don't report error on user snooze
 	assert.NoError(t, ctx.errReported)
+
+	// The snooze is saved
+	cfg := upr.config.(*testConfig)
+	assert.Equal(t, "1.0.1", cfg.snoozeVersion)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), cfg.snoozeUntil, time.Minute)
+
+	// Don't prompt while snoozed
+	ctx.actionReported = ""
+	update, err := upr.Update(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, update)
+	assert.Equal(t, UpdateAction(""), ctx.actionReported)
+
+	// Prompt if ignoring snooze
+	options := newDefaultTestUpdateOptions()
+	options.IgnoreSnooze = true
+	ignoreCtx := newTestContext(options, upr.config, &UpdatePromptResponse{Action: UpdateActionCancel})
+	_, err = upr.Update(ignoreCtx)
+	assert.EqualError(t, err, "Update Error (cancel): Canceled")
+	assert.Equal(t, UpdateActionCancel, ignoreCtx.actionReported)
+	assert.Equal(t, "1.0.1", cfg.snoozeVersion)
+
+	// Prompt once the snooze is over, and clear it
+	cfg.snoozeUntil = time.Now().Add(-time.Minute)
+	_, err = upr.Update(ctx)
+	assert.EqualError(t, err, "Update Error (cancel): Snoozed update")
+	assert.Equal(t, UpdateActionSnooze, ctx.actionReported)
+}
+
+func TestUpdaterSnoozeDefaultDuration(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	cfg := &testConfig{}
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), cfg)
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionSnooze})
+	_, err = upr.Update(ctx)
+	assert.EqualError(t, err, "Update Error (cancel): Snoozed update")
+	assert.WithinDuration(t, time.Now().Add(DefaultSnoozeDuration), cfg.snoozeUntil, time.Minute)
+}
+
+func TestUpdaterSnoozeNewerVersion(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	// Snoozed an older version
+	cfg := &testConfig{snoozeVersion: "1.0.0-1", snoozeUntil: time.Now().Add(time.Hour)}
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), cfg)
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionCancel})
+	_, err = upr.Update(ctx)
+	assert.EqualError(t, err, "Update Error (cancel): Canceled")
+	assert.Equal(t, UpdateActionCancel, ctx.actionReported)
+	assert.Equal(t, "", cfg.snoozeVersion)
 }
 
 func TestUpdaterContinue(t *testing.T) {
@@ -464,10 +563,64 @@ func TestUpdaterDownloadNil(t *testing.T) {
 	tmpDir, err := util.MakeTempDir("TestUpdaterDownloadNil", 0700)
 	defer util.RemoveFileAtPath(tmpDir)
 	require.NoError(t, err)
-	err = upr.downloadAsset(nil, tmpDir, UpdateOptions{})
+	err = upr.downloadAsset(context.Background(), nil, tmpDir, UpdateOptions{})
 	assert.EqualError(t, err, "No asset to download")
 }
 
+// testServerForResume returns a server that drops the connection partway
+// through the first download of path, and serves range requests after that
+func testServerForResume(t *testing.T, path string, rangeRequests *int) *httptest.Server {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"abcdef"`)
+		if requests == 1 {
+			// Claim the full length, but only write half of it
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+			_, _ = w.Write(data[:len(data)/2])
+			return
+		}
+		if r.Header.Get("Range") != "" {
+			*rangeRequests++
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}))
+}
+
+func TestUpdaterDownloadResume(t *testing.T) {
+	rangeRequests := 0
+	testServer := testServerForResume(t, testZipPath, &rangeRequests)
+	defer testServer.Close()
+
+	update := testUpdate(testServer.URL)
+	upr, err := newTestUpdaterWithServer(t, testServer, update, &testConfig{})
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+
+	_, _, err = upr.CheckAndDownload(ctx)
+	require.Error(t, err)
+	// The partial download is kept in the cache
+	path, err := upr.cachePath(*update.Asset)
+	require.NoError(t, err)
+	exists, err := util.FileExists(path + ".download")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	pending, err := upr.pendingAsset()
+	require.NoError(t, err)
+	assert.Equal(t, "", pending)
+
+	updateAvailable, updateWasDownloaded, err := upr.CheckAndDownload(ctx)
+	require.NoError(t, err)
+	assert.True(t, updateAvailable)
+	assert.True(t, updateWasDownloaded)
+	assert.Equal(t, 1, rangeRequests)
+	exists, err = util.FileExists(path)
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
 func TestUpdaterApplyError(t *testing.T) {
 	testServer := testServerForUpdateFile(t, testZipPath)
 	defer testServer.Close()
@@ -500,6 +653,81 @@ func TestUpdaterNotNeeded(t *testing.T) {
 	assert.Equal(t, "deadbeef", upr.config.GetInstallID())
 }
 
+func TestUpdaterDowngrade(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	for _, version := range []string{"1.0.1", "1.0.2"} {
+		upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+		require.NoError(t, err)
+		options := newDefaultTestUpdateOptions()
+		options.Version = version
+		ctx := newTestContext(options, upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+		_, err = upr.Update(ctx)
+		require.Error(t, err)
+		assert.Equal(t, "downgrade", err.(Error).TypeString())
+		assert.True(t, err.(Error).IsDowngrade())
+		assert.False(t, ctx.successReported)
+		require.NotNil(t, ctx.errReported)
+
+		_, _, err = upr.CheckAndDownload(ctx)
+		require.Error(t, err)
+		assert.True(t, err.(Error).IsDowngrade())
+
+		needUpdate, err := upr.NeedUpdate(ctx)
+		require.NoError(t, err)
+		assert.False(t, needUpdate)
+	}
+}
+
+func TestUpdaterDowngradeForce(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+	require.NoError(t, err)
+	options := newDefaultTestUpdateOptions()
+	options.Version = "1.0.2"
+	options.Force = true
+	ctx := newTestContext(options, upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+	update, err := upr.Update(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, update)
+	assert.True(t, ctx.successReported)
+}
+
+func TestUpdaterVersionInvalid(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	// Unknown current version, refuse the update, unless it's forced
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+	require.NoError(t, err)
+	options := newDefaultTestUpdateOptions()
+	options.Version = ""
+	ctx := newTestContext(options, upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+	_, err = upr.Update(ctx)
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsDowngrade())
+	assert.Contains(t, err.Error(), `Invalid current version ""`)
+
+	options.Force = true
+	ctx = newTestContext(options, upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+	update, err := upr.Update(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, update)
+
+	// Invalid update version, refuse the update
+	invalid := testUpdate(testServer.URL)
+	invalid.Version = "invalid"
+	upr, err = newTestUpdaterWithServer(t, testServer, invalid, &testConfig{})
+	require.NoError(t, err)
+	ctx = newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+	_, err = upr.Update(ctx)
+	require.Error(t, err)
+	assert.True(t, err.(Error).IsDowngrade())
+}
+
 func TestUpdaterCheckAndUpdate(t *testing.T) {
 	testServer := testServerForUpdateFile(t, testZipPath)
 	defer testServer.Close()
@@ -537,8 +765,7 @@ func TestUpdaterCheckAndUpdate(t *testing.T) {
 	// 3.Find existing downloaded asset
 	// Need update = true
 	// FindDownloadedAsset = true
-	// return updateAvailable = true, updateWasDownloaded = true
-	tmpDir := makeKeybaseUpdateTempDir(t, upr, testUpdate.Asset)
+	// return updateAvailable = true, updateWasDownloaded = false
 	updateAvailable, updateWasDownloaded, err = upr.CheckAndDownload(ctx)
 	assert.NoError(t, err)
 	assert.True(t, updateAvailable)
@@ -554,13 +781,10 @@ func TestUpdaterCheckAndUpdate(t *testing.T) {
 	assert.False(t, ctx.successReported)
 	assert.Equal(t, "deadbeef", upr.config.GetInstallID())
 
-	util.RemoveFileAtPath(tmpDir)
-
 	// 4.Verify fails b.c. bit flip
 	// Need update = true
 	// FindDownloadedAsset = true
 	// return updateAvailable = false, updateWasDownloaded = false
-	tmpDir = makeKeybaseUpdateTempDir(t, upr, testUpdate.Asset)
 	testUpdate.Asset.Signature = invalidSignature
 
 	updateAvailable, updateWasDownloaded, err = upr.CheckAndDownload(ctx)
@@ -570,24 +794,26 @@ func TestUpdaterCheckAndUpdate(t *testing.T) {
 	assert.False(t, ctx.successReported)
 	assert.Equal(t, "deadbeef", upr.config.GetInstallID())
 
-	util.RemoveFileAtPath(tmpDir)
+	// The failed asset was removed from the cache
+	cachedPath, err := upr.FindDownloadedAsset(*testUpdate.Asset)
+	assert.NoError(t, err)
+	assert.Equal(t, "", cachedPath)
 	testUpdate.Asset.Signature = validSignature
 
 	// 5.Digest fails b.c. bit flip
 	// Need update = true
 	// FindDownloadedAsset = true
 	// return updateAvailable = false, updateWasDownloaded = false
-	tmpDir = makeKeybaseUpdateTempDir(t, upr, testUpdate.Asset)
 	testUpdate.Asset.Digest = invalidDigest
+	cachedPath = makeCachedAsset(t, upr, *testUpdate.Asset)
 
 	updateAvailable, updateWasDownloaded, err = upr.CheckAndDownload(ctx)
-	assert.EqualError(t, err, fmt.Sprintf("Update Error (verify): Invalid digest: 54970995e4d02da631e0634162ef66e2663e0eee7d018e816ac48ed6f7811c84 != 74970995e4d02da631e0634162ef66e2663e0eee7d018e816ac48ed6f7811c84 (%s)", filepath.Join(tmpDir, testUpdate.Asset.Name)))
+	assert.EqualError(t, err, fmt.Sprintf("Update Error (verify): Invalid digest: 54970995e4d02da631e0634162ef66e2663e0eee7d018e816ac48ed6f7811c84 != 74970995e4d02da631e0634162ef66e2663e0eee7d018e816ac48ed6f7811c84 (%s)", cachedPath))
 	assert.False(t, updateAvailable)
 	assert.False(t, updateWasDownloaded)
 	assert.False(t, ctx.successReported)
 	assert.Equal(t, "deadbeef", upr.config.GetInstallID())
 
-	util.RemoveFileAtPath(tmpDir)
 	testUpdate.Asset.Digest = validDigest
 }
 
@@ -659,11 +885,11 @@ func TestApplyDownloaded(t *testing.T) {
 	resetCtxErr()
 
 	// 4. FindDownloadedAsset = true -> digest fails
-	tmpDir := makeKeybaseUpdateTempDir(t, upr, testUpdate.Asset)
 	testUpdate.Asset.Digest = invalidDigest
+	cachedPath := makeCachedAsset(t, upr, *testUpdate.Asset)
 
 	applied, err = upr.ApplyDownloaded(ctx)
-	assert.EqualError(t, err, fmt.Sprintf("Update Error (verify): Invalid digest: 54970995e4d02da631e0634162ef66e2663e0eee7d018e816ac48ed6f7811c84 != 74970995e4d02da631e0634162ef66e2663e0eee7d018e816ac48ed6f7811c84 (%s)", filepath.Join(tmpDir, testUpdate.Asset.Name)))
+	assert.EqualError(t, err, fmt.Sprintf("Update Error (verify): Invalid digest: 54970995e4d02da631e0634162ef66e2663e0eee7d018e816ac48ed6f7811c84 != 74970995e4d02da631e0634162ef66e2663e0eee7d018e816ac48ed6f7811c84 (%s)", cachedPath))
 	assert.False(t, applied)
 	assert.NotNil(t, ctx.errReported)
 	assert.Nil(t, ctx.updateReported)
@@ -671,10 +897,9 @@ func TestApplyDownloaded(t *testing.T) {
 
 	resetCtxErr()
 	testUpdate.Asset.Digest = validDigest
-	util.RemoveFileAtPath(tmpDir)
 
 	// 5. FindDownloadedAsset = true -> verify fails
-	tmpDir = makeKeybaseUpdateTempDir(t, upr, testUpdate.Asset)
+	makeCachedAsset(t, upr, *testUpdate.Asset)
 	testUpdate.Asset.Signature = invalidSignature
 
 	applied, err = upr.ApplyDownloaded(ctx)
@@ -686,10 +911,9 @@ func TestApplyDownloaded(t *testing.T) {
 
 	resetCtxErr()
 	testUpdate.Asset.Signature = validSignature
-	util.RemoveFileAtPath(tmpDir)
 
 	// 6. FindDownloadedAsset = true -> no error success
-	tmpDir = makeKeybaseUpdateTempDir(t, upr, testUpdate.Asset)
+	makeCachedAsset(t, upr, *testUpdate.Asset)
 
 	applied, err = upr.ApplyDownloaded(ctx)
 	assert.NoError(t, err)
@@ -698,64 +922,95 @@ func TestApplyDownloaded(t *testing.T) {
 	assert.NotNil(t, ctx.updateReported)
 	assert.True(t, ctx.successReported)
 
+	// The applied asset was removed from the cache
+	cachedPath, err = upr.FindDownloadedAsset(*testUpdate.Asset)
+	assert.NoError(t, err)
+	assert.Equal(t, "", cachedPath)
+
 	resetCtxErr()
-	util.RemoveFileAtPath(tmpDir)
 }
 
 func TestFindDownloadedAsset(t *testing.T) {
 	upr, err := newTestUpdater(t)
 	assert.NoError(t, err)
-	defer func() {
-		err = upr.CleanupPreviousUpdates()
-		assert.NoError(t, err)
-	}()
-
-	// 1. empty asset
-	matchingAssetPath, err := upr.FindDownloadedAsset("")
-	assert.EqualError(t, err, "No asset name provided")
-	assert.Equal(t, "", matchingAssetPath)
-
-	// 2. assset given -> did not create KeybaseUpdate.
-	matchingAssetPath, err = upr.FindDownloadedAsset("temp")
+	asset := Asset{Name: "test.zip", Digest: validDigest}
+
+	// 1. invalid asset name or digest
+	_, err = upr.FindDownloadedAsset(Asset{Digest: validDigest})
+	assert.EqualError(t, err, `Invalid asset name: ""`)
+	_, err = upr.FindDownloadedAsset(Asset{Name: "../test.zip", Digest: validDigest})
+	assert.EqualError(t, err, `Invalid asset name: "../test.zip"`)
+	_, err = upr.FindDownloadedAsset(Asset{Name: "test.zip", Digest: "../test"})
+	assert.EqualError(t, err, `Invalid asset digest: "../test"`)
+
+	// 2. asset not in cache
+	matchingAssetPath, err := upr.FindDownloadedAsset(asset)
 	assert.NoError(t, err)
 	assert.Equal(t, "", matchingAssetPath)
 
-	// 3. asset given -> created KeybaseUpdate. -> directory empty
-	tmpDir, err := util.MakeTempDir("KeybaseUpdater.", 0700)
-	assert.NoError(t, err)
-	require.NoError(t, err)
-
-	matchingAssetPath, err = upr.FindDownloadedAsset("temp")
+	// 3. asset with the same name but a different digest isn't a match
+	makeCachedAsset(t, upr, Asset{Name: "test.zip", Digest: invalidDigest})
+	matchingAssetPath, err = upr.FindDownloadedAsset(asset)
 	assert.NoError(t, err)
 	assert.Equal(t, "", matchingAssetPath)
 
-	util.RemoveFileAtPath(tmpDir)
-
-	// 4. asset given -> created KeybaseUpdate. -> file exists but no match
-	tmpDir, err = util.MakeTempDir("KeybaseUpdater.", 0700)
+	// 4. asset in cache
+	cachedPath := makeCachedAsset(t, upr, asset)
+	matchingAssetPath, err = upr.FindDownloadedAsset(asset)
 	assert.NoError(t, err)
-	tmpFile := filepath.Join(tmpDir, "nottemp")
-	err = os.WriteFile(tmpFile, []byte("Contents of temp file"), 0700)
-	require.NoError(t, err)
+	assert.Equal(t, cachedPath, matchingAssetPath)
 
-	matchingAssetPath, err = upr.FindDownloadedAsset("temp")
-	assert.NoError(t, err)
-	assert.Equal(t, "", matchingAssetPath)
+	// 5. asset writable by others isn't used
+	err = os.Chmod(cachedPath, 0666)
+	require.NoError(t, err)
+	_, err = upr.FindDownloadedAsset(asset)
+	assert.Error(t, err)
+	err = os.Chmod(cachedPath, 0600)
+	require.NoError(t, err)
 
-	util.RemoveFileAtPath(tmpDir)
+	// 6. cache accessible by others isn't used
+	err = os.Chmod(upr.cacheDir, 0755)
+	require.NoError(t, err)
+	_, err = upr.FindDownloadedAsset(asset)
+	assert.Error(t, err)
+}
 
-	// 5. asset given -> created KeybaseUpdate. -> file exixst and matches
-	tmpDir, err = util.MakeTempDir("KeybaseUpdater.", 0700)
-	tmpFile = filepath.Join(tmpDir, "temp")
-	err = os.WriteFile(tmpFile, []byte("Contents of temp file"), 0700)
+func TestCacheEviction(t *testing.T) {
+	upr, err := newTestUpdater(t)
 	require.NoError(t, err)
+	info, err := os.Stat(testZipPath)
+	require.NoError(t, err)
+	// Room for 2 assets
+	upr.SetCacheLimit(2 * info.Size())
 
-	matchingAssetPath, err = upr.FindDownloadedAsset("temp")
-	assert.NoError(t, err)
-	assert.Equal(t, tmpFile, matchingAssetPath)
+	digests := []string{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"1111111111111111111111111111111111111111111111111111111111111111",
+		"2222222222222222222222222222222222222222222222222222222222222222",
+	}
+	for i, digest := range digests {
+		path := makeCachedAsset(t, upr, Asset{Name: "test.zip", Digest: digest})
+		modTime := time.Now().Add(time.Duration(i-len(digests)) * time.Hour)
+		err = os.Chtimes(filepath.Dir(path), modTime, modTime)
+		require.NoError(t, err)
+	}
 
-	util.RemoveFileAtPath(tmpDir)
+	// Using the oldest asset makes it the most recently used
+	path, err := upr.FindDownloadedAsset(Asset{Name: "test.zip", Digest: digests[0]})
+	require.NoError(t, err)
+	require.NotEqual(t, "", path)
 
+	err = upr.CleanupPreviousUpdates()
+	require.NoError(t, err)
+	for i, digest := range digests {
+		path, err := upr.FindDownloadedAsset(Asset{Name: "test.zip", Digest: digest})
+		require.NoError(t, err)
+		if i == 1 {
+			assert.Equal(t, "", path)
+		} else {
+			assert.NotEqual(t, "", path)
+		}
+	}
 }
 
 func TestUpdaterGuiBusy(t *testing.T) {
@@ -796,3 +1051,285 @@ func TestUpdaterGuiBusy(t *testing.T) {
 	_, err = upr.Update(ctx)
 	assert.NoError(t, err)
 }
+
+// testHealthCheckUI moves the existing destination to tmpDir on apply (like
+// util.MoveFile does) and fails the health check with healthCheckErr.
+type testHealthCheckUI struct {
+	*testUpdateUI
+	healthCheckErr error
+	rolledBack     bool
+}
+
+func (u *testHealthCheckUI) Apply(update Update, options UpdateOptions, tmpDir string) error {
+	tmpPath := filepath.Join(os.TempDir(), "TestUpdaterRollback.new")
+	if err := os.WriteFile(tmpPath, []byte("new"), 0600); err != nil {
+		return err
+	}
+	return util.MoveFile(tmpPath, options.DestinationPath, tmpDir, testLog)
+}
+
+func (u *testHealthCheckUI) HealthCheck(update Update, options UpdateOptions) error {
+	return u.healthCheckErr
+}
+
+func (u *testHealthCheckUI) AfterRollback(update Update, options UpdateOptions) error {
+	u.rolledBack = true
+	return nil
+}
+
+func TestUpdaterRollback(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	options := newDefaultTestUpdateOptions()
+	options.DestinationPath = util.TempPath("", "TestUpdaterRollback.")
+	defer util.RemoveFileAtPath(options.DestinationPath)
+
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+	require.NoError(t, err)
+	ctx := &testHealthCheckUI{
+		testUpdateUI: newTestContext(options, upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true}),
+	}
+
+	// Health check passes, keep the update
+	err = os.WriteFile(options.DestinationPath, []byte("old"), 0600)
+	require.NoError(t, err)
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+	data, err := os.ReadFile(options.DestinationPath)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+	assert.False(t, ctx.rolledBack)
+	assert.True(t, ctx.successReported)
+
+	// Health check fails, restore the previous install
+	err = os.WriteFile(options.DestinationPath, []byte("old"), 0600)
+	require.NoError(t, err)
+	ctx.successReported = false
+	ctx.healthCheckErr = fmt.Errorf("Test health check error")
+	_, err = upr.Update(ctx)
+	assert.EqualError(t, err, "Update Error (rollback): Health check failed: Test health check error; Restored previous install")
+	data, err = os.ReadFile(options.DestinationPath)
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+	assert.True(t, ctx.rolledBack)
+	assert.False(t, ctx.successReported)
+
+	require.NotNil(t, ctx.errReported)
+	assert.Equal(t, RollbackError, ctx.errReported.(Error).errorType)
+}
+
+func TestUpdaterRollbackNoBackup(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true})
+	ctx.afterApplyErr = fmt.Errorf("Test after error")
+
+	// Nothing was replaced, so there is nothing to roll back to
+	_, err = upr.Update(ctx)
+	assert.EqualError(t, err, "Update Error (apply): Test after error")
+	require.NotNil(t, ctx.errReported)
+	assert.Equal(t, ApplyError, ctx.errReported.(Error).errorType)
+}
+
+// testApplyFailUI replaces the install, and then fails to apply
+type testApplyFailUI struct {
+	*testUpdateUI
+}
+
+func (u *testApplyFailUI) Apply(update Update, options UpdateOptions, tmpDir string) error {
+	tmpPath := filepath.Join(os.TempDir(), "TestUpdaterApplyRollback.new")
+	if err := os.WriteFile(tmpPath, []byte("new"), 0600); err != nil {
+		return err
+	}
+	if err := util.MoveFile(tmpPath, options.DestinationPath, tmpDir, testLog); err != nil {
+		return err
+	}
+	return fmt.Errorf("Test apply error")
+}
+
+func TestUpdaterApplyRollback(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	options := newDefaultTestUpdateOptions()
+	options.DestinationPath = util.TempPath("", "TestUpdaterApplyRollback.")
+	defer util.RemoveFileAtPath(options.DestinationPath)
+
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+	require.NoError(t, err)
+	ctx := &testApplyFailUI{
+		testUpdateUI: newTestContext(options, upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true}),
+	}
+
+	// The previous install was moved before the apply failed, so it's restored
+	err = os.WriteFile(options.DestinationPath, []byte("old"), 0600)
+	require.NoError(t, err)
+	_, err = upr.Update(ctx)
+	assert.EqualError(t, err, "Update Error (rollback): Test apply error; Restored previous install")
+	data, err := os.ReadFile(options.DestinationPath)
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+}
+
+// testServerForDelta serves the full test zip and a patch to it, and counts
+// requests for the full asset
+func testServerForDelta(t *testing.T, patch []byte, fullRequests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/test.delta" {
+			_, _ = w.Write(patch)
+			return
+		}
+		*fullRequests++
+		http.ServeFile(w, r, testZipPath)
+	}))
+}
+
+func TestUpdaterDelta(t *testing.T) {
+	target, err := os.ReadFile(testZipPath)
+	require.NoError(t, err)
+	// The installed base is the test zip with some changes
+	base := append([]byte{}, target...)
+	base[len(base)/2] ^= 0xff
+	base = append(base, []byte("installed")...)
+	var patch bytes.Buffer
+	err = delta.Diff(base, target, &patch)
+	require.NoError(t, err)
+	patchDigest, err := util.Digest(bytes.NewReader(patch.Bytes()))
+	require.NoError(t, err)
+	baseDigest, err := util.Digest(bytes.NewReader(base))
+	require.NoError(t, err)
+
+	fullRequests := 0
+	testServer := testServerForDelta(t, patch.Bytes(), &fullRequests)
+	defer testServer.Close()
+
+	options := newDefaultTestUpdateOptions()
+	options.DestinationPath = util.TempPath("", "TestUpdaterDelta.")
+	defer util.RemoveFileAtPath(options.DestinationPath)
+	err = os.WriteFile(options.DestinationPath, base, 0600)
+	require.NoError(t, err)
+
+	update := testUpdate(testServer.URL + "/test.zip")
+	update.Delta = &Delta{
+		URL:         testServer.URL + "/test.delta",
+		Digest:      patchDigest,
+		BaseVersion: options.Version,
+		BaseDigest:  baseDigest,
+	}
+	upr, err := newTestUpdaterWithServer(t, testServer, update, &testConfig{})
+	require.NoError(t, err)
+	ctx := newTestContext(options, upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true})
+
+	// Reconstructed from the delta
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, fullRequests)
+	assert.True(t, ctx.successReported)
+	// The installed asset is kept, as the base for the next delta
+	installed, err := FindInstalledAsset(upr.cacheDir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(InstalledAssetDir(upr.cacheDir), "test.zip"), installed)
+
+	// Installed base doesn't match, fall back to the full asset
+	update.Delta.BaseDigest = invalidDigest
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, fullRequests)
+	update.Delta.BaseDigest = baseDigest
+
+	// Delta is for another version, fall back to the full asset
+	update.Delta.BaseVersion = "0.9.0"
+	_, err = upr.Update(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, fullRequests)
+	update.Delta.BaseVersion = options.Version
+
+	// CheckAndDownload also uses the delta
+	updateAvailable, updateWasDownloaded, err := upr.CheckAndDownload(ctx)
+	require.NoError(t, err)
+	assert.True(t, updateAvailable)
+	assert.True(t, updateWasDownloaded)
+	assert.Equal(t, 2, fullRequests)
+	err = upr.CleanupPreviousUpdates()
+	require.NoError(t, err)
+}
+
+func TestUpdaterContextCanceled(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply, AutoUpdate: true})
+
+	goCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = upr.UpdateContext(goCtx, ctx)
+	assert.EqualError(t, err, "Update Error (cancel): context canceled")
+	assert.True(t, err.(Error).IsCancel())
+	// Don't report cancels
+	assert.Nil(t, ctx.errReported)
+	assert.False(t, ctx.successReported)
+
+	_, _, err = upr.CheckAndDownloadContext(goCtx, ctx)
+	assert.EqualError(t, err, "Update Error (cancel): context canceled")
+}
+
+// testContextPromptUI has a prompt that waits until the context is done
+type testContextPromptUI struct {
+	*testUpdateUI
+}
+
+func (u testContextPromptUI) GetUpdateUI() UpdateUI {
+	return u
+}
+
+func (u testContextPromptUI) UpdatePromptContext(goCtx context.Context, _ Update, _ UpdateOptions, _ UpdatePromptOptions) (*UpdatePromptResponse, error) {
+	<-goCtx.Done()
+	return nil, goCtx.Err()
+}
+
+func TestUpdaterPromptCanceled(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+	require.NoError(t, err)
+	ctx := testContextPromptUI{newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply})}
+
+	goCtx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = upr.UpdateContext(goCtx, ctx)
+	assert.EqualError(t, err, "Update Error (cancel): context deadline exceeded")
+	assert.Nil(t, ctx.errReported)
+}
+
+func TestUpdaterSnoozeAvailable(t *testing.T) {
+	testServer := testServerForUpdateFile(t, testZipPath)
+	defer testServer.Close()
+
+	upr, err := newTestUpdaterWithServer(t, testServer, testUpdate(testServer.URL), &testConfig{})
+	require.NoError(t, err)
+	ctx := newTestContext(newDefaultTestUpdateOptions(), upr.config, &UpdatePromptResponse{Action: UpdateActionApply})
+	cfg := upr.config.(*testConfig)
+
+	err = upr.Snooze(ctx, "", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.1", cfg.snoozeVersion)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), cfg.snoozeUntil, time.Minute)
+
+	err = upr.Snooze(ctx, "1.0.2", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "1.0.2", cfg.snoozeVersion)
+	assert.WithinDuration(t, time.Now().Add(DefaultSnoozeDuration), cfg.snoozeUntil, time.Minute)
+
+	// No update to snooze
+	upr, err = newTestUpdaterWithServer(t, testServer, newTestUpdate(testServer.URL, false), &testConfig{})
+	require.NoError(t, err)
+	err = upr.Snooze(ctx, "", time.Hour)
+	require.EqualError(t, err, "No update to snooze")
+}
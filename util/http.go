@@ -4,11 +4,14 @@
 package util
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -63,6 +66,26 @@ func SaveHTTPResponse(resp *http.Response, savePath string, mode os.FileMode, lo
 	return err
 }
 
+// appendHTTPResponse appends an http.Response to an existing (partial) file at
+// savePath
+func appendHTTPResponse(resp *http.Response, savePath string, log Log) error {
+	if resp == nil {
+		return fmt.Errorf("No response")
+	}
+	file, err := os.OpenFile(savePath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return err
+	}
+	defer Close(file)
+
+	log.Infof("Resuming download to %s", savePath)
+	n, err := io.Copy(file, resp.Body)
+	if err == nil {
+		log.Infof("Downloaded %d bytes", n)
+	}
+	return err
+}
+
 // DiscardAndCloseBodyIgnoreError calls DiscardAndCloseBody.
 // This satisfies lint checks when using with defer and you don't care if there
 // is an error, so instead of:
@@ -130,11 +153,17 @@ type DownloadURLOptions struct {
 
 // DownloadURL downloads a URL to a path.
 func DownloadURL(urlString string, destinationPath string, options DownloadURLOptions) error {
-	_, err := downloadURL(urlString, destinationPath, options)
+	return DownloadURLContext(context.Background(), urlString, destinationPath, options)
+}
+
+// DownloadURLContext downloads a URL to a path. The download stops if the
+// context is done, leaving a partial download to resume from.
+func DownloadURLContext(ctx context.Context, urlString string, destinationPath string, options DownloadURLOptions) error {
+	_, err := downloadURL(ctx, urlString, destinationPath, options)
 	return err
 }
 
-func downloadURL(urlString string, destinationPath string, options DownloadURLOptions) (cached bool, _ error) {
+func downloadURL(ctx context.Context, urlString string, destinationPath string, options DownloadURLOptions) (cached bool, _ error) {
 	log := options.Log
 
 	url, err := parseURL(urlString)
@@ -160,7 +189,12 @@ func downloadURL(urlString string, destinationPath string, options DownloadURLOp
 		}
 	}
 
-	req, err := http.NewRequest("GET", url.String(), nil)
+	// If there is a partial download from a previous attempt, try to resume it
+	savePath := fmt.Sprintf("%s.download", destinationPath)
+	validatorPath := fmt.Sprintf("%s.validator", savePath)
+	offset, validator := partialDownload(savePath, validatorPath)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url.String(), nil)
 	if err != nil {
 		return cached, err
 	}
@@ -168,6 +202,11 @@ func downloadURL(urlString string, destinationPath string, options DownloadURLOp
 		log.Infof("Using etag: %s", etag)
 		req.Header.Set("If-None-Match", etag)
 	}
+	if offset > 0 {
+		log.Infof("Resuming partial download at %d bytes (%s)", offset, validator)
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", validator)
+	}
 	var client http.Client
 	if options.Timeout > 0 {
 		client = http.Client{Timeout: options.Timeout}
@@ -183,34 +222,61 @@ func downloadURL(urlString string, destinationPath string, options DownloadURLOp
 		return cached, fmt.Errorf("No response")
 	}
 	defer DiscardAndCloseBodyIgnoreError(resp)
-	if resp.StatusCode == http.StatusNotModified {
+	switch resp.StatusCode {
+	case http.StatusNotModified:
 		cached = true
 		// ETag matched, we already have it
 		log.Infof("Using cached file: %s", destinationPath)
 		return cached, nil
-	}
-	if resp.StatusCode != http.StatusOK {
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset == 0 {
+			return cached, fmt.Errorf("Responded with %s", resp.Status)
+		}
+		// The partial download doesn't match what the server has, start over
+		log.Infof("Unable to resume partial download, starting over")
+		removePartialDownload(savePath, validatorPath)
+		return downloadURL(ctx, urlString, destinationPath, options)
+	case http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp); !ok || offset == 0 || start != offset {
+			removePartialDownload(savePath, validatorPath)
+			return cached, fmt.Errorf("Invalid partial response (%q) for offset %d", resp.Header.Get("Content-Range"), offset)
+		}
+	case http.StatusOK:
+		if offset > 0 {
+			log.Infof("Server didn't resume partial download, starting over")
+		}
+	default:
 		return cached, fmt.Errorf("Responded with %s", resp.Status)
 	}
 
-	savePath := fmt.Sprintf("%s.download", destinationPath)
-	if _, ferr := os.Stat(savePath); ferr == nil {
-		log.Infof("Removing existing partial download: %s", savePath)
-		if rerr := os.Remove(savePath); rerr != nil {
-			return cached, fmt.Errorf("Error removing existing partial download: %s", rerr)
+	if resp.StatusCode == http.StatusPartialContent {
+		if err := appendHTTPResponse(resp, savePath, log); err != nil {
+			return cached, err
+		}
+	} else {
+		if _, ferr := os.Stat(savePath); ferr == nil {
+			log.Infof("Removing existing partial download: %s", savePath)
+			if rerr := os.Remove(savePath); rerr != nil {
+				return cached, fmt.Errorf("Error removing existing partial download: %s", rerr)
+			}
 		}
-	}
 
-	if err := MakeParentDirs(savePath, 0700, log); err != nil {
-		return cached, err
-	}
+		if err := MakeParentDirs(savePath, 0700, log); err != nil {
+			return cached, err
+		}
 
-	if err := SaveHTTPResponse(resp, savePath, 0600, log); err != nil {
-		return cached, err
+		// Remember the validator so we can resume if the download is interrupted
+		saveValidator(validatorPath, responseValidator(resp), log)
+
+		if err := SaveHTTPResponse(resp, savePath, 0600, log); err != nil {
+			return cached, err
+		}
 	}
 
 	if options.RequireDigest {
 		if err := CheckDigest(options.Digest, savePath, log); err != nil {
+			// Don't resume from a bad download
+			removePartialDownload(savePath, validatorPath)
 			return cached, err
 		}
 	}
@@ -218,10 +284,65 @@ func downloadURL(urlString string, destinationPath string, options DownloadURLOp
 	if err := MoveFile(savePath, destinationPath, "", log); err != nil {
 		return cached, err
 	}
+	RemoveFileAtPath(validatorPath)
 
 	return cached, nil
 }
 
+// partialDownload returns the size of a partial download at savePath and the
+// validator (ETag or Last-Modified) it was downloaded with. If the partial
+// download can't be resumed, the size is 0.
+func partialDownload(savePath string, validatorPath string) (int64, string) {
+	info, err := os.Stat(savePath)
+	if err != nil || info.Size() == 0 {
+		return 0, ""
+	}
+	validator, err := ReadFile(validatorPath)
+	if err != nil || len(validator) == 0 {
+		return 0, ""
+	}
+	return info.Size(), string(validator)
+}
+
+func removePartialDownload(savePath string, validatorPath string) {
+	RemoveFileAtPath(savePath)
+	RemoveFileAtPath(validatorPath)
+}
+
+// responseValidator returns a validator for If-Range, which is a strong ETag or
+// the Last-Modified date, or "" if the response has neither.
+func responseValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+func saveValidator(validatorPath string, validator string, log Log) {
+	if validator == "" {
+		RemoveFileAtPath(validatorPath)
+		return
+	}
+	if err := os.WriteFile(validatorPath, []byte(validator), 0600); err != nil {
+		log.Warningf("Error saving download validator: %s", err)
+	}
+}
+
+// contentRangeStart returns the first byte position of a Content-Range header,
+// for example, "bytes 100-199/200" => 100.
+func contentRangeStart(resp *http.Response) (int64, bool) {
+	contentRange := strings.TrimPrefix(resp.Header.Get("Content-Range"), "bytes ")
+	i := strings.Index(contentRange, "-")
+	if i <= 0 {
+		return 0, false
+	}
+	start, err := strconv.ParseInt(contentRange[:i], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return start, true
+}
+
 func downloadLocal(localPath string, destinationPath string, options DownloadURLOptions) error {
 	if err := CopyFile(localPath, destinationPath, options.Log); err != nil {
 		return err
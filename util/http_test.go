@@ -5,6 +5,8 @@ package util
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -229,11 +231,109 @@ func TestDownloadURLETag(t *testing.T) {
 	require.NoError(t, err)
 	digest, err := Digest(bytes.NewReader(data))
 	assert.NoError(t, err)
-	cached, err := downloadURL(server.URL, destinationPath, DownloadURLOptions{Digest: digest, RequireDigest: true, UseETag: true, Log: testLog})
+	cached, err := downloadURL(context.Background(), server.URL, destinationPath, DownloadURLOptions{Digest: digest, RequireDigest: true, UseETag: true, Log: testLog})
 	require.NoError(t, err)
 	assert.True(t, cached)
 }
 
+// testServerForResume returns a server that drops the connection partway
+// through the first response. If supportsRange is true, it serves range
+// requests after that, otherwise the full data.
+func testServerForResume(t *testing.T, data []byte, etag string, supportsRange bool, rangeRequests *int) *httptest.Server {
+	requests := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", etag)
+		if requests == 1 {
+			// Claim the full length, but only write half of it
+			w.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+			_, _ = w.Write(data[:len(data)/2])
+			return
+		}
+		if r.Header.Get("Range") != "" {
+			*rangeRequests++
+			t.Logf("Range request: %s (If-Range: %s)", r.Header.Get("Range"), r.Header.Get("If-Range"))
+		}
+		if !supportsRange {
+			_, _ = w.Write(data)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+	}))
+}
+
+func testDownloadURLResume(t *testing.T, supportsRange bool) {
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	rangeRequests := 0
+	server := testServerForResume(t, data, `"abcdef"`, supportsRange, &rangeRequests)
+	defer server.Close()
+	destinationPath := TempPath("", "TestDownloadURLResume.")
+	defer RemoveFileAtPath(destinationPath)
+	digest, err := Digest(bytes.NewReader(data))
+	require.NoError(t, err)
+	options := DownloadURLOptions{Digest: digest, RequireDigest: true, Log: testLog}
+
+	err = DownloadURL(server.URL, destinationPath, options)
+	require.Error(t, err)
+	partial, err := os.ReadFile(destinationPath + ".download")
+	require.NoError(t, err)
+	assert.Equal(t, data[:len(data)/2], partial)
+
+	err = DownloadURL(server.URL, destinationPath, options)
+	require.NoError(t, err)
+	assert.Equal(t, 1, rangeRequests)
+	saved, err := os.ReadFile(destinationPath)
+	require.NoError(t, err)
+	assert.Equal(t, data, saved)
+
+	exists, err := FileExists(destinationPath + ".download")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	exists, err = FileExists(destinationPath + ".download.validator")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestDownloadURLResume(t *testing.T) {
+	testDownloadURLResume(t, true)
+}
+
+func TestDownloadURLResumeNotSupported(t *testing.T) {
+	testDownloadURLResume(t, false)
+}
+
+func TestDownloadURLResumeInvalidDigest(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	rangeRequests := 0
+	server := testServerForResume(t, data, `"abcdef"`, true, &rangeRequests)
+	defer server.Close()
+	destinationPath := TempPath("", "TestDownloadURLResumeInvalidDigest.")
+	defer RemoveFileAtPath(destinationPath)
+	options := DownloadURLOptions{Digest: "invalid", RequireDigest: true, Log: testLog}
+
+	err := DownloadURL(server.URL, destinationPath, options)
+	require.Error(t, err)
+	err = DownloadURL(server.URL, destinationPath, options)
+	require.Error(t, err)
+	assert.Equal(t, 1, rangeRequests)
+
+	// The bad download isn't kept around to resume from
+	exists, err := FileExists(destinationPath + ".download")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestDownloadURLContextCancel(t *testing.T) {
+	server := testServer(t, "ok", time.Second)
+	defer server.Close()
+	destinationPath := TempPath("", "TestDownloadURLContextCancel.")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := DownloadURLContext(ctx, server.URL, destinationPath, DownloadURLOptions{Log: testLog})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%s", err)
+}
+
 func TestURLExistsParseError(t *testing.T) {
 	exists, err := URLExists("invalid", time.Millisecond, testLog)
 	assert.False(t, exists)
@@ -0,0 +1,38 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+//go:build !windows
+// +build !windows
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// CheckPermissions returns an error if path is a symlink, isn't owned by the
+// current user, or has any of the permission bits in mask set. For example, a
+// mask of 0077 requires that only the owner have access, and 0022 that only
+// the owner can write.
+func CheckPermissions(path string, mask os.FileMode) error {
+	fileInfo, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if fileInfo.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%s is a symlink", path)
+	}
+	if perm := fileInfo.Mode().Perm(); perm&mask != 0 {
+		return fmt.Errorf("%s has insecure permissions (%#o)", path, perm)
+	}
+	stat, ok := fileInfo.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("Unable to check owner of %s", path)
+	}
+	if int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is not owned by the current user (%d != %d)", path, stat.Uid, os.Getuid())
+	}
+	return nil
+}
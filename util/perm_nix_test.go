@@ -0,0 +1,41 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+//go:build !windows
+// +build !windows
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	dir, err := MakeTempDir("TestCheckPermissions.", 0700)
+	require.NoError(t, err)
+	defer RemoveFileAtPath(dir)
+
+	err = CheckPermissions(dir, 0077)
+	assert.NoError(t, err)
+
+	err = os.Chmod(dir, 0755)
+	require.NoError(t, err)
+	err = CheckPermissions(dir, 0077)
+	assert.Error(t, err)
+	err = CheckPermissions(dir, 0022)
+	assert.NoError(t, err)
+
+	link := filepath.Join(dir, "link")
+	err = os.Symlink(dir, link)
+	require.NoError(t, err)
+	err = CheckPermissions(link, 0)
+	assert.Error(t, err)
+
+	err = CheckPermissions(filepath.Join(dir, "nope"), 0)
+	assert.Error(t, err)
+}
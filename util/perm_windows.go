@@ -0,0 +1,26 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+//go:build windows
+// +build windows
+
+package util
+
+import (
+	"fmt"
+	"os"
+)
+
+// CheckPermissions returns an error if path is a symlink. Ownership and mode
+// (mask) aren't checked on Windows, where paths should be in the user's
+// profile, which other (non-admin) users can't access.
+func CheckPermissions(path string, mask os.FileMode) error {
+	fileInfo, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if fileInfo.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%s is a symlink", path)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+//go:build !windows
+// +build !windows
+
+package util
+
+import "syscall"
+
+// ProcessExists returns true if there is a process with the PID. The PID may
+// have been reused by another process since, so it's a hint, not a lock.
+func ProcessExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	// Signal 0 only checks the process exists, and EPERM means it does, but
+	// is another user's
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,24 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package util
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessExists(t *testing.T) {
+	assert.True(t, ProcessExists(os.Getpid()))
+	assert.False(t, ProcessExists(0))
+
+	// A process that exited
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	err := cmd.Run()
+	require.NoError(t, err)
+	assert.False(t, ProcessExists(cmd.Process.Pid))
+}
@@ -0,0 +1,31 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+//go:build windows
+// +build windows
+
+package util
+
+import "golang.org/x/sys/windows"
+
+// stillActive is the exit code of a process that hasn't exited
+const stillActive = 259
+
+// ProcessExists returns true if there is a process with the PID. The PID may
+// have been reused by another process since, so it's a hint, not a lock.
+func ProcessExists(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		// Access denied means it exists, but is another user's
+		return err == windows.ERROR_ACCESS_DENIED
+	}
+	defer func() { _ = windows.CloseHandle(handle) }()
+	var code uint32
+	if err := windows.GetExitCodeProcess(handle, &code); err != nil {
+		return true
+	}
+	return code == stillActive
+}
@@ -12,3 +12,23 @@ func Semver(version string) string {
 	v.Build = nil
 	return v.String()
 }
+
+// SemverValid returns true if version is a valid semver
+func SemverValid(version string) bool {
+	_, err := semver.Parse(version)
+	return err == nil
+}
+
+// SemverCompare compares versions a and b, returning -1 if a < b, 0 if they
+// are equal, or 1 if a > b. It errors if either version isn't a valid semver.
+func SemverCompare(a string, b string) (int, error) {
+	va, err := semver.Parse(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := semver.Parse(b)
+	if err != nil {
+		return 0, err
+	}
+	return va.Compare(vb), nil
+}
@@ -0,0 +1,46 @@
+// Copyright 2026 Keybase, Inc. All rights reserved. Use of
+// this source code is governed by the included BSD license.
+
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemver(t *testing.T) {
+	assert.Equal(t, "1.2.3", Semver("1.2.3-400+abcdef"))
+	assert.Equal(t, "invalid", Semver("invalid"))
+}
+
+func TestSemverValid(t *testing.T) {
+	assert.True(t, SemverValid("1.2.3-400+abcdef"))
+	assert.False(t, SemverValid(""))
+	assert.False(t, SemverValid("invalid"))
+}
+
+func TestSemverCompare(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0.1", "1.0.0", 1},
+		{"1.0.0", "1.0.0", 0},
+		{"1.0.0", "1.0.1", -1},
+		{"1.0.0-20160101+abc", "1.0.0-20160102+def", -1},
+		{"1.0.0-20160101+abc", "1.0.0-20160101+def", 0},
+		{"1.0.0", "1.0.0-20160101", 1},
+	}
+	for _, c := range cases {
+		result, err := SemverCompare(c.a, c.b)
+		require.NoError(t, err)
+		assert.Equal(t, c.expected, result, "%s vs %s", c.a, c.b)
+	}
+
+	_, err := SemverCompare("1.0.0", "")
+	require.Error(t, err)
+	_, err = SemverCompare("invalid", "1.0.0")
+	require.Error(t, err)
+}